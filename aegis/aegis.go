@@ -0,0 +1,404 @@
+// aegis.go - High-level interface
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to the software, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+// Package aegis implements the AEGIS-128L and AEGIS-256 Authenticated
+// Ciphers.
+//
+// This implementation is derived from draft-irtf-cfrg-aegis-aead.
+package aegis
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// KeySize128L is the size of an AEGIS-128L key in bytes.
+	KeySize128L = 16
+
+	// NonceSize128L is the size of an AEGIS-128L nonce in bytes.
+	NonceSize128L = 16
+
+	// KeySize256 is the size of an AEGIS-256 key in bytes.
+	KeySize256 = 32
+
+	// NonceSize256 is the size of an AEGIS-256 nonce in bytes.
+	NonceSize256 = 32
+
+	// TagSize is the size of the default authentication tag in bytes.
+	TagSize = 16
+
+	// TagSize256 is the size of the long authentication tag in bytes.
+	TagSize256 = 32
+
+	blockSize = 16
+	rate128L  = 2 * blockSize
+	rate256   = blockSize
+)
+
+var (
+	// ErrInvalidKeySize is the error thrown via a panic when a key is an
+	// invalid size.
+	ErrInvalidKeySize = errors.New("aegis: invalid key size")
+
+	// ErrInvalidNonceSize is the error thrown via a panic when a nonce is
+	// an invalid size.
+	ErrInvalidNonceSize = errors.New("aegis: invalid nonce size")
+
+	// ErrInvalidTagSize is the error thrown via a panic when a tag size is
+	// invalid.
+	ErrInvalidTagSize = errors.New("aegis: invalid tag size")
+
+	// ErrOpen is the error returned when the message authentication fails
+	// during an Open call.
+	ErrOpen = errors.New("aegis: message authentication failed")
+
+	c0 = [blockSize]byte{
+		0x00, 0x01, 0x01, 0x02, 0x03, 0x05, 0x08, 0x0d,
+		0x15, 0x22, 0x37, 0x59, 0x90, 0xe9, 0x79, 0x62,
+	}
+	c1 = [blockSize]byte{
+		0xdb, 0x3d, 0x18, 0x55, 0x6d, 0xc2, 0x2f, 0xf1,
+		0x20, 0x11, 0x31, 0x42, 0x73, 0xb5, 0x28, 0xdd,
+	}
+)
+
+// state is the AEGIS state.  AEGIS-128L uses all 8 blocks, AEGIS-256 uses
+// the first 6.
+type state [8][blockSize]byte
+
+// variant is the set of AEGIS-128L or AEGIS-256 specific parameters and
+// routines.
+type variant struct {
+	keySize   int
+	nonceSize int
+	rate      int
+
+	initFn     func(s *state, key, nonce []byte)
+	finalizeFn func(s *state, tag []byte, adLen, msgLen int)
+	absorbFn   func(s *state, src []byte)
+	encFn      func(s *state, dst, src []byte)
+	decFn      func(s *state, dst, src []byte)
+}
+
+var (
+	variant128L = &variant{
+		keySize:    KeySize128L,
+		nonceSize:  NonceSize128L,
+		rate:       rate128L,
+		initFn:     init128L,
+		finalizeFn: finalize128L,
+		absorbFn:   func(s *state, src []byte) { hardwareAccelImpl.absorb128LFn(s, src) },
+		encFn:      func(s *state, dst, src []byte) { hardwareAccelImpl.enc128LFn(s, dst, src) },
+		decFn:      func(s *state, dst, src []byte) { hardwareAccelImpl.dec128LFn(s, dst, src) },
+	}
+
+	variant256 = &variant{
+		keySize:    KeySize256,
+		nonceSize:  NonceSize256,
+		rate:       rate256,
+		initFn:     init256,
+		finalizeFn: finalize256,
+		absorbFn:   func(s *state, src []byte) { hardwareAccelImpl.absorb256Fn(s, src) },
+		encFn:      func(s *state, dst, src []byte) { hardwareAccelImpl.enc256Fn(s, dst, src) },
+		decFn:      func(s *state, dst, src []byte) { hardwareAccelImpl.dec256Fn(s, dst, src) },
+	}
+)
+
+// AEAD is an AEGIS-128L or AEGIS-256 instance, implementing
+// crypto/cipher.AEAD.
+type AEAD struct {
+	v       *variant
+	key     []byte
+	tagSize int
+}
+
+// NonceSize returns the size of the nonce that must be passed to Seal and
+// Open.
+func (ae *AEAD) NonceSize() int {
+	return ae.v.nonceSize
+}
+
+// Overhead returns the maximum difference between the lengths of a plaintext
+// and its ciphertext.
+func (ae *AEAD) Overhead() int {
+	return ae.tagSize
+}
+
+// Seal encrypts and authenticates plaintext, authenticates the
+// additional data and appends the result to dst, returning the updated
+// slice. The nonce must be NonceSize() bytes long and unique for all
+// time, for a given key.
+//
+// The plaintext and dst must overlap exactly or not at all. To reuse
+// plaintext's storage for the encrypted output, use plaintext[:0] as dst.
+func (ae *AEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	var s state
+	var buf [rate128L]byte
+
+	v := ae.v
+	if len(nonce) != v.nonceSize {
+		panic(ErrInvalidNonceSize)
+	}
+
+	ret, out := sliceForAppend(dst, len(plaintext)+ae.tagSize)
+	v.initFn(&s, ae.key, nonce)
+	v.absorbPadded(&s, additionalData)
+
+	full := len(plaintext) - len(plaintext)%v.rate
+	v.encFn(&s, out[:full], plaintext[:full])
+	if rem := plaintext[full:]; len(rem) > 0 {
+		copy(buf[:], rem)
+		v.encFn(&s, buf[:v.rate], buf[:v.rate])
+		copy(out[full:], buf[:len(rem)])
+	}
+
+	v.finalizeFn(&s, out[len(plaintext):], len(additionalData), len(plaintext))
+
+	burnBytes(buf[:])
+	burnState(&s)
+
+	return ret
+}
+
+// Open decrypts and authenticates ciphertext, authenticates the
+// additional data and, if successful, appends the resulting plaintext
+// to dst, returning the updated slice. The nonce must be NonceSize()
+// bytes long and both it and the additional data must match the
+// value passed to Seal.
+//
+// The ciphertext and dst must overlap exactly or not at all. To reuse
+// ciphertext's storage for the decrypted output, use ciphertext[:0] as dst.
+//
+// Even if the function fails, the contents of dst, up to its capacity,
+// may be overwritten.
+func (ae *AEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	var s, sz state
+	var buf [rate128L]byte
+	var tag [TagSize256]byte
+
+	v := ae.v
+	if len(nonce) != v.nonceSize {
+		panic(ErrInvalidNonceSize)
+	}
+	if len(ciphertext) < ae.tagSize {
+		return nil, ErrOpen
+	}
+
+	cLen := len(ciphertext) - ae.tagSize
+	srcTag := ciphertext[cLen:]
+	ret, out := sliceForAppend(dst, cLen)
+	v.initFn(&s, ae.key, nonce)
+	v.absorbPadded(&s, additionalData)
+
+	full := cLen - cLen%v.rate
+	v.decFn(&s, out[:full], ciphertext[:full])
+	if rem := ciphertext[full:cLen]; len(rem) > 0 {
+		// The final partial block updates the state with the zero padded
+		// plaintext, so derive the keystream from a copy of the state,
+		// and absorb the plaintext separately.
+		sz = s
+		v.encFn(&sz, buf[:v.rate], buf[:v.rate])
+		for i := range rem {
+			buf[i] ^= rem[i]
+		}
+		burnBytes(buf[len(rem):])
+		copy(out[full:], buf[:len(rem)])
+		v.absorbFn(&s, buf[:v.rate])
+	}
+
+	v.finalizeFn(&s, tag[:ae.tagSize], len(additionalData), cLen)
+	ok := subtle.ConstantTimeCompare(srcTag, tag[:ae.tagSize]) == 1
+
+	burnBytes(buf[:])
+	burnBytes(tag[:])
+	burnState(&s)
+	burnState(&sz)
+
+	if !ok {
+		// Burn decrypted plaintext on auth failure.
+		burnBytes(out)
+		return nil, ErrOpen
+	}
+
+	return ret, nil
+}
+
+// Reset securely purges stored sensitive data from the AEAD instance.
+func (ae *AEAD) Reset() {
+	burnBytes(ae.key)
+}
+
+// absorbPadded absorbs src into the state, zero padding the final partial
+// block.
+func (v *variant) absorbPadded(s *state, src []byte) {
+	var buf [rate128L]byte
+
+	full := len(src) - len(src)%v.rate
+	v.absorbFn(s, src[:full])
+	if rem := src[full:]; len(rem) > 0 {
+		copy(buf[:], rem)
+		v.absorbFn(s, buf[:v.rate])
+		burnBytes(buf[:])
+	}
+}
+
+func init128L(s *state, key, nonce []byte) {
+	var buf [10 * rate128L]byte
+
+	xorBlock(&s[0], key, nonce)
+	s[1] = c1
+	s[2] = c0
+	s[3] = c1
+	xorBlock(&s[4], key, nonce)
+	xorBlock(&s[5], key, c0[:])
+	xorBlock(&s[6], key, c1[:])
+	xorBlock(&s[7], key, c0[:])
+
+	// Update(nonce, key), 10 times.
+	for i := 0; i < len(buf); i += rate128L {
+		copy(buf[i:], nonce)
+		copy(buf[i+blockSize:], key)
+	}
+	hardwareAccelImpl.absorb128LFn(s, buf[:])
+	burnBytes(buf[:])
+}
+
+func finalize128L(s *state, tag []byte, adLen, msgLen int) {
+	var buf [7 * rate128L]byte
+	var t [blockSize]byte
+
+	binary.LittleEndian.PutUint64(t[0:], uint64(adLen)*8)
+	binary.LittleEndian.PutUint64(t[8:], uint64(msgLen)*8)
+	xorBlock(&t, t[:], s[2][:])
+
+	// Update(t, t), 7 times.
+	for i := 0; i < len(buf); i += blockSize {
+		copy(buf[i:], t[:])
+	}
+	hardwareAccelImpl.absorb128LFn(s, buf[:])
+
+	switch len(tag) {
+	case TagSize:
+		for i := range tag {
+			tag[i] = s[0][i] ^ s[1][i] ^ s[2][i] ^ s[3][i] ^ s[4][i] ^ s[5][i] ^ s[6][i]
+		}
+	case TagSize256:
+		for i := 0; i < blockSize; i++ {
+			tag[i] = s[0][i] ^ s[1][i] ^ s[2][i] ^ s[3][i]
+			tag[blockSize+i] = s[4][i] ^ s[5][i] ^ s[6][i] ^ s[7][i]
+		}
+	}
+
+	burnBytes(buf[:])
+}
+
+func init256(s *state, key, nonce []byte) {
+	var buf [16 * rate256]byte
+	var kn0, kn1 [blockSize]byte
+
+	k0, k1 := key[:blockSize], key[blockSize:]
+	n0, n1 := nonce[:blockSize], nonce[blockSize:]
+	xorBlock(&kn0, k0, n0)
+	xorBlock(&kn1, k1, n1)
+
+	s[0] = kn0
+	s[1] = kn1
+	s[2] = c1
+	s[3] = c0
+	xorBlock(&s[4], k0, c0[:])
+	xorBlock(&s[5], k1, c1[:])
+
+	// Update(k0), Update(k1), Update(k0 ^ n0), Update(k1 ^ n1), 4 times.
+	for i := 0; i < len(buf); i += 4 * blockSize {
+		copy(buf[i:], k0)
+		copy(buf[i+blockSize:], k1)
+		copy(buf[i+2*blockSize:], kn0[:])
+		copy(buf[i+3*blockSize:], kn1[:])
+	}
+	hardwareAccelImpl.absorb256Fn(s, buf[:])
+
+	burnBytes(buf[:])
+	burnBytes(kn0[:])
+	burnBytes(kn1[:])
+}
+
+func finalize256(s *state, tag []byte, adLen, msgLen int) {
+	var buf [7 * rate256]byte
+	var t [blockSize]byte
+
+	binary.LittleEndian.PutUint64(t[0:], uint64(adLen)*8)
+	binary.LittleEndian.PutUint64(t[8:], uint64(msgLen)*8)
+	xorBlock(&t, t[:], s[3][:])
+
+	// Update(t), 7 times.
+	for i := 0; i < len(buf); i += blockSize {
+		copy(buf[i:], t[:])
+	}
+	hardwareAccelImpl.absorb256Fn(s, buf[:])
+
+	switch len(tag) {
+	case TagSize:
+		for i := range tag {
+			tag[i] = s[0][i] ^ s[1][i] ^ s[2][i] ^ s[3][i] ^ s[4][i] ^ s[5][i]
+		}
+	case TagSize256:
+		for i := 0; i < blockSize; i++ {
+			tag[i] = s[0][i] ^ s[1][i] ^ s[2][i]
+			tag[blockSize+i] = s[3][i] ^ s[4][i] ^ s[5][i]
+		}
+	}
+
+	burnBytes(buf[:])
+}
+
+func xorBlock(dst *[blockSize]byte, a, b []byte) {
+	for i := range dst {
+		dst[i] = a[i] ^ b[i]
+	}
+}
+
+func burnState(s *state) {
+	for i := range s {
+		burnBytes(s[i][:])
+	}
+}
+
+func newAEAD(v *variant, key []byte, tagSize int) *AEAD {
+	if len(key) != v.keySize {
+		panic(ErrInvalidKeySize)
+	}
+	if tagSize != TagSize && tagSize != TagSize256 {
+		panic(ErrInvalidTagSize)
+	}
+	return &AEAD{v: v, key: append([]byte{}, key...), tagSize: tagSize}
+}
+
+// New128L returns a new keyed AEGIS-128L instance, with a tagSize byte
+// (TagSize or TagSize256) authentication tag.
+func New128L(key []byte, tagSize int) *AEAD {
+	return newAEAD(variant128L, key, tagSize)
+}
+
+// New256 returns a new keyed AEGIS-256 instance, with a tagSize byte
+// (TagSize or TagSize256) authentication tag.
+func New256(key []byte, tagSize int) *AEAD {
+	return newAEAD(variant256, key, tagSize)
+}
+
+// Shamelessly stolen from the Go runtime library.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
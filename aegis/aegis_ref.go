@@ -0,0 +1,128 @@
+// aegis_ref.go - Reference (portable) implementation
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to the software, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package aegis
+
+import "github.com/mad-day/Yawning-crypto/bsaes/ct64"
+
+// aesRound4 applies the AES round function without AddRoundKey to each of
+// the 4 blocks in b, using the constant time bitsliced implementation.
+func aesRound4(b *[4][blockSize]byte) {
+	var q [8]uint64
+
+	ct64.Load16xU32(&q, b[0][:], b[1][:], b[2][:], b[3][:])
+	ct64.Sbox(&q)
+	ct64.ShiftRows(&q)
+	ct64.MixColumns(&q)
+	ct64.Store16xU32(b[0][:], b[1][:], b[2][:], b[3][:], &q)
+
+	burnUint64s(q[:])
+}
+
+func update128L(s *state, m0, m1 []byte) {
+	t0 := [4][blockSize]byte{s[7], s[0], s[1], s[2]}
+	t1 := [4][blockSize]byte{s[3], s[4], s[5], s[6]}
+	aesRound4(&t0)
+	aesRound4(&t1)
+
+	for i := 0; i < blockSize; i++ {
+		s[0][i] ^= t0[0][i] ^ m0[i]
+		s[1][i] ^= t0[1][i]
+		s[2][i] ^= t0[2][i]
+		s[3][i] ^= t0[3][i]
+		s[4][i] ^= t1[0][i] ^ m1[i]
+		s[5][i] ^= t1[1][i]
+		s[6][i] ^= t1[2][i]
+		s[7][i] ^= t1[3][i]
+	}
+}
+
+func absorb128LRef(s *state, src []byte) {
+	for ; len(src) >= rate128L; src = src[rate128L:] {
+		update128L(s, src[:blockSize], src[blockSize:])
+	}
+}
+
+func enc128LRef(s *state, dst, src []byte) {
+	var m [rate128L]byte
+
+	for ; len(src) >= rate128L; src, dst = src[rate128L:], dst[rate128L:] {
+		copy(m[:], src)
+		for i := 0; i < blockSize; i++ {
+			dst[i] = m[i] ^ s[6][i] ^ s[1][i] ^ (s[2][i] & s[3][i])
+			dst[blockSize+i] = m[blockSize+i] ^ s[2][i] ^ s[5][i] ^ (s[6][i] & s[7][i])
+		}
+		update128L(s, m[:blockSize], m[blockSize:])
+	}
+
+	burnBytes(m[:])
+}
+
+func dec128LRef(s *state, dst, src []byte) {
+	var m [rate128L]byte
+
+	for ; len(src) >= rate128L; src, dst = src[rate128L:], dst[rate128L:] {
+		for i := 0; i < blockSize; i++ {
+			m[i] = src[i] ^ s[6][i] ^ s[1][i] ^ (s[2][i] & s[3][i])
+			m[blockSize+i] = src[blockSize+i] ^ s[2][i] ^ s[5][i] ^ (s[6][i] & s[7][i])
+		}
+		copy(dst, m[:])
+		update128L(s, m[:blockSize], m[blockSize:])
+	}
+
+	burnBytes(m[:])
+}
+
+func update256(s *state, m []byte) {
+	t0 := [4][blockSize]byte{s[5], s[0], s[1], s[2]}
+	t1 := [4][blockSize]byte{s[3], s[4]}
+	aesRound4(&t0)
+	aesRound4(&t1)
+
+	for i := 0; i < blockSize; i++ {
+		s[0][i] ^= t0[0][i] ^ m[i]
+		s[1][i] ^= t0[1][i]
+		s[2][i] ^= t0[2][i]
+		s[3][i] ^= t0[3][i]
+		s[4][i] ^= t1[0][i]
+		s[5][i] ^= t1[1][i]
+	}
+}
+
+func absorb256Ref(s *state, src []byte) {
+	for ; len(src) >= rate256; src = src[rate256:] {
+		update256(s, src)
+	}
+}
+
+func enc256Ref(s *state, dst, src []byte) {
+	var m [rate256]byte
+
+	for ; len(src) >= rate256; src, dst = src[rate256:], dst[rate256:] {
+		copy(m[:], src)
+		for i := range m {
+			dst[i] = m[i] ^ s[1][i] ^ s[4][i] ^ s[5][i] ^ (s[2][i] & s[3][i])
+		}
+		update256(s, m[:])
+	}
+
+	burnBytes(m[:])
+}
+
+func dec256Ref(s *state, dst, src []byte) {
+	var m [rate256]byte
+
+	for ; len(src) >= rate256; src, dst = src[rate256:], dst[rate256:] {
+		for i := range m {
+			m[i] = src[i] ^ s[1][i] ^ s[4][i] ^ s[5][i] ^ (s[2][i] & s[3][i])
+		}
+		copy(dst, m[:])
+		update256(s, m[:])
+	}
+
+	burnBytes(m[:])
+}
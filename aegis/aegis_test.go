@@ -0,0 +1,369 @@
+// aegis_test.go - AEGIS tests
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to the software, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package aegis
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	canAccelerate bool
+
+	_ cipher.AEAD = (*AEAD)(nil)
+)
+
+// Test vectors from draft-irtf-cfrg-aegis-aead, Appendix A.
+type testVector struct {
+	key    string
+	nonce  string
+	ad     string
+	msg    string
+	ct     string
+	tag128 string
+	tag256 string
+}
+
+var vectors128L = []testVector{
+	{
+		key:    "10010000000000000000000000000000",
+		nonce:  "10000200000000000000000000000000",
+		ad:     "",
+		msg:    "00000000000000000000000000000000",
+		ct:     "c1c0e58bd913006feba00f4b3cc3594e",
+		tag128: "abe0ece80c24868a226a35d16bdae37a",
+		tag256: "25835bfbb21632176cf03840687cb968cace4617af1bd0f7d064c639a5c79ee4",
+	},
+	{
+		key:    "10010000000000000000000000000000",
+		nonce:  "10000200000000000000000000000000",
+		ad:     "",
+		msg:    "",
+		ct:     "",
+		tag128: "c2b879a67def9d74e6c14f708bbcc9b4",
+		tag256: "1360dc9db8ae42455f6e5b6a9d488ea4f2184c4e12120249335c4ee84bafe25d",
+	},
+	{
+		key:    "10010000000000000000000000000000",
+		nonce:  "10000200000000000000000000000000",
+		ad:     "0001020304050607",
+		msg:    "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		ct:     "79d94593d8c2119d7e8fd9b8fc77845c5c077a05b2528b6ac54b563aed8efe84",
+		tag128: "cc6f3372f6aa1bb82388d695c3962d9a",
+		tag256: "022cb796fe7e0ae1197525ff67e309484cfbab6528ddef89f17d74ef8ecd82b3",
+	},
+	{
+		key:    "10010000000000000000000000000000",
+		nonce:  "10000200000000000000000000000000",
+		ad:     "0001020304050607",
+		msg:    "000102030405060708090a0b0c0d",
+		ct:     "79d94593d8c2119d7e8fd9b8fc77",
+		tag128: "5c04b3dba849b2701effbe32c7f0fab7",
+		tag256: "86f1b80bfb463aba711d15405d094baf4a55a15dbfec81a76f35ed0b9c8b04ac",
+	},
+	{
+		key:    "10010000000000000000000000000000",
+		nonce:  "10000200000000000000000000000000",
+		ad:     "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20212223242526272829",
+		msg:    "101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f3031323334353637",
+		ct:     "b31052ad1cca4e291abcf2df3502e6bdb1bfd6db36798be3607b1f94d34478aa7ede7f7a990fec10",
+		tag128: "7542a745733014f9474417b337399507",
+		tag256: "b91e2947a33da8bee89b6794e647baf0fc835ff574aca3fc27c33be0db2aff98",
+	},
+}
+
+var vectors256 = []testVector{
+	{
+		key:    "1001000000000000000000000000000000000000000000000000000000000000",
+		nonce:  "1000020000000000000000000000000000000000000000000000000000000000",
+		ad:     "",
+		msg:    "00000000000000000000000000000000",
+		ct:     "754fc3d8c973246dcc6d741412a4b236",
+		tag128: "3fe91994768b332ed7f570a19ec5896e",
+		tag256: "1181a1d18091082bf0266f66297d167d2e68b845f61a3b0527d31fc7b7b89f13",
+	},
+	{
+		key:    "1001000000000000000000000000000000000000000000000000000000000000",
+		nonce:  "1000020000000000000000000000000000000000000000000000000000000000",
+		ad:     "",
+		msg:    "",
+		ct:     "",
+		tag128: "e3def978a0f054afd1e761d7553afba3",
+		tag256: "6a348c930adbd654896e1666aad67de989ea75ebaa2b82fb588977b1ffec864a",
+	},
+	{
+		key:    "1001000000000000000000000000000000000000000000000000000000000000",
+		nonce:  "1000020000000000000000000000000000000000000000000000000000000000",
+		ad:     "0001020304050607",
+		msg:    "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		ct:     "f373079ed84b2709faee373584585d60accd191db310ef5d8b11833df9dec711",
+		tag128: "8d86f91ee606e9ff26a01b64ccbdd91d",
+		tag256: "b7d28d0c3c0ebd409fd22b44160503073a547412da0854bfb9723020dab8da1a",
+	},
+	{
+		key:    "1001000000000000000000000000000000000000000000000000000000000000",
+		nonce:  "1000020000000000000000000000000000000000000000000000000000000000",
+		ad:     "0001020304050607",
+		msg:    "000102030405060708090a0b0c0d",
+		ct:     "f373079ed84b2709faee37358458",
+		tag128: "c60b9c2d33ceb058f96e6dd03c215652",
+		tag256: "8c1cc703c81281bee3f6d9966e14948b4a175b2efbdc31e61a98b4465235c2d9",
+	},
+	{
+		key:    "1001000000000000000000000000000000000000000000000000000000000000",
+		nonce:  "1000020000000000000000000000000000000000000000000000000000000000",
+		ad:     "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20212223242526272829",
+		msg:    "101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f3031323334353637",
+		ct:     "57754a7d09963e7c787583a2e7b859bb24fa1e04d49fd550b2511a358e3bca252a9b1b8b30cc4a67",
+		tag128: "ab8a7d53fd0e98d727accca94925e128",
+		tag256: "a3aca270c006094d71c20e6910b5161c0826df233d08919a566ec2c05990f734",
+	},
+}
+
+func mustInitHardwareAcceleration() {
+	initHardwareAcceleration()
+	if !IsHardwareAccelerated() {
+		panic("initHardwareAcceleration() failed")
+	}
+}
+
+// forEachImpl runs fn with the reference implementation, and with the
+// hardware accelerated implementation if supported.
+func forEachImpl(t *testing.T, name string, fn func(*testing.T)) {
+	forceDisableHardwareAcceleration()
+	t.Run(name+"_"+hardwareAccelImpl.name, fn)
+
+	if !canAccelerate {
+		t.Log("Hardware acceleration not supported on this host.")
+		return
+	}
+	mustInitHardwareAcceleration()
+	t.Run(name+"_"+hardwareAccelImpl.name, fn)
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic("mustDecodeHex: " + err.Error())
+	}
+	return b
+}
+
+func TestKAT(t *testing.T) {
+	forEachImpl(t, "AEGIS-128L_KAT", func(t *testing.T) { doTestKAT(t, New128L, vectors128L) })
+	forEachImpl(t, "AEGIS-256_KAT", func(t *testing.T) { doTestKAT(t, New256, vectors256) })
+}
+
+func doTestKAT(t *testing.T, ctor func([]byte, int) *AEAD, vectors []testVector) {
+	require := require.New(t)
+
+	for i, vec := range vectors {
+		key, nonce := mustDecodeHex(vec.key), mustDecodeHex(vec.nonce)
+		ad, msg := mustDecodeHex(vec.ad), mustDecodeHex(vec.msg)
+		ct := mustDecodeHex(vec.ct)
+
+		for _, tag := range []string{vec.tag128, vec.tag256} {
+			expected := append(append([]byte{}, ct...), mustDecodeHex(tag)...)
+
+			aead := ctor(key, len(tag)/2)
+			require.Equal(len(nonce), aead.NonceSize(), "NonceSize(): %d", i)
+			require.Equal(len(tag)/2, aead.Overhead(), "Overhead(): %d", i)
+
+			c := aead.Seal(nil, nonce, msg, ad)
+			require.Equal(expected, c, "Seal(): %d", i)
+
+			m, err := aead.Open(nil, nonce, c, ad)
+			require.NoError(err, "Open(): %d", i)
+			require.Len(m, len(msg), "Open(): len(m) %d", i)
+			if len(m) != 0 {
+				require.Equal(msg, m, "Open(): m %d", i)
+			}
+
+			// Test malformed tag.
+			badC := append([]byte{}, c...)
+			badC[len(badC)-1] ^= 0x23
+			m, err = aead.Open(nil, nonce, badC, ad)
+			require.Error(err, "Open(Bad tag): %d", i)
+			require.Nil(m, "Open(Bad tag): %d", i)
+		}
+	}
+}
+
+func TestImplsMatch(t *testing.T) {
+	if !canAccelerate {
+		t.Skip("Hardware acceleration not supported on this host.")
+	}
+	defer mustInitHardwareAcceleration()
+
+	require := require.New(t)
+
+	var w, h [256]byte
+	var k [KeySize256]byte
+	var n [NonceSize256]byte
+	rand.Read(w[:])
+	rand.Read(h[:])
+	rand.Read(k[:])
+	rand.Read(n[:])
+
+	for _, v := range []*variant{variant128L, variant256} {
+		aead := newAEAD(v, k[:v.keySize], TagSize256)
+		nonce := n[:v.nonceSize]
+		for i := range w {
+			forceDisableHardwareAcceleration()
+			expected := aead.Seal(nil, nonce, w[:i], h[:i])
+			mustInitHardwareAcceleration()
+			c := aead.Seal(nil, nonce, w[:i], h[:i])
+			require.Equal(expected, c, "Seal(): %d", i)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	forEachImpl(t, "AEGIS-128L", func(t *testing.T) { doTestRoundTrip(t, New128L, KeySize128L) })
+	forEachImpl(t, "AEGIS-256", func(t *testing.T) { doTestRoundTrip(t, New256, KeySize256) })
+}
+
+func doTestRoundTrip(t *testing.T, ctor func([]byte, int) *AEAD, keySize int) {
+	require := require.New(t)
+
+	var w, h [256]byte
+	key := make([]byte, keySize)
+	for i := range w {
+		w[i] = byte(255 & (i*197 + 123))
+	}
+	for i := range h {
+		h[i] = byte(255 & (i*193 + 123))
+	}
+	for i := range key {
+		key[i] = byte(255 & (i*191 + 123))
+	}
+
+	aead := ctor(key, TagSize)
+	n := make([]byte, aead.NonceSize())
+	for i := range n {
+		n[i] = byte(255 & (i*181 + 123))
+	}
+
+	for i := range w {
+		c := aead.Seal(nil, n, w[:i], h[:i])
+		require.Len(c, i+TagSize, "Seal(): len(c) %d", i)
+
+		// In-place.
+		buf := append([]byte{}, w[:i]...)
+		buf = aead.Seal(buf[:0], n, buf, h[:i])
+		require.Equal(c, buf, "Seal(in-place): %d", i)
+
+		m, err := aead.Open(buf[:0], n, buf, h[:i])
+		require.NoError(err, "Open(): %d", i)
+		require.Len(m, i, "Open(): len(m) %d", i)
+		require.True(bytes.Equal(m, w[:i]), "Open(): m %d", i)
+
+		// Test malformed ciphertext.
+		badC := append([]byte{}, c...)
+		badC[i] ^= 0x23
+		m, err = aead.Open(nil, n, badC, h[:i])
+		require.Error(err, "Open(Bad c): %d", i)
+		require.Nil(m, "Open(Bad c): len(m) %d", i)
+
+		// Test malformed AD.
+		if i > 0 {
+			badH := append([]byte{}, h[:i]...)
+			badH[i-1] ^= 0x23
+			m, err = aead.Open(nil, n, c, badH)
+			require.Error(err, "Open(Bad h): %d", i)
+			require.Nil(m, "Open(Bad h): len(m) %d", i)
+		}
+	}
+}
+
+func BenchmarkAEGIS(b *testing.B) {
+	forceDisableHardwareAcceleration()
+	doBenchmarkAEGIS(b)
+
+	if !canAccelerate {
+		b.Log("Hardware acceleration not supported on this host.")
+		return
+	}
+	mustInitHardwareAcceleration()
+	doBenchmarkAEGIS(b)
+}
+
+func doBenchmarkAEGIS(b *testing.B) {
+	benchSizes := []int{8, 32, 64, 576, 1536, 4096, 1024768}
+	impl := "_" + hardwareAccelImpl.name
+
+	for _, v := range []struct {
+		name    string
+		ctor    func([]byte, int) *AEAD
+		keySize int
+	}{
+		{"AEGIS-128L", New128L, KeySize128L},
+		{"AEGIS-256", New256, KeySize256},
+	} {
+		for _, sz := range benchSizes {
+			bn := v.name + impl + "_"
+			sn := fmt.Sprintf("_%d", sz)
+			aead := v.ctor(make([]byte, v.keySize), TagSize)
+			b.Run(bn+"Encrypt"+sn, func(b *testing.B) { doBenchmarkAEADEncrypt(b, aead, sz) })
+			b.Run(bn+"Decrypt"+sn, func(b *testing.B) { doBenchmarkAEADDecrypt(b, aead, sz) })
+		}
+	}
+}
+
+func doBenchmarkAEADEncrypt(b *testing.B, aead *AEAD, sz int) {
+	b.StopTimer()
+	b.SetBytes(int64(sz))
+
+	nonce := make([]byte, aead.NonceSize())
+	m, c := make([]byte, sz), make([]byte, 0, sz+TagSize)
+	rand.Read(nonce)
+	rand.Read(m)
+
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		c = aead.Seal(c[:0], nonce, m, nil)
+		if len(c) != sz+TagSize {
+			b.Fatalf("Seal failed")
+		}
+	}
+}
+
+func doBenchmarkAEADDecrypt(b *testing.B, aead *AEAD, sz int) {
+	b.StopTimer()
+	b.SetBytes(int64(sz))
+
+	nonce := make([]byte, aead.NonceSize())
+	m, c, d := make([]byte, sz), make([]byte, 0, sz+TagSize), make([]byte, 0, sz)
+	rand.Read(nonce)
+	rand.Read(m)
+
+	c = aead.Seal(c, nonce, m, nil)
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		d, err = aead.Open(d[:0], nonce, c, nil)
+		if err != nil {
+			b.Fatalf("Open failed")
+		}
+	}
+	b.StopTimer()
+
+	if !bytes.Equal(m, d) {
+		b.Fatalf("Open output mismatch")
+	}
+}
+
+func init() {
+	canAccelerate = IsHardwareAccelerated()
+}
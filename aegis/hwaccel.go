@@ -0,0 +1,51 @@
+// hwaccel.go - Hardware acceleration hooks
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to the software, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package aegis
+
+var (
+	isHardwareAccelerated = false
+	hardwareAccelImpl     = implReference
+
+	implReference = &hwaccelImpl{
+		name:         "Reference",
+		absorb128LFn: absorb128LRef,
+		enc128LFn:    enc128LRef,
+		dec128LFn:    dec128LRef,
+		absorb256Fn:  absorb256Ref,
+		enc256Fn:     enc256Ref,
+		dec256Fn:     dec256Ref,
+	}
+)
+
+// hwaccelImpl is a set of state update routines.  The absorb, encrypt and
+// decrypt routines only process full blocks (32 bytes for AEGIS-128L, 16
+// bytes for AEGIS-256), and allow dst and src to overlap exactly.
+type hwaccelImpl struct {
+	name         string
+	absorb128LFn func(*state, []byte)
+	enc128LFn    func(*state, []byte, []byte)
+	dec128LFn    func(*state, []byte, []byte)
+	absorb256Fn  func(*state, []byte)
+	enc256Fn     func(*state, []byte, []byte)
+	dec256Fn     func(*state, []byte, []byte)
+}
+
+func forceDisableHardwareAcceleration() {
+	isHardwareAccelerated = false
+	hardwareAccelImpl = implReference
+}
+
+// IsHardwareAccelerated returns true iff the AEGIS implementation will use
+// hardware acceleration (eg: AES-NI).
+func IsHardwareAccelerated() bool {
+	return isHardwareAccelerated
+}
+
+func init() {
+	initHardwareAcceleration()
+}
@@ -0,0 +1,59 @@
+// hwaccel_amd64.go - AMD64 optimized routines
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to the software, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+//go:build amd64 && !gccgo && !noasm && go1.10
+// +build amd64,!gccgo,!noasm,go1.10
+
+package aegis
+
+//go:noescape
+func cpuidAmd64(cpuidParams *uint32)
+
+//go:noescape
+func absorb128LAESNI(s *state, src []byte)
+
+//go:noescape
+func enc128LAESNI(s *state, dst, src []byte)
+
+//go:noescape
+func dec128LAESNI(s *state, dst, src []byte)
+
+//go:noescape
+func absorb256AESNI(s *state, src []byte)
+
+//go:noescape
+func enc256AESNI(s *state, dst, src []byte)
+
+//go:noescape
+func dec256AESNI(s *state, dst, src []byte)
+
+func supportsAESNI() bool {
+	const aesniBit = 1 << 25
+
+	// Check for AES-NI support.
+	// CPUID.(EAX=01H, ECX=0H):ECX.AESNI[bit 25]==1
+	regs := [4]uint32{0x01}
+	cpuidAmd64(&regs[0])
+	return regs[2]&aesniBit != 0
+}
+
+var implAESNI = &hwaccelImpl{
+	name:         "AES-NI",
+	absorb128LFn: absorb128LAESNI,
+	enc128LFn:    enc128LAESNI,
+	dec128LFn:    dec128LAESNI,
+	absorb256Fn:  absorb256AESNI,
+	enc256Fn:     enc256AESNI,
+	dec256Fn:     dec256AESNI,
+}
+
+func initHardwareAcceleration() {
+	if supportsAESNI() {
+		isHardwareAccelerated = true
+		hardwareAccelImpl = implAESNI
+	}
+}
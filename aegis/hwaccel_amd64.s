@@ -0,0 +1,287 @@
+//go:build !noasm && go1.10
+// +build !noasm,go1.10
+// hwaccel_amd64.s - AMD64 optimized routines
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to the software, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+#include "textflag.h"
+
+// func cpuidAmd64(cpuidParams *uint32)
+TEXT ·cpuidAmd64(SB), NOSPLIT, $0-8
+	MOVQ cpuidParams+0(FP), R15
+	MOVL 0(R15), AX
+	MOVL 8(R15), CX
+	CPUID
+	MOVL AX, 0(R15)
+	MOVL BX, 4(R15)
+	MOVL CX, 8(R15)
+	MOVL DX, 12(R15)
+	RET
+
+// XMM Registers: Sx -> State, Mx -> Message, Zx -> Keystream, Tx -> Temporary
+// GP Registers: AX -> State, SI -> Source, DI -> Destination, CX -> Length
+#define S0 X0
+#define S1 X1
+#define S2 X2
+#define S3 X3
+#define S4 X4
+#define S5 X5
+#define S6 X6
+#define S7 X7
+#define T0 X8
+#define T1 X9
+#define M0 X10
+#define M1 X11
+#define Z0 X12
+#define Z1 X13
+
+// AESENC Xk, Xd sets Xd = MixColumns(ShiftRows(SubBytes(Xd))) ^ Xk, which
+// is AESRound(Xd, Xk).  The new state blocks are computed from the highest
+// index down, so that each step consumes the old values.
+
+#define AES_ROUND(SRC, DST) \
+	MOVO   SRC, T1 \
+	AESENC DST, T1 \
+	MOVO   T1, DST
+
+#define LOAD_STATE_128L() \
+	MOVOU 0(AX), S0   \
+	MOVOU 16(AX), S1  \
+	MOVOU 32(AX), S2  \
+	MOVOU 48(AX), S3  \
+	MOVOU 64(AX), S4  \
+	MOVOU 80(AX), S5  \
+	MOVOU 96(AX), S6  \
+	MOVOU 112(AX), S7
+
+#define STORE_STATE_128L() \
+	MOVOU S0, 0(AX)   \
+	MOVOU S1, 16(AX)  \
+	MOVOU S2, 32(AX)  \
+	MOVOU S3, 48(AX)  \
+	MOVOU S4, 64(AX)  \
+	MOVOU S5, 80(AX)  \
+	MOVOU S6, 96(AX)  \
+	MOVOU S7, 112(AX)
+
+#define UPDATE_128L() \
+	MOVO       S7, T0 \
+	AES_ROUND(S6, S7) \
+	AES_ROUND(S5, S6) \
+	AES_ROUND(S4, S5) \
+	PXOR       M1, S4 \
+	AES_ROUND(S3, S4) \
+	AES_ROUND(S2, S3) \
+	AES_ROUND(S1, S2) \
+	AES_ROUND(S0, S1) \
+	PXOR       M0, S0 \
+	AES_ROUND(T0, S0)
+
+// z0 = S6 ^ S1 ^ (S2 & S3), z1 = S2 ^ S5 ^ (S6 & S7)
+#define KEYSTREAM_128L() \
+	MOVO S2, Z0 \
+	PAND S3, Z0 \
+	PXOR S6, Z0 \
+	PXOR S1, Z0 \
+	MOVO S6, Z1 \
+	PAND S7, Z1 \
+	PXOR S2, Z1 \
+	PXOR S5, Z1
+
+#define LOAD_STATE_256() \
+	MOVOU 0(AX), S0  \
+	MOVOU 16(AX), S1 \
+	MOVOU 32(AX), S2 \
+	MOVOU 48(AX), S3 \
+	MOVOU 64(AX), S4 \
+	MOVOU 80(AX), S5
+
+#define STORE_STATE_256() \
+	MOVOU S0, 0(AX)  \
+	MOVOU S1, 16(AX) \
+	MOVOU S2, 32(AX) \
+	MOVOU S3, 48(AX) \
+	MOVOU S4, 64(AX) \
+	MOVOU S5, 80(AX)
+
+#define UPDATE_256() \
+	MOVO       S5, T0 \
+	AES_ROUND(S4, S5) \
+	AES_ROUND(S3, S4) \
+	AES_ROUND(S2, S3) \
+	AES_ROUND(S1, S2) \
+	AES_ROUND(S0, S1) \
+	PXOR       M0, S0 \
+	AES_ROUND(T0, S0)
+
+// z = S1 ^ S4 ^ S5 ^ (S2 & S3)
+#define KEYSTREAM_256() \
+	MOVO S2, Z0 \
+	PAND S3, Z0 \
+	PXOR S1, Z0 \
+	PXOR S4, Z0 \
+	PXOR S5, Z0
+
+#define CLEAR_TEMPORARIES() \
+	PXOR T0, T0 \
+	PXOR T1, T1 \
+	PXOR M0, M0 \
+	PXOR M1, M1 \
+	PXOR Z0, Z0 \
+	PXOR Z1, Z1
+
+// func absorb128LAESNI(s *state, src []byte)
+TEXT ·absorb128LAESNI(SB), NOSPLIT, $0-32
+	MOVQ s+0(FP), AX
+	MOVQ src_base+8(FP), SI
+	MOVQ src_len+16(FP), CX
+	LOAD_STATE_128L()
+
+absorb128LLoop:
+	CMPQ  CX, $32
+	JB    absorb128LDone
+	MOVOU 0(SI), M0
+	MOVOU 16(SI), M1
+	UPDATE_128L()
+	ADDQ  $32, SI
+	SUBQ  $32, CX
+	JMP   absorb128LLoop
+
+absorb128LDone:
+	STORE_STATE_128L()
+	CLEAR_TEMPORARIES()
+	RET
+
+// func enc128LAESNI(s *state, dst, src []byte)
+TEXT ·enc128LAESNI(SB), NOSPLIT, $0-56
+	MOVQ s+0(FP), AX
+	MOVQ dst_base+8(FP), DI
+	MOVQ src_base+32(FP), SI
+	MOVQ src_len+40(FP), CX
+	LOAD_STATE_128L()
+
+enc128LLoop:
+	CMPQ  CX, $32
+	JB    enc128LDone
+	MOVOU 0(SI), M0
+	MOVOU 16(SI), M1
+	KEYSTREAM_128L()
+	PXOR  M0, Z0
+	PXOR  M1, Z1
+	MOVOU Z0, 0(DI)
+	MOVOU Z1, 16(DI)
+	UPDATE_128L()
+	ADDQ  $32, SI
+	ADDQ  $32, DI
+	SUBQ  $32, CX
+	JMP   enc128LLoop
+
+enc128LDone:
+	STORE_STATE_128L()
+	CLEAR_TEMPORARIES()
+	RET
+
+// func dec128LAESNI(s *state, dst, src []byte)
+TEXT ·dec128LAESNI(SB), NOSPLIT, $0-56
+	MOVQ s+0(FP), AX
+	MOVQ dst_base+8(FP), DI
+	MOVQ src_base+32(FP), SI
+	MOVQ src_len+40(FP), CX
+	LOAD_STATE_128L()
+
+dec128LLoop:
+	CMPQ  CX, $32
+	JB    dec128LDone
+	MOVOU 0(SI), M0
+	MOVOU 16(SI), M1
+	KEYSTREAM_128L()
+	PXOR  Z0, M0
+	PXOR  Z1, M1
+	MOVOU M0, 0(DI)
+	MOVOU M1, 16(DI)
+	UPDATE_128L()
+	ADDQ  $32, SI
+	ADDQ  $32, DI
+	SUBQ  $32, CX
+	JMP   dec128LLoop
+
+dec128LDone:
+	STORE_STATE_128L()
+	CLEAR_TEMPORARIES()
+	RET
+
+// func absorb256AESNI(s *state, src []byte)
+TEXT ·absorb256AESNI(SB), NOSPLIT, $0-32
+	MOVQ s+0(FP), AX
+	MOVQ src_base+8(FP), SI
+	MOVQ src_len+16(FP), CX
+	LOAD_STATE_256()
+
+absorb256Loop:
+	CMPQ  CX, $16
+	JB    absorb256Done
+	MOVOU 0(SI), M0
+	UPDATE_256()
+	ADDQ  $16, SI
+	SUBQ  $16, CX
+	JMP   absorb256Loop
+
+absorb256Done:
+	STORE_STATE_256()
+	CLEAR_TEMPORARIES()
+	RET
+
+// func enc256AESNI(s *state, dst, src []byte)
+TEXT ·enc256AESNI(SB), NOSPLIT, $0-56
+	MOVQ s+0(FP), AX
+	MOVQ dst_base+8(FP), DI
+	MOVQ src_base+32(FP), SI
+	MOVQ src_len+40(FP), CX
+	LOAD_STATE_256()
+
+enc256Loop:
+	CMPQ  CX, $16
+	JB    enc256Done
+	MOVOU 0(SI), M0
+	KEYSTREAM_256()
+	PXOR  M0, Z0
+	MOVOU Z0, 0(DI)
+	UPDATE_256()
+	ADDQ  $16, SI
+	ADDQ  $16, DI
+	SUBQ  $16, CX
+	JMP   enc256Loop
+
+enc256Done:
+	STORE_STATE_256()
+	CLEAR_TEMPORARIES()
+	RET
+
+// func dec256AESNI(s *state, dst, src []byte)
+TEXT ·dec256AESNI(SB), NOSPLIT, $0-56
+	MOVQ s+0(FP), AX
+	MOVQ dst_base+8(FP), DI
+	MOVQ src_base+32(FP), SI
+	MOVQ src_len+40(FP), CX
+	LOAD_STATE_256()
+
+dec256Loop:
+	CMPQ  CX, $16
+	JB    dec256Done
+	MOVOU 0(SI), M0
+	KEYSTREAM_256()
+	PXOR  Z0, M0
+	MOVOU M0, 0(DI)
+	UPDATE_256()
+	ADDQ  $16, SI
+	ADDQ  $16, DI
+	SUBQ  $16, CX
+	JMP   dec256Loop
+
+dec256Done:
+	STORE_STATE_256()
+	CLEAR_TEMPORARIES()
+	RET
@@ -10,43 +10,54 @@ package aez
 import (
 	"crypto/cipher"
 	"errors"
+	"math"
 )
 
-var errOpen = errors.New("aez: Message authentication failed")
+var (
+	errOpen             = errors.New("aez: Message authentication failed")
+	errInvalidKeySize   = errors.New("aez: Invalid key size")
+	errInvalidNonceSize = errors.New("aez: Invalid nonce size")
+	errInvalidTagSize   = errors.New("aez: Invalid tag size")
+)
 
 const (
 	aeadNonceSize = 16
 	aeadOverhead  = 16
+
+	// maxTagSize keeps the tag length in bits representable in the 32 bit
+	// encoding used by AEZ-hash.
+	maxTagSize = math.MaxUint32 / 8
 )
 
-// AeadAEZ is AEZ wrapped in the crypto/cipher.AEAD interface.  It expects
-// a 16 byte nonce, and uses a 16 byte tag, per the recommended defaults in
-// the specification.
+// AeadAEZ is AEZ wrapped in the crypto/cipher.AEAD interface.  When created
+// with New, it expects a 16 byte nonce, and uses a 16 byte tag, per the
+// recommended defaults in the specification.  NewAEAD allows other nonce and
+// tag lengths.
 //
-// The AEZ primitive itself supports a vector of authenticated data, variable
-// length nonces, and variable length authentication tags.  Users who require
-// such functionality should investigate the one-shot Encrypt/Decrypt calls
-// instead.
+// The AEZ primitive itself supports a vector of authenticated data, which is
+// available via SealVector and OpenVector.
 type AeadAEZ struct {
-	key [extractedKeySize]byte
+	c         Cipher
+	nonceSize int
+	tagSize   int
 }
 
 // NonceSize returns the size of the nonce that must be passed to Seal
 // and Open.
 func (a *AeadAEZ) NonceSize() int {
-	return aeadNonceSize
+	return a.nonceSize
 }
 
 // Overhead returns the maximum difference between the lengths of a
 // plaintext and its ciphertext.
 func (a *AeadAEZ) Overhead() int {
-	return aeadOverhead
+	return a.tagSize
 }
 
 // Reset clears the sensitive keying material from the datastructure such
 // that it will no longer be in memory.
 func (a *AeadAEZ) Reset() {
-	memwipe(a.key[:])
+	a.c.Reset()
 }
 
 // Seal encrypts and authenticates plaintext, authenticates the
@@ -57,19 +68,7 @@ func (a *AeadAEZ) Reset() {
 // however the AEZ primitive does provide nonce-reuse misuse-resistance,
 // see the paper for more details (MRAE).
 func (a *AeadAEZ) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
-	if len(nonce) != aeadNonceSize {
-		panic("aez: incorrect nonce length given to AEZ")
-	}
-
-	var ad [][]byte
-	if additionalData != nil {
-		ad = append(ad, additionalData)
-	}
-	// WARNING: The AEAD interface expects plaintext/dst overlap to be allowed.
-	c := Encrypt(a.key[:], nonce, ad, aeadOverhead, plaintext, nil)
-	dst = append(dst, c...)
-
-	return dst
+	return a.c.seal(dst, nonce, plaintext, adVector(additionalData), a.nonceSize, a.tagSize)
 }
 
 // Open decrypts and authenticates ciphertext, authenticates the
@@ -78,31 +77,46 @@ func (a *AeadAEZ) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
 // bytes long and both it and the additional data must match the
 // value passed to Seal.
 func (a *AeadAEZ) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
-	if len(nonce) != aeadNonceSize {
-		panic("aez: incorrect nonce length given to AEZ")
-	}
+	return a.c.open(dst, nonce, ciphertext, adVector(additionalData), a.nonceSize, a.tagSize)
+}
 
-	var ad [][]byte
-	if additionalData != nil {
-		ad = append(ad, additionalData)
-	}
-	// WARNING: The AEAD interface expects ciphertext/dst overlap to be allowed.
-	d, ok := Decrypt(a.key[:], nonce, ad, aeadOverhead, ciphertext, nil)
-	if !ok {
-		return nil, errOpen
-	}
-	dst = append(dst, d...)
+// SealVector is Seal, with a vector of additional data.  Each element is
+// authenticated separately, so the vector {"ab"} is distinct from the
+// vector {"a", "b"}.
+func (a *AeadAEZ) SealVector(dst, nonce, plaintext []byte, additionalData [][]byte) []byte {
+	return a.c.seal(dst, nonce, plaintext, additionalData, a.nonceSize, a.tagSize)
+}
 
-	return dst, nil
+// OpenVector is Open, with a vector of additional data, which must match
+// the vector passed to SealVector.
+func (a *AeadAEZ) OpenVector(dst, nonce, ciphertext []byte, additionalData [][]byte) ([]byte, error) {
+	return a.c.open(dst, nonce, ciphertext, additionalData, a.nonceSize, a.tagSize)
 }
 
 // New returns AEZ wrapped in a new cipher.AEAD instance, with the recommended
 // nonce and tag lengths.
 func New(key []byte) (cipher.AEAD, error) {
+	return NewAEAD(key, aeadNonceSize, aeadOverhead)
+}
+
+// NewAEAD returns AEZ wrapped in a new AeadAEZ instance, with the specified
+// nonce and tag lengths in bytes.  Both must be at least 1.  Since AEZ
+// authenticates by checking tagSize bytes of redundancy, longer tags give
+// proportionally stronger authenticity.
+func NewAEAD(key []byte, nonceSize, tagSize int) (*AeadAEZ, error) {
 	if len(key) == 0 {
-		return nil, errors.New("aez: Invalid key size")
+		return nil, errInvalidKeySize
+	}
+	if nonceSize < 1 {
+		return nil, errInvalidNonceSize
+	}
+	if tagSize < 1 || tagSize > maxTagSize {
+		return nil, errInvalidTagSize
+	}
+	a := &AeadAEZ{
+		nonceSize: nonceSize,
+		tagSize:   tagSize,
 	}
-	a := new(AeadAEZ)
-	extract(key, &a.key)
+	a.c.e.init(key)
 	return a, nil
 }
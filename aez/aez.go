@@ -68,6 +68,8 @@ type eState struct {
 	J   [3][16]byte // 1J, 2J, 4J
 	L   [8][16]byte // 0L, 1L ... 7L
 	aes aesImpl
+
+	workers int // The number of goroutines the core passes may use.
 }
 
 func (e *eState) init(k []byte) {
@@ -97,6 +99,7 @@ func (e *eState) init(k []byte) {
 	xorBytes1x16(e.L[6][:], e.L[1][:], e.L[7][:]) // L7 = L6+L1
 
 	e.aes = newAes(&extractedKey)
+	e.initPlatform()
 }
 
 func (e *eState) reset() {
@@ -109,7 +112,38 @@ func (e *eState) reset() {
 	for i := range e.L {
 		memwipe(e.L[i][:])
 	}
-	e.aes.Reset()
+	if e.aes != nil {
+		e.aes.Reset()
+	}
+}
+
+// aes4Slow and aes10Slow call the portable round functions via a type switch
+// rather than the aesImpl interface, as escape analysis can not see through
+// an interface call, and would move every temporary passed to it to the heap.
+func (e *eState) aes4Slow(j, i, l *[blockSize]byte, src []byte, dst *[blockSize]byte) {
+	switch a := e.aes.(type) {
+	case *roundB64:
+		a.AES4(j, i, l, src, dst)
+	case *roundB32:
+		a.AES4(j, i, l, src, dst)
+	case *roundVartime:
+		a.AES4(j, i, l, src, dst)
+	default:
+		panic("aez: unsupported aesImpl")
+	}
+}
+
+func (e *eState) aes10Slow(l *[blockSize]byte, src []byte, dst *[blockSize]byte) {
+	switch a := e.aes.(type) {
+	case *roundB64:
+		a.AES10(l, src, dst)
+	case *roundB32:
+		a.AES10(l, src, dst)
+	case *roundVartime:
+		a.AES10(l, src, dst)
+	default:
+		panic("aez: unsupported aesImpl")
+	}
 }
 
 func multBlock(x uint, src, dst *[blockSize]byte) {
@@ -140,7 +174,7 @@ func doubleBlock(p *[blockSize]byte) {
 }
 
 func (e *eState) aezHash(nonce []byte, ad [][]byte, tau int, result []byte) {
-	var buf, sum, I, J [blockSize]byte
+	var buf, sum, J [blockSize]byte
 
 	if len(result) != blockSize {
 		panic("aez: Hash: len(result)")
@@ -148,127 +182,139 @@ func (e *eState) aezHash(nonce []byte, ad [][]byte, tau int, result []byte) {
 
 	// Initialize sum with hash of tau
 	binary.BigEndian.PutUint32(buf[12:], uint32(tau))
-	xorBytes1x16(e.J[0][:], e.J[1][:], J[:])       // J ^ J2
-	e.aes.AES4(&J, &e.I[1], &e.L[1], buf[:], &sum) // E(3,1)
+	xorBytes1x16(e.J[0][:], e.J[1][:], J[:])   // J ^ J2
+	e.aes4(&J, &e.I[1], &e.L[1], buf[:], &sum) // E(3,1)
 
 	// Hash nonce, accumulate into sum
-	empty := len(nonce) == 0
-	n := nonce
-	nBytes := uint(len(nonce))
-	copy(I[:], e.I[1][:])
-	for i := uint(1); nBytes >= blockSize; i, nBytes = i+1, nBytes-blockSize {
-		e.aes.AES4(&e.J[2], &I, &e.L[i%8], n[:blockSize], &buf) // E(4,i)
-		xorBytes1x16(sum[:], buf[:], sum[:])
-		n = n[blockSize:]
-		if i%8 == 0 {
-			doubleBlock(&I)
-		}
-	}
-	if nBytes > 0 || empty {
-		memwipe(buf[:])
-		copy(buf[:], n)
-		buf[nBytes] = 0x80
-		e.aes.AES4(&e.J[2], &e.I[0], &e.L[0], buf[:], &buf) // E(4,0)
-		xorBytes1x16(sum[:], buf[:], sum[:])
-	}
+	e.aezHashElement(&e.J[2], nonce, &sum) // E(4,i)
 
 	// Hash each vector element, accumulate into sum
 	for k, p := range ad {
-		empty = len(p) == 0
-		bytes := uint(len(p))
-		copy(I[:], e.I[1][:])
 		multBlock(uint(5+k), &e.J[0], &J) // XXX/performance.
-		for i := uint(1); bytes >= blockSize; i, bytes = i+1, bytes-blockSize {
-			e.aes.AES4(&J, &I, &e.L[i%8], p[:blockSize], &buf) // E(5+k,i)
-			xorBytes1x16(sum[:], buf[:], sum[:])
-			p = p[blockSize:]
-			if i%8 == 0 {
-				doubleBlock(&I)
-			}
-		}
-		if bytes > 0 || empty {
-			memwipe(buf[:])
-			copy(buf[:], p)
-			buf[bytes] = 0x80
-			e.aes.AES4(&J, &e.I[0], &e.L[0], buf[:], &buf) // E(5+k,0)
-			xorBytes1x16(sum[:], buf[:], sum[:])
-		}
+		e.aezHashElement(&J, p, &sum)     // E(5+k,i)
 	}
 
-	memwipe(I[:])
 	memwipe(J[:])
 
 	copy(result, sum[:])
 }
 
+// aezHashElement accumulates the hash of a single element of the input
+// vector, with the element's J multiple, into sum.
+func (e *eState) aezHashElement(J *[blockSize]byte, p []byte, sum *[blockSize]byte) {
+	var buf [blockSize]byte
+
+	n := len(p) &^ (blockSize - 1)
+	if n > 0 {
+		e.aezHashBlocks(J, &e.I[1], p[:n], sum)
+	}
+	if len(p) > n || len(p) == 0 {
+		copy(buf[:], p[n:])
+		buf[len(p)-n] = 0x80
+		e.aes4(J, &e.I[0], &e.L[0], buf[:], &buf) // E(j,0)
+		xorBytes1x16(sum[:], buf[:], sum[:])
+		memwipe(buf[:])
+	}
+}
+
+// aezHashBlocksSlow accumulates the hash of the full blocks in src, starting
+// at block index 1 with the I multiple startI, into sum.
+func (e *eState) aezHashBlocksSlow(J, startI *[blockSize]byte, src []byte, sum *[blockSize]byte) {
+	var buf, I [blockSize]byte
+
+	copy(I[:], startI[:])
+	for i := uint(1); len(src) >= blockSize; i++ {
+		e.aes4(J, &I, &e.L[i%8], src[:blockSize], &buf) // E(j,i)
+		xorBytes1x16(sum[:], buf[:], sum[:])
+		src = src[blockSize:]
+		if i%8 == 0 {
+			doubleBlock(&I)
+		}
+	}
+
+	memwipe(I[:])
+	memwipe(buf[:])
+}
+
 func (e *eState) aezPRF(delta *[blockSize]byte, tau int, result []byte) {
+	memwipe(result[:tau])
+	e.aezPRFXor(delta, tau, result)
+}
+
+// aezPRFXor is aezPRF, except that the output is XORed into result, so that
+// the expected tag can be checked in place.
+func (e *eState) aezPRFXor(delta *[blockSize]byte, tau int, result []byte) {
 	var buf, ctr [blockSize]byte
 
-	off := 0
-	for tau >= blockSize {
+	n := tau / blockSize
+	if n > 0 {
+		e.aezPRFBlocks(delta, result[:n*blockSize])
+	}
+	if off := n * blockSize; tau > off {
+		binary.BigEndian.PutUint64(ctr[8:], uint64(n))
 		xorBytes1x16(delta[:], ctr[:], buf[:])
-		e.aes.AES10(&e.L[3], buf[:], &buf) // E(-1,3)
-		copy(result[off:], buf[:])
-
-		i := 15
-		for { // ctr += 1
-			ctr[i]++
-			i--
-			if ctr[i+1] != 0 {
-				break
-			}
-		}
+		e.aes10(&e.L[3], buf[:], &buf) // E(-1,3)
 
-		tau -= blockSize
-		off += blockSize
+		xorBytes(result[off:], buf[:], result[off:tau])
 	}
-	if tau > 0 {
-		xorBytes1x16(delta[:], ctr[:], buf[:])
-		e.aes.AES10(&e.L[3], buf[:], &buf) // E(-1,3)
 
-		copy(result[off:], buf[:])
+	memwipe(buf[:])
+}
+
+// aezPRFBlocksSlow XORs the first len(result)/16 blocks of AEZ-prf output
+// into result.  The counter is a 128 bit value, but the tag length is encoded
+// in 32 bits by AEZ-hash, so the upper 64 bits are always zero.
+func (e *eState) aezPRFBlocksSlow(delta *[blockSize]byte, result []byte) {
+	var buf, ctr [blockSize]byte
+
+	for i := uint64(0); len(result) >= blockSize; i++ {
+		binary.BigEndian.PutUint64(ctr[8:], i)
+		xorBytes1x16(delta[:], ctr[:], buf[:])
+		e.aes10(&e.L[3], buf[:], &buf) // E(-1,3)
+		xorBytes1x16(result, buf[:], result[:blockSize])
+		result = result[blockSize:]
 	}
 
 	memwipe(buf[:])
 }
 
-func (e *eState) aezCorePass1Slow(in, out []byte, X *[blockSize]byte, sz int) {
+func (e *eState) aezCorePass1Slow(in, out []byte, X, startI *[blockSize]byte, sz int) {
 	// NB: The hardware accelerated case is handled prior to this function.
 
 	// Use one of the portable bitsliced options if possible.
 	switch a := e.aes.(type) {
 	case *roundB32:
-		a.aezCorePass1(e, in, out, X, sz)
+		a.aezCorePass1(e, in, out, X, startI, sz)
 	case *roundB64:
-		a.aezCorePass1(e, in, out, X, sz)
+		a.aezCorePass1(e, in, out, X, startI, sz)
 	default:
-		e.aezCorePass1Ref(in, out, X)
+		e.aezCorePass1Ref(in, out, X, startI, sz)
 	}
 }
 
-func (e *eState) aezCorePass2Slow(in, out []byte, Y, S *[blockSize]byte, sz int) {
+func (e *eState) aezCorePass2Slow(in, out []byte, Y, S, startI *[blockSize]byte, sz int) {
 	// NB: The hardware accelerated case is handled prior to this function.
 
 	// Use one of the portable bitsliced options if possible.
 	switch a := e.aes.(type) {
 	case *roundB32:
-		a.aezCorePass2(e, out, Y, S, sz)
+		a.aezCorePass2(e, out, Y, S, startI, sz)
 	case *roundB64:
-		a.aezCorePass2(e, out, Y, S, sz)
+		a.aezCorePass2(e, out, Y, S, startI, sz)
 	default:
-		e.aezCorePass2Ref(in, out, Y, S)
+		e.aezCorePass2Ref(out, Y, S, startI, sz)
 	}
 }
 
-func (e *eState) aezCorePass1Ref(in, out []byte, X *[blockSize]byte) {
+func (e *eState) aezCorePass1Ref(in, out []byte, X, startI *[blockSize]byte, sz int) {
 	var tmp, I [blockSize]byte
 
-	copy(I[:], e.I[1][:])
-	for i, inBytes := uint(1), len(in); inBytes >= 64; i, inBytes = i+1, inBytes-32 {
-		e.aes.AES4(&e.J[0], &I, &e.L[i%8], in[blockSize:blockSize*2], &tmp) // E(1,i)
+	copy(I[:], startI[:])
+	for i := uint(1); sz > 0; i, sz = i+1, sz-32 {
+		e.aes4(&e.J[0], &I, &e.L[i%8], in[blockSize:blockSize*2], &tmp) // E(1,i)
 		xorBytes1x16(in[:], tmp[:], out[:blockSize])
 
-		e.aes.AES4(&zero, &e.I[0], &e.L[0], out[:blockSize], &tmp) // E(0,0)
+		e.aes4(&zero, &e.I[0], &e.L[0], out[:blockSize], &tmp) // E(0,0)
 		xorBytes1x16(in[blockSize:], tmp[:], out[blockSize:blockSize*2])
 		xorBytes1x16(out[blockSize:], X[:], X[:])
 
@@ -282,25 +328,25 @@ func (e *eState) aezCorePass1Ref(in, out []byte, X *[blockSize]byte) {
 	memwipe(I[:])
 }
 
-func (e *eState) aezCorePass2Ref(in, out []byte, Y, S *[blockSize]byte) {
+func (e *eState) aezCorePass2Ref(out []byte, Y, S, startI *[blockSize]byte, sz int) {
 	var tmp, I [blockSize]byte
 
-	copy(I[:], e.I[1][:])
-	for i, inBytes := uint(1), len(in); inBytes >= 64; i, inBytes = i+1, inBytes-32 {
-		e.aes.AES4(&e.J[1], &I, &e.L[i%8], S[:], &tmp) // E(2,i)
+	copy(I[:], startI[:])
+	for i := uint(1); sz > 0; i, sz = i+1, sz-32 {
+		e.aes4(&e.J[1], &I, &e.L[i%8], S[:], &tmp) // E(2,i)
 		xorBytes1x16(out, tmp[:], out[:blockSize])
 		xorBytes1x16(out[blockSize:], tmp[:], out[blockSize:blockSize*2])
 		xorBytes1x16(out, Y[:], Y[:])
 
-		e.aes.AES4(&zero, &e.I[0], &e.L[0], out[blockSize:blockSize*2], &tmp) // E(0,0)
+		e.aes4(&zero, &e.I[0], &e.L[0], out[blockSize:blockSize*2], &tmp) // E(0,0)
 		xorBytes1x16(out, tmp[:], out[:blockSize])
 
-		e.aes.AES4(&e.J[0], &I, &e.L[i%8], out[:blockSize], &tmp) // E(1,i)
+		e.aes4(&e.J[0], &I, &e.L[i%8], out[:blockSize], &tmp) // E(1,i)
 		xorBytes1x16(out[blockSize:], tmp[:], out[blockSize:blockSize*2])
 
 		swapBlocks(&tmp, out)
 
-		in, out = in[32:], out[32:]
+		out = out[32:]
 		if i%8 == 0 {
 			doubleBlock(&I)
 		}
@@ -326,28 +372,28 @@ func (e *eState) aezCore(delta *[blockSize]byte, in []byte, d uint, out []byte)
 	// Compute X and store intermediate results
 	// Pass 1 over in[0:-32], store intermediate values in out[0:-32]
 	if len(in) >= 64 {
-		e.aezCorePass1(in, out, &X, initialBytes)
+		e.corePass1(in, out, &X, initialBytes, parallelMinShard)
 	}
 
 	// Finish X calculation
 	in = in[initialBytes:]
 	if fragBytes >= blockSize {
-		e.aes.AES4(&zero, &e.I[1], &e.L[4], in[:blockSize], &tmp) // E(0,4)
+		e.aes4(&zero, &e.I[1], &e.L[4], in[:blockSize], &tmp) // E(0,4)
 		xorBytes1x16(X[:], tmp[:], X[:])
 		oneZeroPad(in[blockSize:], fragBytes-blockSize, &tmp)
-		e.aes.AES4(&zero, &e.I[1], &e.L[5], tmp[:], &tmp) // E(0,5)
+		e.aes4(&zero, &e.I[1], &e.L[5], tmp[:], &tmp) // E(0,5)
 		xorBytes1x16(X[:], tmp[:], X[:])
 	} else if fragBytes > 0 {
 		oneZeroPad(in, fragBytes, &tmp)
-		e.aes.AES4(&zero, &e.I[1], &e.L[4], tmp[:], &tmp) // E(0,4)
+		e.aes4(&zero, &e.I[1], &e.L[4], tmp[:], &tmp) // E(0,4)
 		xorBytes1x16(X[:], tmp[:], X[:])
 	}
 
 	// Calculate S
 	out, in = outOrig[len(inOrig)-32:], inOrig[len(inOrig)-32:]
-	e.aes.AES4(&zero, &e.I[1], &e.L[(1+d)%8], in[blockSize:2*blockSize], &tmp) // E(0,1+d)
+	e.aes4(&zero, &e.I[1], &e.L[(1+d)%8], in[blockSize:2*blockSize], &tmp) // E(0,1+d)
 	xorBytes4x16(X[:], in[:], delta[:], tmp[:], out[:blockSize])
-	e.aes.AES10(&e.L[(1+d)%8], out[:blockSize], &tmp) // E(-1,1+d)
+	e.aes10(&e.L[(1+d)%8], out[:blockSize], &tmp) // E(-1,1+d)
 	xorBytes1x16(in[blockSize:], tmp[:], out[blockSize:blockSize*2])
 	xorBytes1x16(out, out[blockSize:], S[:])
 	// XXX/performance: Early abort if tag is corrupted.
@@ -355,42 +401,42 @@ func (e *eState) aezCore(delta *[blockSize]byte, in []byte, d uint, out []byte)
 	// Pass 2 over intermediate values in out[32..]. Final values written
 	out, in = outOrig, inOrig
 	if len(in) >= 64 {
-		e.aezCorePass2(in, out, &Y, &S, initialBytes)
+		e.corePass2(in, out, &Y, &S, initialBytes, parallelMinShard)
 	}
 
 	// Finish Y calculation and finish encryption of fragment bytes
 	out, in = out[initialBytes:], in[initialBytes:]
 	if fragBytes >= blockSize {
-		e.aes.AES10(&e.L[4], S[:], &tmp) // E(-1,4)
+		e.aes10(&e.L[4], S[:], &tmp) // E(-1,4)
 		xorBytes1x16(in, tmp[:], out[:blockSize])
-		e.aes.AES4(&zero, &e.I[1], &e.L[4], out[:blockSize], &tmp) // E(0,4)
+		e.aes4(&zero, &e.I[1], &e.L[4], out[:blockSize], &tmp) // E(0,4)
 		xorBytes1x16(Y[:], tmp[:], Y[:])
 
 		out, in = out[blockSize:], in[blockSize:]
 		fragBytes -= blockSize
 
-		e.aes.AES10(&e.L[5], S[:], &tmp)      // E(-1,5)
+		e.aes10(&e.L[5], S[:], &tmp)          // E(-1,5)
 		xorBytes(in, tmp[:], tmp[:fragBytes]) // non-16 byte xorBytes()
 		copy(out, tmp[:fragBytes])
 		memwipe(tmp[fragBytes:])
 		tmp[fragBytes] = 0x80
-		e.aes.AES4(&zero, &e.I[1], &e.L[5], tmp[:], &tmp) // E(0,5)
+		e.aes4(&zero, &e.I[1], &e.L[5], tmp[:], &tmp) // E(0,5)
 		xorBytes1x16(Y[:], tmp[:], Y[:])
 	} else if fragBytes > 0 {
-		e.aes.AES10(&e.L[4], S[:], &tmp)      // E(-1,4)
+		e.aes10(&e.L[4], S[:], &tmp)          // E(-1,4)
 		xorBytes(in, tmp[:], tmp[:fragBytes]) // non-16 byte xorBytes()
 		copy(out, tmp[:fragBytes])
 		memwipe(tmp[fragBytes:])
 		tmp[fragBytes] = 0x80
-		e.aes.AES4(&zero, &e.I[1], &e.L[4], tmp[:], &tmp) // E(0,4)
+		e.aes4(&zero, &e.I[1], &e.L[4], tmp[:], &tmp) // E(0,4)
 		xorBytes1x16(Y[:], tmp[:], Y[:])
 	}
 
 	// Finish encryption of last two blocks
 	out = outOrig[len(inOrig)-32:]
-	e.aes.AES10(&e.L[(2-d)%8], out[blockSize:], &tmp) // E(-1,2-d)
+	e.aes10(&e.L[(2-d)%8], out[blockSize:], &tmp) // E(-1,2-d)
 	xorBytes1x16(out, tmp[:], out[:blockSize])
-	e.aes.AES4(&zero, &e.I[1], &e.L[(2-d)%8], out[:blockSize], &tmp) // E(0,2-d)
+	e.aes4(&zero, &e.I[1], &e.L[(2-d)%8], out[:blockSize], &tmp) // E(0,2-d)
 	xorBytes4x16(tmp[:], out[blockSize:], delta[:], Y[:], out[blockSize:])
 	copy(tmp[:], out[:blockSize])
 	copy(out[:blockSize], out[blockSize:])
@@ -402,87 +448,165 @@ func (e *eState) aezCore(delta *[blockSize]byte, in []byte, d uint, out []byte)
 }
 
 func (e *eState) aezTiny(delta *[blockSize]byte, in []byte, d uint, out []byte) {
-	var rounds, i, j uint
-	var buf [2 * blockSize]byte
-	var L, R [blockSize]byte
-	var step int
-	mask, pad := byte(0x00), byte(0x80)
-	defer memwipe(L[:])
-	defer memwipe(R[:])
+	var t tinyState
+	defer t.reset()
 
-	var tmp [16]byte
+	if t.init(delta, in, d) {
+		e.aes4(&zero, &e.I[1], &e.L[3], t.buf[:], &t.tmp) // E(0,3)
+		t.fixL()
+	}
+	e.aezTinyRounds(&t.L, &t.R, &t.M, &t.C, &e.L[t.i], t.rounds, t.j, t.step)
+	if t.finish(delta, d, out) {
+		e.aes4(&zero, &e.I[1], &e.L[3], t.buf[:], &t.tmp) // E(0,3)
+		t.fixOut(out)
+	}
+}
 
-	i = 7
+// tinyState is the state of aezTiny for a single message, split out so that
+// the AES4 calls of several messages may be interleaved.
+type tinyState struct {
+	L, R, M, C [blockSize]byte
+	buf, tmp   [blockSize]byte
+	inBytes    int
+	i, rounds  uint
+	j, step    int
+}
+
+// init splits in into the L and R half blocks, and derives the parameters of
+// the Feistel rounds.  When deciphering a message shorter than a block, it
+// returns true, and E(0,3) of buf must be written to tmp, followed by a call
+// to fixL before the rounds.
+func (t *tinyState) init(delta *[blockSize]byte, in []byte, d uint) bool {
+	mask, pad := byte(0x00), byte(0x80)
+
+	t.i = 7
 	inBytes := len(in)
 	if inBytes == 1 {
-		rounds = 24
+		t.rounds = 24
 	} else if inBytes == 2 {
-		rounds = 16
+		t.rounds = 16
 	} else if inBytes < 16 {
-		rounds = 10
+		t.rounds = 10
 	} else {
-		i, rounds = 6, 8
+		t.i, t.rounds = 6, 8
 	}
+	t.inBytes = inBytes
 
 	// Split (inbytes*8)/2 bits into L and R. Beware: May end in nibble.
-	copy(L[:], in[:(inBytes+1)/2])
-	copy(R[:], in[inBytes/2:inBytes/2+(inBytes+1)/2])
+	copy(t.L[:], in[:(inBytes+1)/2])
+	copy(t.R[:], in[inBytes/2:inBytes/2+(inBytes+1)/2])
 	if inBytes&1 != 0 { // Must shift R left by half a byte
 		for k := uint(0); k < uint(inBytes/2); k++ {
-			R[k] = (R[k] << 4) | (R[k+1] >> 4)
+			t.R[k] = (t.R[k] << 4) | (t.R[k+1] >> 4)
 		}
-		R[inBytes/2] = R[inBytes/2] << 4
+		t.R[inBytes/2] = t.R[inBytes/2] << 4
 		pad = 0x08
 		mask = 0xf0
 	}
-	if d != 0 {
-		if inBytes < 16 {
-			memwipe(buf[:blockSize])
-			copy(buf[:], in)
-			buf[0] |= 0x80
-			xorBytes1x16(delta[:], buf[:], buf[:blockSize])
-			e.aes.AES4(&zero, &e.I[1], &e.L[3], buf[:blockSize], &tmp) // E(0,3)
-			L[0] ^= (tmp[0] & 0x80)
-		}
-		j, step = rounds-1, -1
-	} else {
-		step = 1
+
+	// Each round enciphers the half block, truncated to the valid bits and
+	// padded, so precompute the mask and the padding, which is combined with
+	// delta.
+	for k := 0; k < inBytes/2; k++ {
+		t.M[k] = 0xff
 	}
-	for k := uint(0); k < rounds/2; k, j = k+1, uint(int(j)+2*step) {
-		memwipe(buf[:blockSize])
-		copy(buf[:], R[:(inBytes+1)/2])
-		buf[inBytes/2] = (buf[inBytes/2] & mask) | pad
-		xorBytes1x16(buf[:], delta[:], buf[:blockSize])
-		buf[15] ^= byte(j)
-		e.aes.AES4(&zero, &e.I[1], &e.L[i], buf[:blockSize], &tmp) // E(0,i)
-		xorBytes1x16(L[:], tmp[:], L[:blockSize])
-
-		memwipe(buf[:blockSize])
-		copy(buf[:], L[:(inBytes+1)/2])
-		buf[inBytes/2] = (buf[inBytes/2] & mask) | pad
-		xorBytes1x16(buf[:], delta[:], buf[:blockSize])
-		buf[15] ^= byte(int(j) + step)
-		e.aes.AES4(&zero, &e.I[1], &e.L[i], buf[:blockSize], &tmp) // E(0,i)
-		xorBytes1x16(R[:], tmp[:], R[:blockSize])
-	}
-	copy(buf[:], R[:inBytes/2])
-	copy(buf[inBytes/2:], L[:(inBytes+1)/2])
+	t.M[inBytes/2] = mask
+	t.C[inBytes/2] = pad
+	xorBytes1x16(t.C[:], delta[:], t.C[:])
+
+	if d == 0 {
+		t.step = 1
+		return false
+	}
+	t.j, t.step = int(t.rounds)-1, -1
+	if inBytes >= 16 {
+		return false
+	}
+	copy(t.buf[:], in)
+	t.buf[0] |= 0x80
+	xorBytes1x16(delta[:], t.buf[:], t.buf[:])
+	return true
+}
+
+func (t *tinyState) fixL() {
+	t.L[0] ^= (t.tmp[0] & 0x80)
+}
+
+// roundInput writes the input to half round n of the Feistel rounds to buf,
+// and returns the half block that E(0,i) of it is to be XORed into.
+func (t *tinyState) roundInput(n uint) *[blockSize]byte {
+	src, dst := &t.R, &t.L
+	if n&1 != 0 {
+		src, dst = &t.L, &t.R
+	}
+	for k := range t.buf {
+		t.buf[k] = src[k]&t.M[k] ^ t.C[k]
+	}
+	t.buf[15] ^= byte(t.j + int(n)*t.step)
+	return dst
+}
+
+// finish writes the output of the Feistel rounds to out.  When enciphering a
+// message shorter than a block, it returns true, and E(0,3) of buf must be
+// written to tmp, followed by a call to fixOut.
+func (t *tinyState) finish(delta *[blockSize]byte, d uint, out []byte) bool {
+	var buf [2 * blockSize]byte
+	defer memwipe(buf[:])
+
+	inBytes := t.inBytes
+	copy(buf[:], t.R[:inBytes/2])
+	copy(buf[inBytes/2:], t.L[:(inBytes+1)/2])
 	if inBytes&1 != 0 {
 		for k := inBytes - 1; k > inBytes/2; k-- {
 			buf[k] = (buf[k] >> 4) | (buf[k-1] << 4)
 		}
-		buf[inBytes/2] = (L[0] >> 4) | (R[inBytes/2] & 0xf0)
+		buf[inBytes/2] = (t.L[0] >> 4) | (t.R[inBytes/2] & 0xf0)
 	}
 	copy(out, buf[:inBytes])
-	if inBytes < 16 && d == 0 {
-		memwipe(buf[inBytes:blockSize])
-		buf[0] |= 0x80
-		xorBytes1x16(delta[:], buf[:], buf[:blockSize])
-		e.aes.AES4(&zero, &e.I[1], &e.L[3], buf[:blockSize], &tmp) // E(0,3)
-		out[0] ^= tmp[0] & 0x80
+	if inBytes >= 16 || d != 0 {
+		return false
 	}
+	memwipe(buf[inBytes:blockSize])
+	buf[0] |= 0x80
+	xorBytes1x16(delta[:], buf[:], t.buf[:])
+	return true
+}
 
-	memwipe(tmp[:])
+func (t *tinyState) fixOut(out []byte) {
+	out[0] ^= t.tmp[0] & 0x80
+}
+
+func (t *tinyState) reset() {
+	memwipe(t.L[:])
+	memwipe(t.R[:])
+	memwipe(t.C[:])
+	memwipe(t.buf[:])
+	memwipe(t.tmp[:])
+}
+
+// aezTinyRoundsSlow runs the Feistel rounds of aezTiny over the half blocks L
+// and R, where each half block is masked with M, and XORed with C and the
+// round number before being enciphered with E(0,i).
+func (e *eState) aezTinyRoundsSlow(L, R, M, C, Li *[blockSize]byte, rounds uint, j, step int) {
+	var buf [blockSize]byte
+
+	for k := uint(0); k < rounds/2; k, j = k+1, j+2*step {
+		for n := range buf {
+			buf[n] = R[n]&M[n] ^ C[n]
+		}
+		buf[15] ^= byte(j)
+		e.aes4(&zero, &e.I[1], Li, buf[:], &buf) // E(0,i)
+		xorBytes1x16(L[:], buf[:], L[:])
+
+		for n := range buf {
+			buf[n] = L[n]&M[n] ^ C[n]
+		}
+		buf[15] ^= byte(j + step)
+		e.aes4(&zero, &e.I[1], Li, buf[:], &buf) // E(0,i)
+		xorBytes1x16(R[:], buf[:], R[:])
+	}
+
+	memwipe(buf[:])
 }
 
 func (e *eState) encipher(delta *[blockSize]byte, in, out []byte) {
@@ -512,85 +636,145 @@ func (e *eState) decipher(delta *[blockSize]byte, in, out []byte) {
 // Encrypt encrypts and authenticates the plaintext, authenticates the
 // additional data, and appends the result to ciphertext, returning the
 // updated slice.  The length of the authentication tag in bytes is specified
-// by tau.  The plaintext and dst slices MUST NOT overlap.
+// by tau.  The plaintext and dst slices may overlap exactly, with plaintext
+// starting at dst[len(dst)], or not at all.
 func Encrypt(key []byte, nonce []byte, additionalData [][]byte, tau int, plaintext, dst []byte) []byte {
-	var delta [blockSize]byte
+	var e eState
+	defer e.reset()
 
-	var x []byte
-	dstSz, xSz := len(dst), len(plaintext)+tau
-	if cap(dst) >= dstSz+xSz {
-		dst = dst[:dstSz+xSz]
-	} else {
-		x = make([]byte, dstSz+xSz)
-		copy(x, dst)
-		dst = x
-	}
-	x = dst[dstSz:]
+	e.init(key)
+	return e.encrypt(nonce, additionalData, tau, plaintext, dst)
+}
+
+// Decrypt decrypts and authenticates the ciphertext, authenticates the
+// additional data, and if successful appends the resulting plaintext to the
+// provided slice and returns the updated slice and true.  The length of the
+// expected authentication tag in bytes is specified by tau.  The ciphertext
+// and dst slices may overlap exactly, with ciphertext starting at
+// dst[len(dst)], or not at all.
+func Decrypt(key []byte, nonce []byte, additionalData [][]byte, tau int, ciphertext, dst []byte) ([]byte, bool) {
+	var e eState
+	defer e.reset()
+
+	e.init(key)
+	return e.decrypt(nonce, additionalData, tau, ciphertext, dst)
+}
+
+// Encipher enciphers src with the length preserving tweakable wide-block
+// cipher at the heart of AEZ, and writes the result to dst, which must be at
+// least as long as src.  The first element of tweak is used as the AEZ nonce,
+// and the rest as the associated data, so this is exactly Encrypt with a tau
+// of 0.  An empty tweak is equivalent to a tweak with one empty element.
+//
+// There is no authentication, but since every bit of the output depends on
+// every bit of the input and the tweak, it is suitable for applications such
+// as disk sector or filename encryption, where the length can not change.
+// The src and dst slices may overlap exactly or not at all.
+func Encipher(key []byte, tweak [][]byte, dst, src []byte) {
+	var e eState
+	defer e.reset()
 
+	e.init(key)
+	e.encipherTweak(tweak, dst, src, false)
+}
+
+// Decipher deciphers src, which was produced by Encipher with the same key
+// and tweak, and writes the result to dst, which must be at least as long as
+// src.  The src and dst slices may overlap exactly or not at all.
+func Decipher(key []byte, tweak [][]byte, dst, src []byte) {
 	var e eState
 	defer e.reset()
 
 	e.init(key)
+	e.encipherTweak(tweak, dst, src, true)
+}
+
+func (e *eState) encipherTweak(tweak [][]byte, dst, src []byte, decrypt bool) {
+	var delta [blockSize]byte
+
+	if len(dst) < len(src) {
+		panic("aez: dst is shorter than src")
+	}
+	dst = dst[:len(src)]
+
+	var nonce []byte
+	var ad [][]byte
+	if len(tweak) > 0 {
+		nonce, ad = tweak[0], tweak[1:]
+	}
+
+	e.aezHash(nonce, ad, 0, delta[:])
+	copy(dst, src)
+	if decrypt {
+		e.decipher(&delta, dst, dst)
+	} else {
+		e.encipher(&delta, dst, dst)
+	}
+
+	memwipe(delta[:])
+}
+
+func (e *eState) encrypt(nonce []byte, additionalData [][]byte, tau int, plaintext, dst []byte) []byte {
+	var delta [blockSize]byte
+
 	e.aezHash(nonce, additionalData, tau*8, delta[:])
+	return e.encryptDelta(&delta, tau, plaintext, dst)
+}
+
+// encryptDelta is encrypt, given the AEZ-hash of the nonce, associated data
+// and tau.
+func (e *eState) encryptDelta(delta *[blockSize]byte, tau int, plaintext, dst []byte) []byte {
+	ret, x := sliceForAppend(dst, len(plaintext)+tau)
+
 	if len(plaintext) == 0 {
-		e.aezPRF(&delta, tau, x)
+		e.aezPRF(delta, tau, x)
 	} else {
-		memwipe(x[len(plaintext):])
 		copy(x, plaintext)
-		e.encipher(&delta, x, x)
+		memwipe(x[len(plaintext):])
+		e.encipher(delta, x, x)
 	}
 
-	return dst
+	return ret
 }
 
-// Decrypt decrypts and authenticates the ciphertext, authenticates the
-// additional data, and if successful appends the resulting plaintext to the
-// provided slice and returns the updated slice and true.  The length of the
-// expected authentication tag in bytes is specified by tau.  The ciphertext
-// and dst slices MUST NOT overlap.
-func Decrypt(key []byte, nonce []byte, additionalData [][]byte, tau int, ciphertext, dst []byte) ([]byte, bool) {
+func (e *eState) decrypt(nonce []byte, additionalData [][]byte, tau int, ciphertext, dst []byte) ([]byte, bool) {
 	var delta [blockSize]byte
-	sum := byte(0)
 
 	if len(ciphertext) < tau {
 		return nil, false
 	}
 
-	var x []byte
-	dstSz, xSz := len(dst), len(ciphertext)
-	if cap(dst) >= dstSz+xSz {
-		dst = dst[:dstSz+xSz]
-	} else {
-		x = make([]byte, dstSz+xSz)
-		copy(x, dst)
-		dst = x
-	}
-	x = dst[dstSz:]
+	e.aezHash(nonce, additionalData, tau*8, delta[:])
+	return e.decryptDelta(&delta, tau, ciphertext, dst)
+}
 
-	var e eState
-	defer e.reset()
+// decryptDelta is decrypt, given the AEZ-hash of the nonce, associated data
+// and tau.  The ciphertext must be at least tau bytes long.
+func (e *eState) decryptDelta(delta *[blockSize]byte, tau int, ciphertext, dst []byte) ([]byte, bool) {
+	sum := byte(0)
+
+	ret, x := sliceForAppend(dst, len(ciphertext))
+	copy(x, ciphertext)
 
-	e.init(key)
-	e.aezHash(nonce, additionalData, tau*8, delta[:])
 	if len(ciphertext) == tau {
-		e.aezPRF(&delta, tau, x)
+		e.aezPRFXor(delta, tau, x)
 		for i := 0; i < tau; i++ {
-			sum |= x[i] ^ ciphertext[i]
+			sum |= x[i]
 		}
-		dst = dst[:dstSz]
+		memwipe(x)
+		ret = ret[:len(dst)]
 	} else {
-		e.decipher(&delta, ciphertext, x)
+		e.decipher(delta, x, x)
 		for i := 0; i < tau; i++ {
 			sum |= x[len(ciphertext)-tau+i]
 		}
-		if sum == 0 {
-			dst = dst[:dstSz+len(ciphertext)-tau]
-		}
+		ret = ret[:len(dst)+len(ciphertext)-tau]
 	}
 	if sum != 0 { // return true if valid, false if invalid
+		memwipe(x)
 		return nil, false
 	}
-	return dst, true
+	return ret, true
 }
 
 // IsHardwareAccelerated returns true iff the AEZ implementation will use
@@ -614,6 +798,21 @@ func xorBytes(a, b, dst []byte) {
 	}
 }
 
+// sliceForAppend takes a slice and a requested number of bytes. It returns a
+// slice with the contents of the given slice followed by that many bytes and
+// a second slice that aliases into it and contains only the extra bytes. If
+// the original slice has sufficient capacity then no allocation is performed.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
+
 func swapBlocks(tmp *[blockSize]byte, b []byte) {
 	copy(tmp[:], b[:])
 	copy(b[:blockSize], b[blockSize:])
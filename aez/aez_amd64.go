@@ -35,6 +35,15 @@ func aezCorePass1AMD64AESNI(src, dst, x, i, l, k, consts *byte, sz int)
 //go:noescape
 func aezCorePass2AMD64AESNI(dst, y, s, j, i, l, k, consts *byte, sz int)
 
+//go:noescape
+func aezHashAMD64AESNI(src, sum, j, i, l, k, consts *byte, sz int)
+
+//go:noescape
+func aezPRFAMD64AESNI(delta, l, k, dst *byte, blocks int)
+
+//go:noescape
+func aezTinyAMD64AESNI(l, r, m, c, k *byte, n, idx, step int)
+
 func xorBytes1x16(a, b, dst []byte) {
 	xorBytes1x16AMD64SSE2(&a[0], &b[0], &dst[0])
 }
@@ -45,10 +54,23 @@ func xorBytes4x16(a, b, c, d, dst []byte) {
 
 type roundAESNI struct {
 	keys [extractedKeySize]byte
+
+	// The core passes use aligned loads, so they are passed this copy of
+	// the eState J and L multiples, which unlike the eState, is always on
+	// the heap.  The starting I multiple is loaded unaligned, as it varies
+	// when the passes are split across goroutines.
+	J [3][16]byte
+	L [8][16]byte
 }
 
 func (r *roundAESNI) Reset() {
 	memwipe(r.keys[:])
+	for i := range r.J {
+		memwipe(r.J[i][:])
+	}
+	for i := range r.L {
+		memwipe(r.L[i][:])
+	}
 	resetAMD64SSE2()
 }
 
@@ -67,6 +89,28 @@ func newRoundAESNI(extractedKey *[extractedKeySize]byte) aesImpl {
 	return r
 }
 
+func (e *eState) initPlatform() {
+	if a, ok := e.aes.(*roundAESNI); ok {
+		a.J, a.L = e.J, e.L
+	}
+}
+
+func (e *eState) aes4(j, i, l *[blockSize]byte, src []byte, dst *[blockSize]byte) {
+	if a, ok := e.aes.(*roundAESNI); ok {
+		a.AES4(j, i, l, src, dst)
+		return
+	}
+	e.aes4Slow(j, i, l, src, dst)
+}
+
+func (e *eState) aes10(l *[blockSize]byte, src []byte, dst *[blockSize]byte) {
+	if a, ok := e.aes.(*roundAESNI); ok {
+		a.AES10(l, src, dst)
+		return
+	}
+	e.aes10Slow(l, src, dst)
+}
+
 var dblConsts = [32]byte{
 	// PSHUFB constant
 	0x0f, 0x0e, 0x0d, 0x0c, 0x0b, 0x0a, 0x09, 0x08,
@@ -77,28 +121,54 @@ var dblConsts = [32]byte{
 	0x01, 0x00, 0x00, 0x00, 0x87, 0x00, 0x00, 0x00,
 }
 
-func (e *eState) aezCorePass1(in, out []byte, X *[blockSize]byte, sz int) {
-	// Call the "slow" implementation if hardware/OS doesn't allow AES-NI.
-	if !useAESNI {
-		e.aezCorePass1Slow(in, out, X, sz)
+func (e *eState) aezCorePass1(in, out []byte, X, startI *[blockSize]byte, sz int) {
+	// Call the AES-NI implementation if the key uses it.
+	if a, ok := e.aes.(*roundAESNI); ok {
+		aezCorePass1AMD64AESNI(&in[0], &out[0], &X[0], &startI[0], &a.L[0][0], &a.keys[0], &dblConsts[0], sz)
+		return
+	}
+
+	// Otherwise call the "slow" implementation.
+	e.aezCorePass1Slow(in, out, X, startI, sz)
+}
+
+func (e *eState) aezCorePass2(in, out []byte, Y, S, startI *[blockSize]byte, sz int) {
+	// Call the AES-NI implementation if the key uses it.
+	if a, ok := e.aes.(*roundAESNI); ok {
+		aezCorePass2AMD64AESNI(&out[0], &Y[0], &S[0], &a.J[0][0], &startI[0], &a.L[0][0], &a.keys[0], &dblConsts[0], sz)
 		return
 	}
 
-	// Call the AES-NI implementation.
-	a := e.aes.(*roundAESNI)
-	aezCorePass1AMD64AESNI(&in[0], &out[0], &X[0], &e.I[1][0], &e.L[0][0], &a.keys[0], &dblConsts[0], sz)
+	// Otherwise call the "slow" implementation.
+	e.aezCorePass2Slow(in, out, Y, S, startI, sz)
+}
+
+func (e *eState) aezHashBlocks(J, startI *[blockSize]byte, src []byte, sum *[blockSize]byte) {
+	if a, ok := e.aes.(*roundAESNI); ok {
+		aezHashAMD64AESNI(&src[0], &sum[0], &J[0], &startI[0], &a.L[0][0], &a.keys[0], &dblConsts[0], len(src))
+		return
+	}
+	e.aezHashBlocksSlow(J, startI, src, sum)
 }
 
-func (e *eState) aezCorePass2(in, out []byte, Y, S *[blockSize]byte, sz int) {
-	// Call the "slow" implementation if hardware/OS doesn't allow AES-NI.
-	if !useAESNI {
-		e.aezCorePass2Slow(in, out, Y, S, sz)
+func (e *eState) aezPRFBlocks(delta *[blockSize]byte, result []byte) {
+	if a, ok := e.aes.(*roundAESNI); ok {
+		aezPRFAMD64AESNI(&delta[0], &a.L[3][0], &a.keys[0], &result[0], len(result)/blockSize)
 		return
 	}
+	e.aezPRFBlocksSlow(delta, result)
+}
 
-	// Call the AES-NI implementation.
-	a := e.aes.(*roundAESNI)
-	aezCorePass2AMD64AESNI(&out[0], &Y[0], &S[0], &e.J[0][0], &e.I[1][0], &e.L[0][0], &a.keys[0], &dblConsts[0], sz)
+func (e *eState) aezTinyRounds(L, R, M, C, Li *[blockSize]byte, rounds uint, j, step int) {
+	if a, ok := e.aes.(*roundAESNI); ok {
+		// Fold the I and L multiples into the per-round constant.
+		var c [blockSize]byte
+		xorBytes4x16(C[:], e.I[1][:], Li[:], zero[:], c[:])
+		aezTinyAMD64AESNI(&L[0], &R[0], &M[0], &c[0], &a.keys[0], int(rounds/2), j, step)
+		memwipe(c[:])
+		return
+	}
+	e.aezTinyRoundsSlow(L, R, M, C, Li, rounds, j, step)
 }
 
 func supportsAESNI() bool {
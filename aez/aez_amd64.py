@@ -213,9 +213,9 @@ with Function("aezAES4AMD64AESNI", (j, i, l, k, src, dst), target=uarch.zen):
     xmm_zero = XMMRegister()
 
     MOVDQU(xmm_state, [reg_src])
-    MOVDQA(xmm_j, [reg_j])
-    MOVDQA(xmm_i, [reg_i])
-    MOVDQA(xmm_l, [reg_l])
+    MOVDQU(xmm_j, [reg_j])
+    MOVDQU(xmm_i, [reg_i])
+    MOVDQU(xmm_l, [reg_l])
 
     PXOR(xmm_state, xmm_j)
     PXOR(xmm_i, xmm_l)
@@ -1267,3 +1267,324 @@ with Function("aezCorePass2AMD64AESNI", (dst, y, s, j, i, l, k, consts, sz), tar
     MOV(registers.rsp, reg_sp_save)
 
     RETURN()
+
+sum = Argument(ptr(uint8_t))
+
+with Function("aezHashAMD64AESNI", (src, sum, j, i, l, k, consts, sz), target=uarch.zen):
+    reg_src = GeneralPurposeRegister64()
+    reg_sum = GeneralPurposeRegister64()
+    reg_j = GeneralPurposeRegister64()
+    reg_i = GeneralPurposeRegister64()
+    reg_l = GeneralPurposeRegister64()
+    reg_k = GeneralPurposeRegister64()
+    reg_consts = GeneralPurposeRegister64()
+    reg_bytes = GeneralPurposeRegister64()
+
+    LOAD.ARGUMENT(reg_src, src)       # src pointer
+    LOAD.ARGUMENT(reg_sum, sum)
+    LOAD.ARGUMENT(reg_j, j)           # The element's J multiple
+    LOAD.ARGUMENT(reg_i, i)           # The starting I multiple
+    LOAD.ARGUMENT(reg_l, l)           # e.L[]
+    LOAD.ARGUMENT(reg_k, k)
+    LOAD.ARGUMENT(reg_consts, consts) # doubleBlock constants
+    LOAD.ARGUMENT(reg_bytes, sz)      # bytes remaining
+
+    xmm_sum = XMMRegister()
+    xmm_i = XMMRegister()    # AESENC Round key I
+    xmm_j = XMMRegister()    # AESENC Round key J
+    xmm_l = XMMRegister()    # AESENC Round key L
+    xmm_zero = XMMRegister() # [16]byte{0x00}
+    xmm_jElem = XMMRegister()
+    xmm_iDbl = XMMRegister()
+
+    xmm_o0 = XMMRegister()
+    xmm_o1 = XMMRegister()
+    xmm_o2 = XMMRegister()
+    xmm_o3 = XMMRegister()
+    xmm_o4 = XMMRegister()
+    xmm_o5 = XMMRegister()
+    xmm_o6 = XMMRegister()
+    xmm_o7 = XMMRegister()
+    xmm_ji = XMMRegister()
+
+    MOVDQU(xmm_sum, [reg_sum])
+    MOVDQU(xmm_jElem, [reg_j])
+    MOVDQU(xmm_iDbl, [reg_i])
+    MOVDQA(xmm_i, [reg_k])
+    MOVDQA(xmm_j, [reg_k+16])
+    MOVDQA(xmm_l, [reg_k+32])
+    PXOR(xmm_zero, xmm_zero)
+
+    # Process 8 * 16 bytes at a time in a loop, which covers a full cycle of
+    # e.L[], followed by doubling I.
+    vector_loop128 = Loop()
+    SUB(reg_bytes, 128)
+    JB(vector_loop128.end)
+    with vector_loop128:
+        # o0 = aes4(o0 ^ J ^ I ^ L[1], keys) // E(j,1)
+        # ...
+        # o7 = aes4(o7 ^ J ^ I ^ L[0], keys) // E(j,8)
+        MOVDQU(xmm_o0, [reg_src])
+        MOVDQU(xmm_o1, [reg_src+16])
+        MOVDQU(xmm_o2, [reg_src+32])
+        MOVDQU(xmm_o3, [reg_src+48])
+        MOVDQU(xmm_o4, [reg_src+64])
+        MOVDQU(xmm_o5, [reg_src+80])
+        MOVDQU(xmm_o6, [reg_src+96])
+        MOVDQU(xmm_o7, [reg_src+112])
+        MOVDQA(xmm_ji, xmm_jElem) # ji = J ^ iDbl
+        PXOR(xmm_ji, xmm_iDbl)
+        PXOR(xmm_o0, xmm_ji)
+        PXOR(xmm_o1, xmm_ji)
+        PXOR(xmm_o2, xmm_ji)
+        PXOR(xmm_o3, xmm_ji)
+        PXOR(xmm_o4, xmm_ji)
+        PXOR(xmm_o5, xmm_ji)
+        PXOR(xmm_o6, xmm_ji)
+        PXOR(xmm_o7, xmm_ji)
+        PXOR(xmm_o0, [reg_l+16])  # L[1]
+        PXOR(xmm_o1, [reg_l+32])  # L[2]
+        PXOR(xmm_o2, [reg_l+48])  # L[3]
+        PXOR(xmm_o3, [reg_l+64])  # L[4]
+        PXOR(xmm_o4, [reg_l+80])  # L[5]
+        PXOR(xmm_o5, [reg_l+96])  # L[6]
+        PXOR(xmm_o6, [reg_l+112]) # L[7]
+        PXOR(xmm_o7, [reg_l])     # L[0]
+        aesenc4x8(xmm_o0, xmm_o1, xmm_o2, xmm_o3, xmm_o4, xmm_o5, xmm_o6, xmm_o7, xmm_j, xmm_i, xmm_l, xmm_zero)
+
+        # sum ^= o0 ^ o1 ^ o2 ^ o3 ^ o4 ^ o5 ^ o6 ^ o7
+        PXOR(xmm_sum, xmm_o0)
+        PXOR(xmm_sum, xmm_o1)
+        PXOR(xmm_sum, xmm_o2)
+        PXOR(xmm_sum, xmm_o3)
+        PXOR(xmm_sum, xmm_o4)
+        PXOR(xmm_sum, xmm_o5)
+        PXOR(xmm_sum, xmm_o6)
+        PXOR(xmm_sum, xmm_o7)
+
+        # doubleBlock(I)
+        doubleBlock(xmm_iDbl, xmm_o0, xmm_o1, reg_consts)
+
+        # Update book keeping.
+        ADD(reg_src, 128)
+        SUB(reg_bytes, 128)
+        JAE(vector_loop128.begin)
+    ADD(reg_bytes, 128)
+
+    # Process the remaining (at most 7) blocks one at a time, with I fixed.
+    MOVDQA(xmm_ji, xmm_jElem)
+    PXOR(xmm_ji, xmm_iDbl)
+    ADD(reg_l, 16) # L[1]
+    out = Label()
+    vector_loop16 = Loop()
+    SUB(reg_bytes, 16)
+    JB(out)
+    with vector_loop16:
+        # o0 = aes4(o0 ^ J ^ I ^ L[i], keys) // E(j,i)
+        MOVDQU(xmm_o0, [reg_src])
+        PXOR(xmm_o0, xmm_ji)
+        PXOR(xmm_o0, [reg_l])
+        aesenc4x1(xmm_o0, xmm_j, xmm_i, xmm_l, xmm_zero)
+
+        # sum ^= o0
+        PXOR(xmm_sum, xmm_o0)
+
+        ADD(reg_src, 16)
+        ADD(reg_l, 16)
+        SUB(reg_bytes, 16)
+        JAE(vector_loop16.begin)
+
+    LABEL(out)
+
+    # Write back sum.
+    MOVDQU([reg_sum], xmm_sum)
+
+    RETURN()
+
+delta = Argument(ptr(const_uint8_t))
+blocks = Argument(size_t)
+
+def aes10x4(o0, o1, o2, o3, i, j, l):
+    for rk in [i, j, l, i, j, l, i, j, l, i]:
+        AESENC(o0, rk)
+        AESENC(o1, rk)
+        AESENC(o2, rk)
+        AESENC(o3, rk)
+
+def aes10x1(o, i, j, l):
+    for rk in [i, j, l, i, j, l, i, j, l, i]:
+        AESENC(o, rk)
+
+def loadCtr(o, reg_ctr, reg_tmp, tmp):
+    # o ^= ctr, as a 128 bit big endian counter, with the upper 64 bits
+    # always 0.
+    MOV(reg_tmp, reg_ctr)
+    BSWAP(reg_tmp)
+    MOVQ(tmp, reg_tmp)
+    PSLLDQ(tmp, 8)
+    PXOR(o, tmp)
+    INC(reg_ctr)
+
+with Function("aezPRFAMD64AESNI", (delta, l, k, dst, blocks), target=uarch.zen):
+    reg_delta = GeneralPurposeRegister64()
+    reg_l = GeneralPurposeRegister64()
+    reg_k = GeneralPurposeRegister64()
+    reg_dst = GeneralPurposeRegister64()
+    reg_blocks = GeneralPurposeRegister64()
+    reg_ctr = GeneralPurposeRegister64()
+    reg_tmp = GeneralPurposeRegister64()
+
+    LOAD.ARGUMENT(reg_delta, delta)
+    LOAD.ARGUMENT(reg_l, l)           # e.L[3]
+    LOAD.ARGUMENT(reg_k, k)
+    LOAD.ARGUMENT(reg_dst, dst)       # dst pointer
+    LOAD.ARGUMENT(reg_blocks, blocks) # blocks remaining
+
+    xmm_delta = XMMRegister() # delta ^ L[3]
+    xmm_i = XMMRegister()     # AESENC Round key I
+    xmm_j = XMMRegister()     # AESENC Round key J
+    xmm_l = XMMRegister()     # AESENC Round key L
+    xmm_o0 = XMMRegister()
+    xmm_o1 = XMMRegister()
+    xmm_o2 = XMMRegister()
+    xmm_o3 = XMMRegister()
+    xmm_tmp = XMMRegister()
+
+    MOVDQU(xmm_delta, [reg_delta])
+    MOVDQU(xmm_o0, [reg_l])
+    PXOR(xmm_delta, xmm_o0)
+    MOVDQA(xmm_i, [reg_k])
+    MOVDQA(xmm_j, [reg_k+16])
+    MOVDQA(xmm_l, [reg_k+32])
+    XOR(reg_ctr, reg_ctr)
+
+    # Process 4 * 16 bytes at a time in a loop.
+    vector_loop64 = Loop()
+    SUB(reg_blocks, 4)
+    JB(vector_loop64.end)
+    with vector_loop64:
+        # o = aes10(delta ^ ctr ^ L[3], keys) // E(-1,3)
+        MOVDQA(xmm_o0, xmm_delta)
+        MOVDQA(xmm_o1, xmm_delta)
+        MOVDQA(xmm_o2, xmm_delta)
+        MOVDQA(xmm_o3, xmm_delta)
+        loadCtr(xmm_o0, reg_ctr, reg_tmp, xmm_tmp)
+        loadCtr(xmm_o1, reg_ctr, reg_tmp, xmm_tmp)
+        loadCtr(xmm_o2, reg_ctr, reg_tmp, xmm_tmp)
+        loadCtr(xmm_o3, reg_ctr, reg_tmp, xmm_tmp)
+        aes10x4(xmm_o0, xmm_o1, xmm_o2, xmm_o3, xmm_i, xmm_j, xmm_l)
+
+        # dst ^= o
+        MOVDQU(xmm_tmp, [reg_dst])
+        PXOR(xmm_o0, xmm_tmp)
+        MOVDQU([reg_dst], xmm_o0)
+        MOVDQU(xmm_tmp, [reg_dst+16])
+        PXOR(xmm_o1, xmm_tmp)
+        MOVDQU([reg_dst+16], xmm_o1)
+        MOVDQU(xmm_tmp, [reg_dst+32])
+        PXOR(xmm_o2, xmm_tmp)
+        MOVDQU([reg_dst+32], xmm_o2)
+        MOVDQU(xmm_tmp, [reg_dst+48])
+        PXOR(xmm_o3, xmm_tmp)
+        MOVDQU([reg_dst+48], xmm_o3)
+
+        ADD(reg_dst, 64)
+        SUB(reg_blocks, 4)
+        JAE(vector_loop64.begin)
+    ADD(reg_blocks, 4)
+
+    out = Label()
+    vector_loop16 = Loop()
+    SUB(reg_blocks, 1)
+    JB(out)
+    with vector_loop16:
+        # o0 = aes10(delta ^ ctr ^ L[3], keys) // E(-1,3)
+        MOVDQA(xmm_o0, xmm_delta)
+        loadCtr(xmm_o0, reg_ctr, reg_tmp, xmm_tmp)
+        aes10x1(xmm_o0, xmm_i, xmm_j, xmm_l)
+
+        # dst ^= o0
+        MOVDQU(xmm_tmp, [reg_dst])
+        PXOR(xmm_o0, xmm_tmp)
+        MOVDQU([reg_dst], xmm_o0)
+
+        ADD(reg_dst, 16)
+        SUB(reg_blocks, 1)
+        JAE(vector_loop16.begin)
+
+    LABEL(out)
+
+    RETURN()
+
+r = Argument(ptr(uint8_t))
+m = Argument(ptr(const_uint8_t))
+n = Argument(size_t)
+idx = Argument(ptrdiff_t)
+step = Argument(ptrdiff_t)
+
+with Function("aezTinyAMD64AESNI", (l, r, m, c, k, n, idx, step), target=uarch.zen):
+    # The Feistel rounds are inherently serial, so this just saves on the
+    # call overhead and the redundant loads of calling aezAES4 per round.
+    reg_l = GeneralPurposeRegister64()
+    reg_r = GeneralPurposeRegister64()
+    reg_m = GeneralPurposeRegister64()
+    reg_c = GeneralPurposeRegister64()
+    reg_k = GeneralPurposeRegister64()
+    reg_n = GeneralPurposeRegister64()
+    reg_idx = GeneralPurposeRegister64()
+    reg_step = GeneralPurposeRegister64()
+
+    LOAD.ARGUMENT(reg_l, l)       # L half block
+    LOAD.ARGUMENT(reg_r, r)       # R half block
+    LOAD.ARGUMENT(reg_m, m)       # Half block mask
+    LOAD.ARGUMENT(reg_c, c)       # Padding ^ delta ^ I ^ L[i]
+    LOAD.ARGUMENT(reg_k, k)
+    LOAD.ARGUMENT(reg_n, n)       # Round pairs remaining (non-zero)
+    LOAD.ARGUMENT(reg_idx, idx)   # Round number
+    LOAD.ARGUMENT(reg_step, step) # Round number increment
+
+    xmm_l = XMMRegister()
+    xmm_r = XMMRegister()
+    xmm_m = XMMRegister()
+    xmm_c = XMMRegister()
+    xmm_ki = XMMRegister()   # AESENC Round key I
+    xmm_kj = XMMRegister()   # AESENC Round key J
+    xmm_kl = XMMRegister()   # AESENC Round key L
+    xmm_zero = XMMRegister() # [16]byte{0x00}
+    xmm_o = XMMRegister()
+    xmm_tmp = XMMRegister()
+
+    MOVDQU(xmm_l, [reg_l])
+    MOVDQU(xmm_r, [reg_r])
+    MOVDQU(xmm_m, [reg_m])
+    MOVDQU(xmm_c, [reg_c])
+    MOVDQA(xmm_ki, [reg_k])
+    MOVDQA(xmm_kj, [reg_k+16])
+    MOVDQA(xmm_kl, [reg_k+32])
+    PXOR(xmm_zero, xmm_zero)
+
+    def feistelRound(dst, src):
+        # dst ^= aes4((src & m) ^ c ^ idx, keys) // E(0,i)
+        MOVDQA(xmm_o, src)
+        PAND(xmm_o, xmm_m)
+        PXOR(xmm_o, xmm_c)
+        MOVQ(xmm_tmp, reg_idx)
+        PSLLDQ(xmm_tmp, 15)
+        PXOR(xmm_o, xmm_tmp)
+        aesenc4x1(xmm_o, xmm_kj, xmm_ki, xmm_kl, xmm_zero)
+        PXOR(dst, xmm_o)
+        ADD(reg_idx, reg_step)
+
+    vector_loop = Loop()
+    with vector_loop:
+        feistelRound(xmm_l, xmm_r)
+        feistelRound(xmm_r, xmm_l)
+
+        SUB(reg_n, 1)
+        JNZ(vector_loop.begin)
+
+    # Write back L and R.
+    MOVDQU([reg_l], xmm_l)
+    MOVDQU([reg_r], xmm_r)
+
+    RETURN()
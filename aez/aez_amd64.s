@@ -71,9 +71,9 @@ TEXT ·aezAES4AMD64AESNI(SB),4,$0-48
 	MOVQ src+32(FP), DI
 	MOVQ dst+40(FP), SI
 	MOVOU 0(DI), X0
-	MOVO 0(AX), X1
-	MOVO 0(BX), X2
-	MOVO 0(CX), X3
+	MOVOU 0(AX), X1
+	MOVOU 0(BX), X2
+	MOVOU 0(CX), X3
 	PXOR X1, X0
 	PXOR X3, X2
 	PXOR X2, X0
@@ -989,3 +989,305 @@ out:
 	MOVO X5, 96(SP)
 	MOVQ R9, SP
 	RET
+
+// func aezHashAMD64AESNI(src *uint8, sum *uint8, j *uint8, i *uint8, l *uint8, k *uint8, consts *uint8, sz *uint)
+TEXT ·aezHashAMD64AESNI(SB),4,$0-64
+	MOVQ src+0(FP), AX
+	MOVQ sum+8(FP), BX
+	MOVQ j+16(FP), CX
+	MOVQ i+24(FP), DX
+	MOVQ l+32(FP), SI
+	MOVQ k+40(FP), DI
+	MOVQ consts+48(FP), R8
+	MOVQ sz+56(FP), R9
+	MOVOU 0(BX), X0
+	MOVOU 0(CX), X5
+	MOVOU 0(DX), X6
+	MOVO 0(DI), X1
+	MOVO 16(DI), X2
+	MOVO 32(DI), X3
+	PXOR X4, X4
+	SUBQ $128, R9
+	JCS vector_loop128_end
+vector_loop128_begin:
+		MOVOU 0(AX), X7
+		MOVOU 16(AX), X8
+		MOVOU 32(AX), X9
+		MOVOU 48(AX), X10
+		MOVOU 64(AX), X11
+		MOVOU 80(AX), X12
+		MOVOU 96(AX), X13
+		MOVOU 112(AX), X14
+		MOVO X5, X15
+		PXOR X6, X15
+		PXOR X15, X7
+		PXOR X15, X8
+		PXOR X15, X9
+		PXOR X15, X10
+		PXOR X15, X11
+		PXOR X15, X12
+		PXOR X15, X13
+		PXOR X15, X14
+		PXOR 16(SI), X7
+		PXOR 32(SI), X8
+		PXOR 48(SI), X9
+		PXOR 64(SI), X10
+		PXOR 80(SI), X11
+		PXOR 96(SI), X12
+		PXOR 112(SI), X13
+		PXOR 0(SI), X14
+		AESENC X2, X7
+		AESENC X2, X8
+		AESENC X2, X9
+		AESENC X2, X10
+		AESENC X2, X11
+		AESENC X2, X12
+		AESENC X2, X13
+		AESENC X2, X14
+		AESENC X1, X7
+		AESENC X1, X8
+		AESENC X1, X9
+		AESENC X1, X10
+		AESENC X1, X11
+		AESENC X1, X12
+		AESENC X1, X13
+		AESENC X1, X14
+		AESENC X3, X7
+		AESENC X3, X8
+		AESENC X3, X9
+		AESENC X3, X10
+		AESENC X3, X11
+		AESENC X3, X12
+		AESENC X3, X13
+		AESENC X3, X14
+		AESENC X4, X7
+		AESENC X4, X8
+		AESENC X4, X9
+		AESENC X4, X10
+		AESENC X4, X11
+		AESENC X4, X12
+		AESENC X4, X13
+		AESENC X4, X14
+		PXOR X7, X0
+		PXOR X8, X0
+		PXOR X9, X0
+		PXOR X10, X0
+		PXOR X11, X0
+		PXOR X12, X0
+		PXOR X13, X0
+		PXOR X14, X0
+		MOVO 0(R8), X7
+		PSHUFB X7, X6
+		MOVO X6, X8
+		PSRAL $31, X8
+		PAND 16(R8), X8
+		PSHUFL $147, X8, X8
+		PSLLL $1, X6
+		PXOR X8, X6
+		PSHUFB X7, X6
+		ADDQ $128, AX
+		SUBQ $128, R9
+		JCC vector_loop128_begin
+vector_loop128_end:
+	ADDQ $128, R9
+	MOVO X5, X15
+	PXOR X6, X15
+	ADDQ $16, SI
+	SUBQ $16, R9
+	JCS out
+vector_loop16_begin:
+		MOVOU 0(AX), X7
+		PXOR X15, X7
+		PXOR 0(SI), X7
+		AESENC X2, X7
+		AESENC X1, X7
+		AESENC X3, X7
+		AESENC X4, X7
+		PXOR X7, X0
+		ADDQ $16, AX
+		ADDQ $16, SI
+		SUBQ $16, R9
+		JCC vector_loop16_begin
+out:
+	MOVOU X0, 0(BX)
+	RET
+
+// func aezPRFAMD64AESNI(delta *uint8, l *uint8, k *uint8, dst *uint8, blocks uint)
+TEXT ·aezPRFAMD64AESNI(SB),4,$0-40
+	MOVQ delta+0(FP), AX
+	MOVQ l+8(FP), BX
+	MOVQ k+16(FP), CX
+	MOVQ dst+24(FP), DX
+	MOVQ blocks+32(FP), SI
+	MOVOU 0(AX), X0
+	MOVOU 0(BX), X4
+	PXOR X4, X0
+	MOVO 0(CX), X1
+	MOVO 16(CX), X2
+	MOVO 32(CX), X3
+	XORQ DI, DI
+	SUBQ $4, SI
+	JCS vector_loop64_end
+vector_loop64_begin:
+		MOVO X0, X4
+		MOVO X0, X5
+		MOVO X0, X6
+		MOVO X0, X7
+		MOVQ DI, R8
+		BSWAPQ R8
+		MOVQ R8, X8
+		PSLLDQ $8, X8
+		PXOR X8, X4
+		INCQ DI
+		MOVQ DI, R8
+		BSWAPQ R8
+		MOVQ R8, X8
+		PSLLDQ $8, X8
+		PXOR X8, X5
+		INCQ DI
+		MOVQ DI, R8
+		BSWAPQ R8
+		MOVQ R8, X8
+		PSLLDQ $8, X8
+		PXOR X8, X6
+		INCQ DI
+		MOVQ DI, R8
+		BSWAPQ R8
+		MOVQ R8, X8
+		PSLLDQ $8, X8
+		PXOR X8, X7
+		INCQ DI
+		AESENC X1, X4
+		AESENC X1, X5
+		AESENC X1, X6
+		AESENC X1, X7
+		AESENC X2, X4
+		AESENC X2, X5
+		AESENC X2, X6
+		AESENC X2, X7
+		AESENC X3, X4
+		AESENC X3, X5
+		AESENC X3, X6
+		AESENC X3, X7
+		AESENC X1, X4
+		AESENC X1, X5
+		AESENC X1, X6
+		AESENC X1, X7
+		AESENC X2, X4
+		AESENC X2, X5
+		AESENC X2, X6
+		AESENC X2, X7
+		AESENC X3, X4
+		AESENC X3, X5
+		AESENC X3, X6
+		AESENC X3, X7
+		AESENC X1, X4
+		AESENC X1, X5
+		AESENC X1, X6
+		AESENC X1, X7
+		AESENC X2, X4
+		AESENC X2, X5
+		AESENC X2, X6
+		AESENC X2, X7
+		AESENC X3, X4
+		AESENC X3, X5
+		AESENC X3, X6
+		AESENC X3, X7
+		AESENC X1, X4
+		AESENC X1, X5
+		AESENC X1, X6
+		AESENC X1, X7
+		MOVOU 0(DX), X8
+		PXOR X8, X4
+		MOVOU X4, 0(DX)
+		MOVOU 16(DX), X8
+		PXOR X8, X5
+		MOVOU X5, 16(DX)
+		MOVOU 32(DX), X8
+		PXOR X8, X6
+		MOVOU X6, 32(DX)
+		MOVOU 48(DX), X8
+		PXOR X8, X7
+		MOVOU X7, 48(DX)
+		ADDQ $64, DX
+		SUBQ $4, SI
+		JCC vector_loop64_begin
+vector_loop64_end:
+	ADDQ $4, SI
+	SUBQ $1, SI
+	JCS out
+vector_loop16_begin:
+		MOVO X0, X4
+		MOVQ DI, R8
+		BSWAPQ R8
+		MOVQ R8, X8
+		PSLLDQ $8, X8
+		PXOR X8, X4
+		INCQ DI
+		AESENC X1, X4
+		AESENC X2, X4
+		AESENC X3, X4
+		AESENC X1, X4
+		AESENC X2, X4
+		AESENC X3, X4
+		AESENC X1, X4
+		AESENC X2, X4
+		AESENC X3, X4
+		AESENC X1, X4
+		MOVOU 0(DX), X8
+		PXOR X8, X4
+		MOVOU X4, 0(DX)
+		ADDQ $16, DX
+		SUBQ $1, SI
+		JCC vector_loop16_begin
+out:
+	RET
+
+// func aezTinyAMD64AESNI(l *uint8, r *uint8, m *uint8, c *uint8, k *uint8, n uint, idx int, step int)
+TEXT ·aezTinyAMD64AESNI(SB),4,$0-64
+	MOVQ l+0(FP), AX
+	MOVQ r+8(FP), BX
+	MOVQ m+16(FP), CX
+	MOVQ c+24(FP), DX
+	MOVQ k+32(FP), SI
+	MOVQ n+40(FP), DI
+	MOVQ idx+48(FP), R8
+	MOVQ step+56(FP), R9
+	MOVOU 0(AX), X0
+	MOVOU 0(BX), X1
+	MOVOU 0(CX), X2
+	MOVOU 0(DX), X3
+	MOVO 0(SI), X4
+	MOVO 16(SI), X5
+	MOVO 32(SI), X6
+	PXOR X7, X7
+vector_loop_begin:
+		MOVO X1, X8
+		PAND X2, X8
+		PXOR X3, X8
+		MOVQ R8, X9
+		PSLLDQ $15, X9
+		PXOR X9, X8
+		AESENC X5, X8
+		AESENC X4, X8
+		AESENC X6, X8
+		AESENC X7, X8
+		PXOR X8, X0
+		ADDQ R9, R8
+		MOVO X0, X8
+		PAND X2, X8
+		PXOR X3, X8
+		MOVQ R8, X9
+		PSLLDQ $15, X9
+		PXOR X9, X8
+		AESENC X5, X8
+		AESENC X4, X8
+		AESENC X6, X8
+		AESENC X7, X8
+		PXOR X8, X1
+		ADDQ R9, R8
+		SUBQ $1, DI
+		JNE vector_loop_begin
+	MOVOU X0, 0(AX)
+	MOVOU X1, 0(BX)
+	RET
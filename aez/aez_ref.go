@@ -21,12 +21,36 @@ func xorBytes4x16(a, b, c, d, dst []byte) {
 	}
 }
 
-func (e *eState) aezCorePass1(in, out []byte, X *[blockSize]byte, sz int) {
-	e.aezCorePass1Slow(in, out, X, sz)
+func (e *eState) initPlatform() {
+	// Nothing special to do here.
+}
+
+func (e *eState) aes4(j, i, l *[blockSize]byte, src []byte, dst *[blockSize]byte) {
+	e.aes4Slow(j, i, l, src, dst)
+}
+
+func (e *eState) aes10(l *[blockSize]byte, src []byte, dst *[blockSize]byte) {
+	e.aes10Slow(l, src, dst)
+}
+
+func (e *eState) aezCorePass1(in, out []byte, X, startI *[blockSize]byte, sz int) {
+	e.aezCorePass1Slow(in, out, X, startI, sz)
+}
+
+func (e *eState) aezCorePass2(in, out []byte, Y, S, startI *[blockSize]byte, sz int) {
+	e.aezCorePass2Slow(in, out, Y, S, startI, sz)
+}
+
+func (e *eState) aezHashBlocks(J, startI *[blockSize]byte, src []byte, sum *[blockSize]byte) {
+	e.aezHashBlocksSlow(J, startI, src, sum)
+}
+
+func (e *eState) aezPRFBlocks(delta *[blockSize]byte, result []byte) {
+	e.aezPRFBlocksSlow(delta, result)
 }
 
-func (e *eState) aezCorePass2(in, out []byte, Y, S *[blockSize]byte, sz int) {
-	e.aezCorePass2Slow(in, out, Y, S, sz)
+func (e *eState) aezTinyRounds(L, R, M, C, Li *[blockSize]byte, rounds uint, j, step int) {
+	e.aezTinyRoundsSlow(L, R, M, C, Li, rounds, j, step)
 }
 
 func platformInit() {
@@ -13,10 +13,13 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/iotest"
 )
 
 func readJsonTestdata(t *testing.T, name string, destination interface{}) {
@@ -106,6 +109,27 @@ func TestHash(t *testing.T) {
 		e.init(vecK)
 		e.aezHash(nonce, ad, vec.Tau, result[:])
 		assertEqual(t, i, vecV, result[:])
+
+		// The incremental hash, with a variety of write sizes.
+		for _, chunk := range []int{1, 7, 16, 33, 128, 200} {
+			var h hashState
+			h.init(&e, vec.Tau)
+			for k, d := range append([][]byte{nonce}, ad...) {
+				if k > 0 {
+					h.nextElement()
+				}
+				for len(d) > 0 {
+					n := chunk
+					if n > len(d) {
+						n = len(d)
+					}
+					h.write(d[:n])
+					d = d[n:]
+				}
+			}
+			h.sum(&result)
+			assertEqual(t, i, vecV, result[:])
+		}
 	}
 }
 
@@ -224,10 +248,28 @@ func assertEncrypt(t *testing.T, vectors []EncryptVector) {
 			}
 		}
 
+		keyed, err := NewCipher(vecK)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// And the vector interface, for vectors with a tag and nonce.
+		var vecAead *AeadAEZ
+		if len(vecNonce) > 0 && vec.Tau > 0 {
+			vecAead, err = NewAEAD(vecK, len(vecNonce), vec.Tau)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
 		e.init(vecK)
 		c := Encrypt(vecK, vecNonce, vecData, vec.Tau, vecM, nil)
 		assertEqual(t, i, vecC, c)
+		kc := keyed.Encrypt(vecNonce, vecData, vec.Tau, vecM, nil)
+		assertEqual(t, i, vecC, kc)
 		if aead != nil {
+			kc = keyed.Seal(nil, vecNonce, vecM, ad)
+			assertEqual(t, i, vecC, kc)
 			ac := aead.Seal(nil, vecNonce, vecM, ad)
 			assertEqual(t, i, vecC, ac)
 		}
@@ -237,6 +279,41 @@ func assertEncrypt(t *testing.T, vectors []EncryptVector) {
 			t.Fatalf("decrypt failed: [%d]", i)
 		}
 		assertEqual(t, i, vecM, m)
+		km, ok := keyed.Decrypt(vecNonce, vecData, vec.Tau, vecC, nil)
+		if !ok {
+			t.Fatalf("keyed decrypt failed: [%d]", i)
+		}
+		assertEqual(t, i, vecM, km)
+		if len(vecM) == 0 && vec.Tau > 0 {
+			inputs := append([][]byte{vecNonce}, vecData...)
+			r := make([]byte, vec.Tau)
+			PRF(vecK, inputs, r)
+			assertEqual(t, i, vecC, r)
+			keyed.PRF(inputs, r)
+			assertEqual(t, i, vecC, r)
+		}
+		if vec.Tau == 0 {
+			tweak := append([][]byte{vecNonce}, vecData...)
+			ec := make([]byte, len(vecM))
+			Encipher(vecK, tweak, ec, vecM)
+			assertEqual(t, i, vecC, ec)
+			Decipher(vecK, tweak, ec, ec)
+			assertEqual(t, i, vecM, ec)
+
+			keyed.Encipher(tweak, ec, vecM)
+			assertEqual(t, i, vecC, ec)
+			keyed.Decipher(tweak, ec, vecC)
+			assertEqual(t, i, vecM, ec)
+		}
+		if vecAead != nil {
+			vc := vecAead.SealVector(nil, vecNonce, vecM, vecData)
+			assertEqual(t, i, vecC, vc)
+			vm, err := vecAead.OpenVector(nil, vecNonce, vecC, vecData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertEqual(t, i, vecM, vm)
+		}
 		if aead != nil {
 			am, err := aead.Open(nil, vecNonce, vecC, ad)
 			if err != nil {
@@ -247,6 +324,529 @@ func assertEncrypt(t *testing.T, vectors []EncryptVector) {
 	}
 }
 
+func TestAEADVector(t *testing.T) {
+	var key [extractedKeySize]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, params := range [][2]int{{0, 16}, {16, 0}, {-1, 16}, {16, maxTagSize + 1}} {
+		if _, err := NewAEAD(key[:], params[0], params[1]); err == nil {
+			t.Fatalf("NewAEAD(%d, %d) accepted invalid parameters", params[0], params[1])
+		}
+	}
+
+	a, err := NewAEAD(key[:], 24, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.NonceSize() != 24 || a.Overhead() != 32 {
+		t.Fatalf("NonceSize()/Overhead() = %d/%d", a.NonceSize(), a.Overhead())
+	}
+
+	nonce := make([]byte, 24)
+	msg := []byte("a message with a structured header")
+	ad := [][]byte{[]byte("version"), []byte("header")}
+
+	ct := a.SealVector(nil, nonce, msg, ad)
+	if len(ct) != len(msg)+32 {
+		t.Fatalf("len(ct) = %d", len(ct))
+	}
+	pt, err := a.OpenVector(nil, nonce, ct, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pt, msg) {
+		t.Fatalf("OpenVector returned the wrong plaintext")
+	}
+
+	// The vector structure is authenticated, not just the concatenation.
+	if _, err = a.OpenVector(nil, nonce, ct, [][]byte{[]byte("versionheader")}); err == nil {
+		t.Fatalf("OpenVector accepted a different AD vector")
+	}
+
+	// A single element vector matches the single slice interface.
+	ct = a.SealVector(nil, nonce, msg, ad[:1])
+	if pt, err = a.Open(nil, nonce, ct, ad[0]); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pt, msg) {
+		t.Fatalf("Open returned the wrong plaintext")
+	}
+
+	ct[0] ^= 1
+	if _, err = a.OpenVector(nil, nonce, ct, ad[:1]); err == nil {
+		t.Fatalf("OpenVector accepted a tampered ciphertext")
+	}
+}
+
+func TestEncipherTweak(t *testing.T) {
+	var key [extractedKeySize]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	src := make([]byte, 100)
+	if _, err := rand.Read(src); err != nil {
+		t.Fatal(err)
+	}
+
+	encipher := func(tweak [][]byte) []byte {
+		dst := make([]byte, len(src))
+		Encipher(key[:], tweak, dst, src)
+		return dst
+	}
+
+	// An empty tweak is the same as a single empty element.
+	if !bytes.Equal(encipher(nil), encipher([][]byte{{}})) {
+		t.Fatalf("Encipher(nil) != Encipher({{}})")
+	}
+
+	// Each element of the tweak is significant.
+	base := encipher([][]byte{[]byte("sector"), []byte("42")})
+	for _, tweak := range [][][]byte{
+		{[]byte("sector42")},
+		{[]byte("sector"), []byte("43")},
+		{[]byte("sector"), []byte("42"), {}},
+	} {
+		if bytes.Equal(base, encipher(tweak)) {
+			t.Fatalf("Encipher(%q) collides", tweak)
+		}
+	}
+}
+
+func TestMAC(t *testing.T) {
+	var key [extractedKeySize]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewMAC(key[:], 0); err == nil {
+		t.Fatalf("NewMAC accepted a 0 byte tag")
+	}
+
+	msg := make([]byte, 300)
+	if _, err := rand.Read(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range []int{1, 16, 32, 45} {
+		m, err := NewMAC(key[:], size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := NewCipher(key[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		cm, err := c.NewMAC(size)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, n := range []int{0, 1, 15, 16, 17, 32, 33, 300} {
+			// The MAC of a message is the AEZ encryption of the empty
+			// message with the message as the nonce.
+			expected := Encrypt(key[:], msg[:n], nil, size, nil, nil)
+
+			m.Reset()
+			m.Write(msg[:n/2])
+			m.Write(msg[n/2 : n])
+			tag := m.Sum(nil)
+			assertEqual(t, n, expected, tag)
+			assertEqual(t, n, expected, m.Sum(nil))
+			if !m.Verify(tag) {
+				t.Fatalf("Verify(%d, %d) failed", size, n)
+			}
+			tag[0] ^= 1
+			if m.Verify(tag) {
+				t.Fatalf("Verify(%d, %d) accepted an invalid tag", size, n)
+			}
+
+			cm.Reset()
+			cm.Write(msg[:n])
+			assertEqual(t, n, expected, cm.Sum([]byte{})[:size])
+		}
+
+		// Clearing a MAC wipes its own key, but not that of the Cipher it
+		// was created from.
+		m.Clear()
+		if m.e.L[1] != [blockSize]byte{} {
+			t.Fatalf("Clear did not wipe the key")
+		}
+		cm.Clear()
+		assertEqual(t, size, Encrypt(key[:], nil, nil, size, nil, nil), c.Encrypt(nil, nil, size, nil, nil))
+		c.Reset()
+	}
+}
+
+func TestInPlace(t *testing.T) {
+	var key [extractedKeySize]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tau := range []int{16, 7, 35} {
+		a, err := NewAEAD(key[:], aeadNonceSize, tau)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce := make([]byte, aeadNonceSize)
+		ad := []byte("associated data")
+
+		for _, n := range []int{0, 1, 15, 16, 17, 31, 32, 33, 64, 65, 255, 256, 1024} {
+			msg := make([]byte, n)
+			if _, err := rand.Read(msg); err != nil {
+				t.Fatal(err)
+			}
+			expected := a.Seal(nil, nonce, msg, ad)
+
+			buf := make([]byte, n, n+tau)
+			copy(buf, msg)
+			ct := a.Seal(buf[:0], nonce, buf, ad)
+			if &ct[0] != &buf[:1][0] {
+				t.Fatalf("Seal(%d, %d) did not use dst", tau, n)
+			}
+			assertEqual(t, n, expected, ct)
+
+			pt, err := a.Open(ct[:0], nonce, ct, ad)
+			if err != nil {
+				t.Fatalf("Open(%d, %d): %v", tau, n, err)
+			}
+			assertEqual(t, n, msg, pt)
+
+			// A failed in-place Open does not leave plaintext behind.
+			ct = a.Seal(buf[:0], nonce, buf[:n], ad)
+			ct[len(ct)-1] ^= 1
+			if _, err = a.Open(ct[:0], nonce, ct, ad); err == nil {
+				t.Fatalf("Open(%d, %d) accepted a tampered ciphertext", tau, n)
+			}
+			if !bytes.Equal(ct, make([]byte, len(ct))) {
+				t.Fatalf("Open(%d, %d) left data behind on failure", tau, n)
+			}
+		}
+	}
+}
+
+func TestParallel(t *testing.T) {
+	var key [extractedKeySize]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		t.Fatal(err)
+	}
+	var e eState
+	e.init(key[:])
+	defer e.reset()
+
+	// Split the core passes as finely as possible, so that short inputs
+	// exercise the sharding, and check them against the serial passes.
+	for _, workers := range []int{2, 3, 7} {
+		e.workers = workers
+		for _, sz := range []int{512, 544, 1024, 2080, 4096, 7776, 65536 + 32} {
+			in := make([]byte, sz)
+			if _, err := rand.Read(in); err != nil {
+				t.Fatal(err)
+			}
+			var S, X, Y, expectedX, expectedY [blockSize]byte
+			if _, err := rand.Read(S[:]); err != nil {
+				t.Fatal(err)
+			}
+			expected := make([]byte, sz)
+			out := make([]byte, sz)
+
+			e.aezCorePass1(in, expected, &expectedX, &e.I[1], sz)
+			e.corePass1(in, out, &X, sz, shardAlign)
+			assertEqual(t, sz, expected, out)
+			assertEqual(t, sz, expectedX[:], X[:])
+
+			e.aezCorePass2(in, expected, &expectedY, &S, &e.I[1], sz)
+			e.corePass2(in, out, &Y, &S, sz, shardAlign)
+			assertEqual(t, sz, expected, out)
+			assertEqual(t, sz, expectedY[:], Y[:])
+		}
+	}
+
+	// Messages of two or more minimum sized shards are split by default.
+	serial, err := NewCipher(key[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	par, err := NewCipher(key[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	par.SetParallelism(2)
+
+	nonce := make([]byte, aeadNonceSize)
+	ad := [][]byte{[]byte("associated data")}
+	n := 2*parallelMinShard + 48
+	msg := make([]byte, n)
+	if _, err := rand.Read(msg); err != nil {
+		t.Fatal(err)
+	}
+	expected := serial.Encrypt(nonce, ad, aeadOverhead, msg, nil)
+	ct := par.Encrypt(nonce, ad, aeadOverhead, msg, nil)
+	assertEqual(t, n, expected, ct)
+
+	pt, ok := par.Decrypt(nonce, ad, aeadOverhead, ct, nil)
+	if !ok {
+		t.Fatalf("Decrypt(%d) failed", n)
+	}
+	assertEqual(t, n, msg, pt)
+}
+
+func TestBlockRoutines(t *testing.T) {
+	// The platform specific hash, PRF and Feistel routines must match the
+	// portable ones for every length, including those not covered by the
+	// test vectors.
+	var key [extractedKeySize]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		t.Fatal(err)
+	}
+	var e eState
+	e.init(key[:])
+	defer e.reset()
+
+	var J, I, delta, expected, actual [blockSize]byte
+	for _, b := range [][]byte{J[:], I[:], delta[:]} {
+		if _, err := rand.Read(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src := make([]byte, 40*blockSize)
+	if _, err := rand.Read(src); err != nil {
+		t.Fatal(err)
+	}
+	for n := 1; n <= 40; n++ {
+		expected, actual = [blockSize]byte{}, [blockSize]byte{}
+		e.aezHashBlocksSlow(&J, &I, src[:n*blockSize], &expected)
+		e.aezHashBlocks(&J, &I, src[:n*blockSize], &actual)
+		assertEqual(t, n, expected[:], actual[:])
+
+		expectedPRF := append([]byte{}, src[:n*blockSize]...)
+		actualPRF := append([]byte{}, src[:n*blockSize]...)
+		e.aezPRFBlocksSlow(&delta, expectedPRF)
+		e.aezPRFBlocks(&delta, actualPRF)
+		assertEqual(t, n, expectedPRF, actualPRF)
+	}
+
+	var M [blockSize]byte
+	for i := range M[:7] {
+		M[i] = 0xff
+	}
+	M[7] = 0xf0
+	for _, rounds := range []uint{8, 10, 16, 24} {
+		for _, step := range []int{1, -1} {
+			j := 0
+			if step < 0 {
+				j = int(rounds) - 1
+			}
+			L0, R0 := J, I
+			L1, R1 := J, I
+			e.aezTinyRoundsSlow(&L0, &R0, &M, &delta, &e.L[7], rounds, j, step)
+			e.aezTinyRounds(&L1, &R1, &M, &delta, &e.L[7], rounds, j, step)
+			assertEqual(t, int(rounds), L0[:], L1[:])
+			assertEqual(t, int(rounds), R0[:], R1[:])
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	var key [extractedKeySize]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		t.Fatal(err)
+	}
+	ref, err := NewCipher(key[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Messages covering the AEZ-prf, tiny and core paths, along with
+	// multi-block nonces and associated data.
+	var nonces, plaintexts, ad, dst [][]byte
+	for n := 0; n < 40; n++ {
+		nonce := make([]byte, aeadNonceSize)
+		msg := make([]byte, n%24)
+		if n%7 == 0 {
+			msg = make([]byte, 17*n)
+		}
+		var a []byte
+		switch n % 3 {
+		case 1:
+			a = []byte{}
+		case 2:
+			a = make([]byte, 5*n)
+		}
+		for _, b := range [][]byte{nonce, msg, a} {
+			if _, err := rand.Read(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		nonces = append(nonces, nonce)
+		plaintexts = append(plaintexts, msg)
+		ad = append(ad, a)
+		dst = append(dst, []byte("prefix")[:n%7])
+	}
+
+	oldNewAes := newAes
+	defer func() { newAes = oldNewAes }()
+	for _, ctor := range []aesImplCtor{newAes, newRoundB64, newRoundB32, newRoundVartime} {
+		newAes = ctor
+		c, err := NewCipher(key[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, d := range [][][]byte{nil, dst} {
+			for _, a := range [][][]byte{nil, ad} {
+				cts := c.SealBatch(d, nonces, plaintexts, a)
+				for m := range cts {
+					expected := ref.Seal(batchElem(d, m), nonces[m], plaintexts[m], batchElem(a, m))
+					assertEqual(t, m, expected, cts[m])
+					cts[m] = cts[m][len(batchElem(d, m)):]
+				}
+
+				// Corrupt some of the ciphertexts, and truncate one.
+				for m := 0; m < len(cts); m += 5 {
+					cts[m][m%len(cts[m])] ^= 0x20
+				}
+				cts[3] = cts[3][:aeadOverhead-1]
+
+				pts, errs := c.OpenBatch(d, nonces, cts, a)
+				for m := range pts {
+					if m%5 == 0 || m == 3 {
+						if errs[m] == nil || pts[m] != nil {
+							t.Fatalf("OpenBatch accepted corrupted message %d", m)
+						}
+						continue
+					}
+					if errs[m] != nil {
+						t.Fatalf("OpenBatch(%d): %v", m, errs[m])
+					}
+					expected := append(append([]byte{}, batchElem(d, m)...), plaintexts[m]...)
+					assertEqual(t, m, expected, pts[m])
+				}
+			}
+		}
+		c.Reset()
+	}
+}
+
+func TestReaders(t *testing.T) {
+	var key [extractedKeySize]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewCipher(key[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Elements that are empty, partial blocks, and long enough to take the
+	// multi-block hashing path, with the nonce also spanning blocks.
+	nonce := make([]byte, 40)
+	var ad [][]byte
+	for _, n := range []int{0, 1, 15, 16, 17, 0, 127, 128, 129, 300, 5000} {
+		ad = append(ad, make([]byte, n))
+	}
+	for _, b := range append([][]byte{nonce}, ad...) {
+		if _, err := rand.Read(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readers := func(wrap func(io.Reader) io.Reader) []io.Reader {
+		var r []io.Reader
+		for _, a := range ad {
+			r = append(r, wrap(bytes.NewReader(a)))
+		}
+		return r
+	}
+	wrappers := []func(io.Reader) io.Reader{
+		func(r io.Reader) io.Reader { return r },
+		iotest.OneByteReader,
+		iotest.HalfReader,
+		iotest.DataErrReader,
+	}
+
+	for _, tau := range []int{0, 1, 16} {
+		for _, n := range []int{0, 1, 20, 100} {
+			msg := make([]byte, n)
+			if _, err := rand.Read(msg); err != nil {
+				t.Fatal(err)
+			}
+			expected := c.Encrypt(nonce, ad, tau, msg, nil)
+			for i, wrap := range wrappers {
+				ct, err := c.EncryptReaders(nonce, readers(wrap), tau, msg, nil)
+				if err != nil {
+					t.Fatalf("EncryptReaders(%d): %v", i, err)
+				}
+				assertEqual(t, i, expected, ct)
+
+				pt, err := c.DecryptReaders(nonce, readers(wrap), tau, ct, nil)
+				if err != nil {
+					t.Fatalf("DecryptReaders(%d): %v", i, err)
+				}
+				assertEqual(t, i, msg, pt)
+			}
+
+			if tau > 0 {
+				expected[0] ^= 0x80
+				if _, err := c.DecryptReaders(nonce, readers(wrappers[0]), tau, expected, nil); err == nil {
+					t.Fatalf("DecryptReaders accepted a corrupted ciphertext")
+				}
+			}
+		}
+	}
+
+	// Errors from the readers are returned, and nothing is written.
+	errRead := errors.New("read failed")
+	r := readers(func(r io.Reader) io.Reader { return r })
+	r[7] = io.MultiReader(r[7], iotest.ErrReader(errRead))
+	if ct, err := c.EncryptReaders(nonce, r, 16, []byte("msg"), nil); err != errRead || ct != nil {
+		t.Fatalf("EncryptReaders: got (%v, %v), expected read error", ct, err)
+	}
+
+	// And the AEAD interface.
+	a, err := New(key[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead := a.(*AeadAEZ)
+	nonce = nonce[:aead.NonceSize()]
+	ct, err := aead.SealReaders(nil, nonce, []byte("msg"), readers(iotest.OneByteReader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, 0, aead.SealVector(nil, nonce, []byte("msg"), ad), ct)
+	if _, err := aead.OpenReaders(nil, nonce, ct, readers(iotest.HalfReader)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSealOpenAllocs(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	var key [extractedKeySize]byte
+	a, err := NewAEAD(key[:], aeadNonceSize, aeadOverhead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, aeadNonceSize)
+	buf := make([]byte, 1024+aeadOverhead)
+
+	allocs := testing.AllocsPerRun(10, func() {
+		ct := a.Seal(buf[:0], nonce, buf[:1024], nil)
+		if _, err := a.Open(ct[:0], nonce, ct, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("Seal/Open allocated %v times", allocs)
+	}
+}
+
 func assertEqual(t *testing.T, idx int, expected, actual []byte) {
 	if !bytes.Equal(expected, actual) {
 		for i, v := range actual {
@@ -309,3 +909,84 @@ func BenchmarkEncrypt(b *testing.B) {
 		b.Run(n, func(b *testing.B) { doBenchEncrypt(b, sz) })
 	}
 }
+
+func doBenchCipherEncrypt(b *testing.B, n int) {
+	var key [extractedKeySize]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		b.Error(err)
+		b.Fail()
+	}
+
+	const tau = 16
+
+	c, err := NewCipher(key[:])
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer c.Reset()
+
+	var nonce [16]byte
+	src := make([]byte, n)
+	dst := make([]byte, n+tau)
+
+	b.SetBytes(int64(n))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dst = c.Encrypt(nonce[:], nil, tau, src[:n], dst[:0])
+	}
+
+	benchOutput = dst
+}
+
+func BenchmarkCipherEncrypt(b *testing.B) {
+	b.SetParallelism(1) // AES-NI is a per-physical core thing.
+
+	for _, sz := range []int{1, 32, 512, 1024, 16384} {
+		n := fmt.Sprintf("%d", sz)
+		b.Run(n, func(b *testing.B) { doBenchCipherEncrypt(b, sz) })
+	}
+}
+
+func doBenchSealBatch(b *testing.B, n int, batch bool) {
+	var key [extractedKeySize]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		b.Fatal(err)
+	}
+
+	// The interleaving only applies to the bitsliced implementations.
+	oldNewAes := newAes
+	newAes = newRoundB64
+	c, err := NewCipher(key[:])
+	newAes = oldNewAes
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer c.Reset()
+
+	const nMsgs = 64
+	nonces, plaintexts := make([][]byte, nMsgs), make([][]byte, nMsgs)
+	for m := range nonces {
+		nonces[m] = make([]byte, aeadNonceSize)
+		plaintexts[m] = make([]byte, n)
+	}
+
+	b.SetBytes(int64(n * nMsgs))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if batch {
+			c.SealBatch(nil, nonces, plaintexts, nil)
+			continue
+		}
+		for m := range nonces {
+			benchOutput = c.Seal(nil, nonces[m], plaintexts[m], nil)
+		}
+	}
+}
+
+func BenchmarkSealBatch(b *testing.B) {
+	for _, sz := range []int{1, 8, 15} {
+		n := fmt.Sprintf("%d", sz)
+		b.Run("Seal/"+n, func(b *testing.B) { doBenchSealBatch(b, sz, false) })
+		b.Run("SealBatch/"+n, func(b *testing.B) { doBenchSealBatch(b, sz, true) })
+	}
+}
@@ -0,0 +1,436 @@
+// batch.go - Batched AEZ for many small messages
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to aez, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package aez
+
+import "encoding/binary"
+
+// SealBatch seals each of the plaintexts as with Seal, with the corresponding
+// nonce and additional data, and appends the result to the corresponding dst,
+// returning the updated slices.  The dst and additionalData slices may be
+// nil, and otherwise must be the same length as nonces and plaintexts.  As
+// with Seal, each plaintext may overlap its dst exactly, but messages may not
+// overlap each other.
+//
+// With the bitsliced AES implementations, the AES calls made by AEZ-hash,
+// AEZ-prf and the Feistel rounds for tiny messages are interleaved across
+// messages to fill the otherwise unused lanes, which is considerably faster
+// than calling Seal for each message when they are short.  The output is
+// identical either way.
+func (c *Cipher) SealBatch(dst, nonces, plaintexts, additionalData [][]byte) [][]byte {
+	if c.wasReset {
+		panic("aez: SealBatch() called after Reset()")
+	}
+	checkBatch(dst, nonces, plaintexts, additionalData)
+
+	ret := make([][]byte, len(nonces))
+	if c.e.batchLanes() == 1 {
+		for m := range ret {
+			ret[m] = c.Seal(batchElem(dst, m), nonces[m], plaintexts[m], batchElem(additionalData, m))
+		}
+		return ret
+	}
+
+	xs := make([][]byte, len(nonces))
+	for m, plaintext := range plaintexts {
+		ret[m], xs[m] = sliceForAppend(batchElem(dst, m), len(plaintext)+aeadOverhead)
+		copy(xs[m], plaintext)
+		memwipe(xs[m][len(plaintext):])
+	}
+	c.e.encryptBatch(nonces, additionalData, aeadOverhead, xs)
+
+	return ret
+}
+
+// OpenBatch opens each of the ciphertexts as with Open, with the
+// corresponding nonce and additional data, and appends the resulting
+// plaintext to the corresponding dst.  It returns the updated slices, and a
+// slice of errors, where a non-nil error means that the corresponding
+// ciphertext failed to authenticate, and the corresponding updated slice is
+// nil.  The restrictions on the arguments are the same as for SealBatch.
+func (c *Cipher) OpenBatch(dst, nonces, ciphertexts, additionalData [][]byte) ([][]byte, []error) {
+	if c.wasReset {
+		panic("aez: OpenBatch() called after Reset()")
+	}
+	checkBatch(dst, nonces, ciphertexts, additionalData)
+
+	ret := make([][]byte, len(nonces))
+	errs := make([]error, len(nonces))
+	if c.e.batchLanes() == 1 {
+		for m := range ret {
+			ret[m], errs[m] = c.Open(batchElem(dst, m), nonces[m], ciphertexts[m], batchElem(additionalData, m))
+		}
+		return ret, errs
+	}
+
+	// Messages that are too short to be valid are not part of the batch.
+	var idx []int
+	var batchNonces, batchAD, xs [][]byte
+	for m, ciphertext := range ciphertexts {
+		if len(ciphertext) < aeadOverhead {
+			errs[m] = errOpen
+			continue
+		}
+		var x []byte
+		ret[m], x = sliceForAppend(batchElem(dst, m), len(ciphertext))
+		copy(x, ciphertext)
+		idx = append(idx, m)
+		batchNonces = append(batchNonces, nonces[m])
+		batchAD = append(batchAD, batchElem(additionalData, m))
+		xs = append(xs, x)
+	}
+
+	ok := c.e.decryptBatch(batchNonces, batchAD, aeadOverhead, xs)
+	for k, m := range idx {
+		if !ok[k] {
+			memwipe(xs[k])
+			ret[m], errs[m] = nil, errOpen
+			continue
+		}
+		ret[m] = ret[m][:len(ret[m])-aeadOverhead]
+	}
+
+	return ret, errs
+}
+
+func checkBatch(dst, nonces, msgs, additionalData [][]byte) {
+	n := len(nonces)
+	if len(msgs) != n || (dst != nil && len(dst) != n) || (additionalData != nil && len(additionalData) != n) {
+		panic("aez: mismatched batch lengths")
+	}
+	for _, nonce := range nonces {
+		if len(nonce) != aeadNonceSize {
+			panic("aez: incorrect nonce length given to AEZ")
+		}
+	}
+}
+
+func batchElem(s [][]byte, i int) []byte {
+	if s == nil {
+		return nil
+	}
+	return s[i]
+}
+
+// encryptBatch is encrypt for several messages, each with at most a single
+// element of associated data, that have already been copied and zero padded
+// to their final length in xs.
+func (e *eState) encryptBatch(nonces, additionalData [][]byte, tau int, xs [][]byte) {
+	deltas := make([][blockSize]byte, len(xs))
+	e.aezHashBatch(nonces, additionalData, tau*8, deltas)
+
+	var prf, tiny []int
+	for m, x := range xs {
+		switch {
+		case len(x) == tau:
+			prf = append(prf, m)
+		case len(x) < 32:
+			tiny = append(tiny, m)
+		default:
+			// The core passes already use all of the lanes.
+			e.encipher(&deltas[m], x, x)
+		}
+	}
+	e.aezPRFBatch(deltas, xs, prf, tau)
+	e.aezTinyBatch(deltas, xs, tiny, 0)
+
+	wipeBlocks(deltas)
+}
+
+// decryptBatch is decrypt for several messages, each with at most a single
+// element of associated data, that have already been copied to xs, and
+// returns if each message is valid.
+func (e *eState) decryptBatch(nonces, additionalData [][]byte, tau int, xs [][]byte) []bool {
+	deltas := make([][blockSize]byte, len(xs))
+	e.aezHashBatch(nonces, additionalData, tau*8, deltas)
+
+	var prf, tiny []int
+	for m, x := range xs {
+		switch {
+		case len(x) == tau:
+			prf = append(prf, m)
+		case len(x) < 32:
+			tiny = append(tiny, m)
+		default:
+			e.decipher(&deltas[m], x, x)
+		}
+	}
+	e.aezPRFBatch(deltas, xs, prf, tau)
+	e.aezTinyBatch(deltas, xs, tiny, 1)
+
+	wipeBlocks(deltas)
+
+	ok := make([]bool, len(xs))
+	for m, x := range xs {
+		sum := byte(0)
+		for _, v := range x[len(x)-tau:] {
+			sum |= v
+		}
+		ok[m] = sum == 0
+	}
+	return ok
+}
+
+// aes4Op is an AES4 call, queued so that the calls for independent messages
+// can share the lanes of the bitsliced implementations.
+type aes4Op struct {
+	j, i, l *[blockSize]byte
+	src     []byte
+	dst     *[blockSize]byte
+}
+
+// aes10Op is an AES10 call, queued like aes4Op.
+type aes10Op struct {
+	l   *[blockSize]byte
+	src []byte
+	dst *[blockSize]byte
+}
+
+// batchLanes returns the number of AES calls that the implementation can
+// process at once.
+func (e *eState) batchLanes() int {
+	switch e.aes.(type) {
+	case *roundB64:
+		return 4
+	case *roundB32:
+		return 2
+	default:
+		return 1
+	}
+}
+
+func (e *eState) aes4Batch(ops []aes4Op) {
+	switch r := e.aes.(type) {
+	case *roundB64:
+		for ; len(ops) >= 4; ops = ops[4:] {
+			r.aes4x4(ops[0].j, ops[0].i, ops[0].l, ops[0].src, ops[0].dst,
+				ops[1].j, ops[1].i, ops[1].l, ops[1].src, ops[1].dst,
+				ops[2].j, ops[2].i, ops[2].l, ops[2].src, ops[2].dst,
+				ops[3].j, ops[3].i, ops[3].l, ops[3].src, ops[3].dst)
+		}
+	case *roundB32:
+		for ; len(ops) >= 2; ops = ops[2:] {
+			r.aes4x2(ops[0].j, ops[0].i, ops[0].l, ops[0].src, ops[0].dst,
+				ops[1].j, ops[1].i, ops[1].l, ops[1].src, ops[1].dst)
+		}
+	}
+	for k := range ops {
+		e.aes4(ops[k].j, ops[k].i, ops[k].l, ops[k].src, ops[k].dst)
+	}
+}
+
+func (e *eState) aes10Batch(ops []aes10Op) {
+	switch r := e.aes.(type) {
+	case *roundB64:
+		for ; len(ops) >= 4; ops = ops[4:] {
+			r.aes10x4(ops[0].l, ops[0].src, ops[0].dst,
+				ops[1].l, ops[1].src, ops[1].dst,
+				ops[2].l, ops[2].src, ops[2].dst,
+				ops[3].l, ops[3].src, ops[3].dst)
+		}
+	case *roundB32:
+		for ; len(ops) >= 2; ops = ops[2:] {
+			r.aes10x2(ops[0].l, ops[0].src, ops[0].dst,
+				ops[1].l, ops[1].src, ops[1].dst)
+		}
+	}
+	for k := range ops {
+		e.aes10(ops[k].l, ops[k].src, ops[k].dst)
+	}
+}
+
+// hashBatch queues the AES4 calls of AEZ-hash for several messages, and
+// tracks which message each result belongs to.
+type hashBatch struct {
+	ops    []aes4Op
+	owners []int
+	outs   [][blockSize]byte
+	pads   [][blockSize]byte
+	nPads  int
+
+	// Is holds I doubled once for each group of 8 blocks.
+	Is [][blockSize]byte
+}
+
+func (b *hashBatch) push(j, i, l *[blockSize]byte, src []byte, m int) {
+	k := len(b.ops)
+	b.ops = append(b.ops, aes4Op{j, i, l, src, &b.outs[k]})
+	b.owners = append(b.owners, m)
+}
+
+// element queues the hash of one element of a message's input vector, with
+// the element's J multiple, as in aezHashElement.
+func (b *hashBatch) element(e *eState, J *[blockSize]byte, p []byte, m int) {
+	n := len(p) &^ (blockSize - 1)
+	for i := 0; i < n/blockSize; i++ {
+		b.push(J, &b.Is[i/8], &e.L[(i+1)%8], p[i*blockSize:(i+1)*blockSize], m) // E(j,i+1)
+	}
+	if len(p) > n || len(p) == 0 {
+		pad := &b.pads[b.nPads]
+		b.nPads++
+		copy(pad[:], p[n:])
+		pad[len(p)-n] = 0x80
+		b.push(J, &e.I[0], &e.L[0], pad[:], m) // E(j,0)
+	}
+}
+
+// aezHashBatch is aezHash for several messages, each with at most a single
+// element of associated data, where a nil element is the absence of
+// associated data.
+func (e *eState) aezHashBatch(nonces, additionalData [][]byte, tau int, deltas [][blockSize]byte) {
+	var tauBlock, J, J5 [blockSize]byte
+
+	// Size the queue, and the table of I multiples.
+	nOps, nPads, maxBlocks := 0, 0, 0
+	count := func(p []byte) {
+		n := len(p) / blockSize
+		nOps += n
+		if len(p)%blockSize != 0 || len(p) == 0 {
+			nOps++
+			nPads++
+		}
+		if n > maxBlocks {
+			maxBlocks = n
+		}
+	}
+	for m := range deltas {
+		nOps++
+		count(nonces[m])
+		if ad := batchElem(additionalData, m); ad != nil {
+			count(ad)
+		}
+	}
+	b := &hashBatch{
+		ops:    make([]aes4Op, 0, nOps),
+		owners: make([]int, 0, nOps),
+		outs:   make([][blockSize]byte, nOps),
+		pads:   make([][blockSize]byte, nPads),
+		Is:     make([][blockSize]byte, maxBlocks/8+1),
+	}
+	b.Is[0] = e.I[1]
+	for g := 1; g < len(b.Is); g++ {
+		b.Is[g] = b.Is[g-1]
+		doubleBlock(&b.Is[g])
+	}
+
+	binary.BigEndian.PutUint32(tauBlock[12:], uint32(tau))
+	xorBytes1x16(e.J[0][:], e.J[1][:], J[:]) // J ^ J2
+	multBlock(5, &e.J[0], &J5)
+	for m := range deltas {
+		b.push(&J, &e.I[1], &e.L[1], tauBlock[:], m) // E(3,1)
+		b.element(e, &e.J[2], nonces[m], m)          // E(4,i)
+		if ad := batchElem(additionalData, m); ad != nil {
+			b.element(e, &J5, ad, m) // E(5,i)
+		}
+	}
+
+	e.aes4Batch(b.ops)
+	for k, m := range b.owners {
+		xorBytes1x16(deltas[m][:], b.outs[k][:], deltas[m][:])
+	}
+
+	memwipe(J[:])
+	memwipe(J5[:])
+	wipeBlocks(b.outs)
+	wipeBlocks(b.pads)
+	wipeBlocks(b.Is)
+}
+
+// aezPRFBatch is aezPRFXor for the messages in xs selected by idx.
+func (e *eState) aezPRFBatch(deltas [][blockSize]byte, xs [][]byte, idx []int, tau int) {
+	var ctr [blockSize]byte
+
+	nBlocks := (tau + blockSize - 1) / blockSize
+	bufs := make([][blockSize]byte, len(idx)*nBlocks)
+	ops := make([]aes10Op, len(bufs))
+	for k, m := range idx {
+		for i := 0; i < nBlocks; i++ {
+			n := k*nBlocks + i
+			binary.BigEndian.PutUint64(ctr[8:], uint64(i))
+			xorBytes1x16(deltas[m][:], ctr[:], bufs[n][:])
+			ops[n] = aes10Op{&e.L[3], bufs[n][:], &bufs[n]} // E(-1,3)
+		}
+	}
+
+	e.aes10Batch(ops)
+	for k, m := range idx {
+		for i := 0; i < nBlocks; i++ {
+			off, end := i*blockSize, (i+1)*blockSize
+			if end > tau {
+				end = tau
+			}
+			xorBytes(xs[m][off:], bufs[k*nBlocks+i][:], xs[m][off:end])
+		}
+	}
+
+	wipeBlocks(bufs)
+}
+
+// aezTinyBatch is aezTiny, in place, for the messages in xs selected by idx.
+func (e *eState) aezTinyBatch(deltas [][blockSize]byte, xs [][]byte, idx []int, d uint) {
+	if len(idx) == 0 {
+		return
+	}
+
+	ts := make([]tinyState, len(idx))
+	ops := make([]aes4Op, 0, len(idx))
+	sel := make([]int, 0, len(idx))
+	halves := make([]*[blockSize]byte, 0, len(idx))
+
+	maxRounds := uint(0)
+	for k, m := range idx {
+		if ts[k].init(&deltas[m], xs[m], d) {
+			ops = append(ops, aes4Op{&zero, &e.I[1], &e.L[3], ts[k].buf[:], &ts[k].tmp}) // E(0,3)
+			sel = append(sel, k)
+		}
+		if ts[k].rounds > maxRounds {
+			maxRounds = ts[k].rounds
+		}
+	}
+	e.aes4Batch(ops)
+	for _, k := range sel {
+		ts[k].fixL()
+	}
+
+	for n := uint(0); n < maxRounds; n++ {
+		ops, halves = ops[:0], halves[:0]
+		for k := range ts {
+			t := &ts[k]
+			if n >= t.rounds {
+				continue
+			}
+			halves = append(halves, t.roundInput(n))
+			ops = append(ops, aes4Op{&zero, &e.I[1], &e.L[t.i], t.buf[:], &t.tmp}) // E(0,i)
+		}
+		e.aes4Batch(ops)
+		for k, h := range halves {
+			xorBytes1x16(h[:], ops[k].dst[:], h[:])
+		}
+	}
+
+	ops, sel = ops[:0], sel[:0]
+	for k, m := range idx {
+		if ts[k].finish(&deltas[m], d, xs[m]) {
+			ops = append(ops, aes4Op{&zero, &e.I[1], &e.L[3], ts[k].buf[:], &ts[k].tmp}) // E(0,3)
+			sel = append(sel, k)
+		}
+	}
+	e.aes4Batch(ops)
+	for _, k := range sel {
+		ts[k].fixOut(xs[idx[k]])
+	}
+
+	for k := range ts {
+		ts[k].reset()
+	}
+}
+
+func wipeBlocks(b [][blockSize]byte) {
+	for i := range b {
+		memwipe(b[i][:])
+	}
+}
@@ -0,0 +1,166 @@
+// cipher.go - Keyed AEZ instance
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to aez, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package aez
+
+// Cipher is an AEZ instance bound to a single key.  The key extraction, the
+// derived I/J/L multiples and the AES round keys are computed once when the
+// Cipher is created, instead of once per message as with the one-shot
+// Encrypt/Decrypt calls, which matters for short messages.
+//
+// A Cipher is safe for concurrent use by multiple goroutines, except that
+// Reset and SetParallelism must not be called concurrently with any other
+// method.  It also implements crypto/cipher.AEAD with the same nonce and tag
+// lengths as AeadAEZ.
+type Cipher struct {
+	e        eState
+	wasReset bool
+}
+
+// NewCipher returns a new Cipher with the given key, which may be of any
+// non-zero length.
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) == 0 {
+		return nil, errInvalidKeySize
+	}
+	c := new(Cipher)
+	c.e.init(key)
+	return c, nil
+}
+
+// Encrypt encrypts and authenticates the plaintext, authenticates the
+// additional data, and appends the result to ciphertext, returning the
+// updated slice.  The length of the authentication tag in bytes is specified
+// by tau.  The plaintext and dst slices may overlap exactly, with plaintext
+// starting at dst[len(dst)], or not at all.
+func (c *Cipher) Encrypt(nonce []byte, additionalData [][]byte, tau int, plaintext, dst []byte) []byte {
+	if c.wasReset {
+		panic("aez: Encrypt() called after Reset()")
+	}
+	return c.e.encrypt(nonce, additionalData, tau, plaintext, dst)
+}
+
+// Decrypt decrypts and authenticates the ciphertext, authenticates the
+// additional data, and if successful appends the resulting plaintext to the
+// provided slice and returns the updated slice and true.  The length of the
+// expected authentication tag in bytes is specified by tau.  The ciphertext
+// and dst slices may overlap exactly, with ciphertext starting at
+// dst[len(dst)], or not at all.
+func (c *Cipher) Decrypt(nonce []byte, additionalData [][]byte, tau int, ciphertext, dst []byte) ([]byte, bool) {
+	if c.wasReset {
+		panic("aez: Decrypt() called after Reset()")
+	}
+	return c.e.decrypt(nonce, additionalData, tau, ciphertext, dst)
+}
+
+// Encipher enciphers src with the length preserving tweakable wide-block
+// cipher at the heart of AEZ, and writes the result to dst.  See the
+// package level Encipher for details.
+func (c *Cipher) Encipher(tweak [][]byte, dst, src []byte) {
+	if c.wasReset {
+		panic("aez: Encipher() called after Reset()")
+	}
+	c.e.encipherTweak(tweak, dst, src, false)
+}
+
+// Decipher deciphers src, which was produced by Encipher with the same key
+// and tweak, and writes the result to dst.
+func (c *Cipher) Decipher(tweak [][]byte, dst, src []byte) {
+	if c.wasReset {
+		panic("aez: Decipher() called after Reset()")
+	}
+	c.e.encipherTweak(tweak, dst, src, true)
+}
+
+// PRF fills out with AEZ-prf over the vector of inputs.  See the package
+// level PRF for details.
+func (c *Cipher) PRF(inputs [][]byte, out []byte) {
+	if c.wasReset {
+		panic("aez: PRF() called after Reset()")
+	}
+	c.e.prf(inputs, out)
+}
+
+// NewMAC creates and returns a new MAC instance that shares the Cipher's key
+// state, with a tag size of size bytes.  The MAC may not be used after the
+// Cipher's Reset is called.
+func (c *Cipher) NewMAC(size int) (*MAC, error) {
+	if c.wasReset {
+		panic("aez: NewMAC() called after Reset()")
+	}
+	return newMAC(&c.e, size)
+}
+
+// NonceSize returns the size of the nonce that must be passed to Seal
+// and Open.
+func (c *Cipher) NonceSize() int {
+	return aeadNonceSize
+}
+
+// Overhead returns the maximum difference between the lengths of a
+// plaintext and its ciphertext.
+func (c *Cipher) Overhead() int {
+	return aeadOverhead
+}
+
+// Seal encrypts and authenticates plaintext, authenticates the
+// additional data and appends the result to dst, returning the updated
+// slice.  The nonce must be NonceSize() bytes long.
+func (c *Cipher) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	return c.seal(dst, nonce, plaintext, adVector(additionalData), aeadNonceSize, aeadOverhead)
+}
+
+// Open decrypts and authenticates ciphertext, authenticates the
+// additional data and, if successful, appends the resulting plaintext
+// to dst, returning the updated slice. The nonce must be NonceSize()
+// bytes long and both it and the additional data must match the
+// value passed to Seal.
+func (c *Cipher) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	return c.open(dst, nonce, ciphertext, adVector(additionalData), aeadNonceSize, aeadOverhead)
+}
+
+func (c *Cipher) seal(dst, nonce, plaintext []byte, additionalData [][]byte, nonceSize, tau int) []byte {
+	if len(nonce) != nonceSize {
+		panic("aez: incorrect nonce length given to AEZ")
+	}
+
+	return c.Encrypt(nonce, additionalData, tau, plaintext, dst)
+}
+
+func (c *Cipher) open(dst, nonce, ciphertext []byte, additionalData [][]byte, nonceSize, tau int) ([]byte, error) {
+	if len(nonce) != nonceSize {
+		panic("aez: incorrect nonce length given to AEZ")
+	}
+
+	ret, ok := c.Decrypt(nonce, additionalData, tau, ciphertext, dst)
+	if !ok {
+		return nil, errOpen
+	}
+
+	return ret, nil
+}
+
+// adVector returns the single slice of associated data passed to the
+// crypto/cipher.AEAD interface as a vector.  A nil slice is treated as the
+// absence of associated data, which is distinct from a single empty element.
+func adVector(additionalData []byte) [][]byte {
+	if additionalData == nil {
+		return nil
+	}
+	return [][]byte{additionalData}
+}
+
+// Reset clears the sensitive keying material from the datastructure such
+// that it will no longer be in memory.  The Cipher may not be used after
+// Reset is called, and Reset must not be called concurrently with any other
+// method.
+func (c *Cipher) Reset() {
+	if !c.wasReset {
+		c.wasReset = true
+		c.e.reset()
+	}
+}
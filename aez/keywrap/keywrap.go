@@ -0,0 +1,135 @@
+// keywrap.go - AEZ based deterministic key wrapping
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to keywrap, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+// Package keywrap implements deterministic key wrapping with AEZ, as a
+// replacement for AES-KW (RFC 3394) where interoperability is not needed.
+//
+// AEZ is misuse-resistant, so with a fixed nonce it is a deterministic
+// authenticated encryption scheme, which is exactly what key wrapping calls
+// for.  Unlike AES-KW, keys and other small secrets of any non-zero length
+// may be wrapped, and additional data may be bound to the blob.  As with any
+// deterministic scheme, wrapping the same key under the same key-encryption
+// key and additional data yields the same blob, so blobs reveal equality.
+//
+// A blob is a single version byte followed by the AEZ ciphertext:
+//
+//	blob = Version || AEZ(kek, N = Version, AD = {aad}, tau = 16, key)
+//
+// The version byte doubles as the nonce, so that it is authenticated, and the
+// additional data is always passed as a single element vector, so a nil aad
+// is equivalent to an empty one.
+package keywrap
+
+import (
+	"errors"
+
+	"github.com/mad-day/Yawning-crypto/aez"
+)
+
+const (
+	// Version is the version of the blob encoding produced by Wrap.
+	Version = 1
+
+	// Overhead is the number of bytes a blob is longer than the wrapped key.
+	Overhead = 1 + tagSize
+
+	tagSize = 16
+)
+
+var (
+	// ErrInvalidKeySize is the error returned when wrapping an empty key.
+	ErrInvalidKeySize = errors.New("keywrap: invalid key size")
+
+	// ErrUnsupportedVersion is the error returned when unwrapping a blob
+	// with a version other than Version.
+	ErrUnsupportedVersion = errors.New("keywrap: unsupported blob version")
+
+	// ErrUnwrap is the error returned when a blob is malformed or fails to
+	// authenticate.  The cause is deliberately not distinguished.
+	ErrUnwrap = errors.New("keywrap: failed to unwrap key")
+)
+
+// Wrapper wraps and unwraps keys under a single key-encryption key, which is
+// only expanded once.  It is safe for concurrent use by multiple goroutines.
+type Wrapper struct {
+	c *aez.Cipher
+}
+
+// New returns a new Wrapper with the given key-encryption key, which may be
+// of any non-zero length, but should have at least 256 bits of entropy.
+func New(kek []byte) (*Wrapper, error) {
+	c, err := aez.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return &Wrapper{c: c}, nil
+}
+
+// Wrap encrypts and authenticates key, authenticates aad, and returns the
+// resulting blob, which is Overhead bytes longer than key.
+func (w *Wrapper) Wrap(aad, key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, ErrInvalidKeySize
+	}
+
+	hdr := [1]byte{Version}
+	blob := make([]byte, 1, Overhead+len(key))
+	blob[0] = Version
+	return w.c.Encrypt(hdr[:], [][]byte{aad}, tagSize, key, blob), nil
+}
+
+// Unwrap authenticates and decrypts blob and aad, which must match the value
+// passed to Wrap, and returns the key.  Authentication is done in constant
+// time, and on failure no part of the decrypted blob is returned or left in
+// memory.
+func (w *Wrapper) Unwrap(aad, blob []byte) ([]byte, error) {
+	// Neither the version nor the length is secret, so rejecting on either
+	// early is fine.
+	if len(blob) == 0 {
+		return nil, ErrUnwrap
+	}
+	if blob[0] != Version {
+		return nil, ErrUnsupportedVersion
+	}
+	if len(blob) <= Overhead {
+		return nil, ErrUnwrap
+	}
+
+	key, ok := w.c.Decrypt(blob[:1], [][]byte{aad}, tagSize, blob[1:], nil)
+	if !ok {
+		return nil, ErrUnwrap
+	}
+	return key, nil
+}
+
+// Reset clears the key-encryption key from memory.  The Wrapper may not be
+// used after Reset is called.
+func (w *Wrapper) Reset() {
+	w.c.Reset()
+}
+
+// Wrap wraps key under kek, binding aad.  See Wrapper.Wrap.
+func Wrap(kek, aad, key []byte) ([]byte, error) {
+	w, err := New(kek)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Reset()
+
+	return w.Wrap(aad, key)
+}
+
+// Unwrap unwraps blob with kek, verifying aad.  See Wrapper.Unwrap.
+func Unwrap(kek, aad, blob []byte) ([]byte, error) {
+	w, err := New(kek)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Reset()
+
+	return w.Unwrap(aad, blob)
+}
@@ -0,0 +1,118 @@
+// keywrap_test.go - AEZ key wrapping tests.
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to keywrap_test.go, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package keywrap
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/mad-day/Yawning-crypto/aez"
+)
+
+func mustRandBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic("mustRandBytes: " + err.Error())
+	}
+	return b
+}
+
+func TestWrapUnwrap(t *testing.T) {
+	kek := mustRandBytes(32)
+	aad := []byte("keywrap test")
+
+	for _, n := range []int{1, 15, 16, 17, 31, 32, 64, 100} {
+		key := mustRandBytes(n)
+
+		blob, err := Wrap(kek, aad, key)
+		if err != nil {
+			t.Fatalf("Wrap(%d): %v", n, err)
+		}
+		if len(blob) != n+Overhead {
+			t.Fatalf("Wrap(%d): blob length %d", n, len(blob))
+		}
+
+		// The encoding is the version byte followed by AEZ, with the version
+		// as the nonce.
+		c, err := aez.NewCipher(kek)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := c.Encrypt([]byte{Version}, [][]byte{aad}, 16, key, []byte{Version})
+		if !bytes.Equal(expected, blob) {
+			t.Fatalf("Wrap(%d): blob mismatch", n)
+		}
+
+		// Wrapping is deterministic.
+		blob2, err := Wrap(kek, aad, key)
+		if err != nil || !bytes.Equal(blob, blob2) {
+			t.Fatalf("Wrap(%d): not deterministic", n)
+		}
+
+		got, err := Unwrap(kek, aad, blob)
+		if err != nil {
+			t.Fatalf("Unwrap(%d): %v", n, err)
+		}
+		if !bytes.Equal(key, got) {
+			t.Fatalf("Unwrap(%d): key mismatch", n)
+		}
+
+		// Every bit of the blob is authenticated.
+		for i := 1; i < len(blob); i++ {
+			blob[i] ^= 0x01
+			if got, err := Unwrap(kek, aad, blob); err != ErrUnwrap || got != nil {
+				t.Fatalf("Unwrap(%d): accepted blob corrupted at %d", n, i)
+			}
+			blob[i] ^= 0x01
+		}
+		if _, err := Unwrap(kek, []byte("other"), blob); err != ErrUnwrap {
+			t.Fatalf("Unwrap(%d): accepted incorrect aad", n)
+		}
+		if _, err := Unwrap(mustRandBytes(32), aad, blob); err != ErrUnwrap {
+			t.Fatalf("Unwrap(%d): accepted incorrect kek", n)
+		}
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	kek := mustRandBytes(32)
+	w, err := New(kek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Reset()
+
+	if _, err := w.Wrap(nil, nil); err != ErrInvalidKeySize {
+		t.Fatalf("Wrap: accepted an empty key")
+	}
+	if _, err := New(nil); err == nil {
+		t.Fatalf("New: accepted an empty kek")
+	}
+
+	blob, err := w.Wrap(nil, mustRandBytes(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A nil aad is the same as an empty one.
+	if _, err := w.Unwrap([]byte{}, blob); err != nil {
+		t.Fatalf("Unwrap: rejected empty aad: %v", err)
+	}
+
+	for _, n := range []int{0, 1, Overhead} {
+		if _, err := w.Unwrap(nil, blob[:n]); err != ErrUnwrap {
+			t.Fatalf("Unwrap: accepted truncated blob (%d bytes)", n)
+		}
+	}
+
+	blob[0] = Version + 1
+	if _, err := w.Unwrap(nil, blob); err != ErrUnsupportedVersion {
+		t.Fatalf("Unwrap: accepted unknown version")
+	}
+}
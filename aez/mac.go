@@ -0,0 +1,277 @@
+// mac.go - AEZ-hash and AEZ-prf as a MAC and PRF
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to aez, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package aez
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+)
+
+// PRF fills out with AEZ-prf(key, inputs, len(out)), the arbitrary length
+// PRF that AEZ uses for empty messages, over the vector of inputs.  The
+// output for a given vector is the same as that of Encrypt with an empty
+// plaintext, a nonce of inputs[0], associated data of inputs[1:], and a tau
+// of len(out), so the same key should not be used for both unless the
+// inputs are domain separated.  An empty vector is equivalent to a vector
+// with one empty element.
+func PRF(key []byte, inputs [][]byte, out []byte) {
+	var e eState
+	defer e.reset()
+
+	e.init(key)
+	e.prf(inputs, out)
+}
+
+func (e *eState) prf(inputs [][]byte, out []byte) {
+	var delta [blockSize]byte
+
+	var nonce []byte
+	var ad [][]byte
+	if len(inputs) > 0 {
+		nonce, ad = inputs[0], inputs[1:]
+	}
+
+	e.aezHash(nonce, ad, len(out)*8, delta[:])
+	e.aezPRF(&delta, len(out), out)
+
+	memwipe(delta[:])
+}
+
+// MAC is a message authentication code built from AEZ-hash and AEZ-prf, with
+// a tag of any length.  The tag of a message is PRF(key, {message}, tag), and
+// unlike PRF, the message is hashed incrementally as it is written.  It
+// implements hash.Hash.
+type MAC struct {
+	e    *eState
+	size int
+
+	h hashState
+
+	ownsKey    bool
+	wasCleared bool
+}
+
+// NewMAC creates and returns a new MAC instance with the given key, which
+// may be of any non-zero length, and a tag size of size bytes.
+func NewMAC(key []byte, size int) (*MAC, error) {
+	if len(key) == 0 {
+		return nil, errInvalidKeySize
+	}
+
+	e := new(eState)
+	e.init(key)
+	m, err := newMAC(e, size)
+	if err != nil {
+		e.reset()
+		return nil, err
+	}
+	m.ownsKey = true
+
+	return m, nil
+}
+
+func newMAC(e *eState, size int) (*MAC, error) {
+	if size < 1 || size > maxTagSize {
+		return nil, errInvalidTagSize
+	}
+
+	m := &MAC{
+		e:    e,
+		size: size,
+	}
+	m.Reset()
+
+	return m, nil
+}
+
+// Size returns the MAC tag size in bytes.
+func (m *MAC) Size() int {
+	return m.size
+}
+
+// BlockSize returns the underlying block size in bytes.
+func (m *MAC) BlockSize() int {
+	return blockSize
+}
+
+// Reset resets the MAC to its initial state, ready to authenticate a new
+// message under the same key.
+func (m *MAC) Reset() {
+	if m.wasCleared {
+		panic("aez: Reset() called after Clear()")
+	}
+	m.h.init(m.e, m.size*8)
+}
+
+// Clear clears the sensitive keying material and hash state from the
+// datastructure such that it will no longer be in memory.  A MAC created
+// with Cipher.NewMAC shares the Cipher's key, which is instead cleared by
+// the Cipher's Reset.  The MAC may not be used after Clear is called.
+func (m *MAC) Clear() {
+	if !m.wasCleared {
+		m.wasCleared = true
+		m.h.reset()
+		if m.ownsKey {
+			m.e.reset()
+		}
+	}
+}
+
+// Write adds more data to the running MAC.  It never returns an error.
+func (m *MAC) Write(b []byte) (int, error) {
+	if m.wasCleared {
+		panic("aez: Write() called after Clear()")
+	}
+	m.h.write(b)
+	return len(b), nil
+}
+
+// Sum appends the current MAC tag to b and returns the resulting slice.  It
+// does not change the underlying MAC state.
+func (m *MAC) Sum(b []byte) []byte {
+	var delta [blockSize]byte
+
+	if m.wasCleared {
+		panic("aez: Sum() called after Clear()")
+	}
+
+	ret, out := sliceForAppend(b, m.size)
+	m.h.sum(&delta)
+	m.e.aezPRF(&delta, m.size, out)
+	memwipe(delta[:])
+
+	return ret
+}
+
+// Verify returns true iff tag is the MAC of the data written so far.  The
+// comparison is done in constant time.
+func (m *MAC) Verify(tag []byte) bool {
+	if len(tag) != m.size {
+		return false
+	}
+
+	expected := m.Sum(nil)
+	defer memwipe(expected)
+
+	return subtle.ConstantTimeCompare(expected, tag) == 1
+}
+
+// hashState is an incremental AEZ-hash, equivalent to eState.aezHash.
+type hashState struct {
+	e *eState
+
+	acc  [blockSize]byte // The running sum over all completed blocks.
+	I, J [blockSize]byte // The current I doubling, and element multiple.
+	buf  [blockSize]byte
+	nBuf int
+	i    uint // The index of the next full block in the element.
+	k    int  // The element index, with 0 being the nonce.
+	n    int  // The number of bytes in the element.
+}
+
+func (h *hashState) init(e *eState, tau int) {
+	var buf, J [blockSize]byte
+
+	*h = hashState{e: e}
+
+	binary.BigEndian.PutUint32(buf[12:], uint32(tau))
+	xorBytes1x16(e.J[0][:], e.J[1][:], J[:])     // J ^ J2
+	e.aes4(&J, &e.I[1], &e.L[1], buf[:], &h.acc) // E(3,1)
+
+	h.startElement()
+}
+
+func (h *hashState) startElement() {
+	h.I = h.e.I[1]
+	if h.k == 0 {
+		h.J = h.e.J[2]
+	} else {
+		multBlock(uint(4+h.k), &h.e.J[0], &h.J)
+	}
+	h.i, h.n, h.nBuf = 1, 0, 0
+}
+
+// nextElement finishes the current element of the input vector, and starts
+// the next one.
+func (h *hashState) nextElement() {
+	h.finishElement(&h.acc)
+	h.k++
+	h.startElement()
+}
+
+func (h *hashState) write(b []byte) {
+	var tmp [blockSize]byte
+
+	h.n += len(b)
+	for len(b) > 0 {
+		if h.nBuf == 0 && h.i%8 == 1 && len(b) >= 8*blockSize {
+			// Hash whole cycles of the L multiples at once, which is
+			// where the I multiple is doubled.
+			n := len(b) &^ (8*blockSize - 1)
+			h.e.aezHashBlocks(&h.J, &h.I, b[:n], &h.acc)
+			for k := 0; k < n; k += 8 * blockSize {
+				doubleBlock(&h.I)
+			}
+			h.i += uint(n / blockSize)
+			b = b[n:]
+			continue
+		}
+		if h.nBuf == 0 && len(b) >= blockSize {
+			h.block(b[:blockSize], &tmp)
+			b = b[blockSize:]
+			continue
+		}
+
+		n := copy(h.buf[h.nBuf:], b)
+		h.nBuf += n
+		b = b[n:]
+		if h.nBuf == blockSize {
+			h.block(h.buf[:], &tmp)
+			h.nBuf = 0
+		}
+	}
+
+	memwipe(tmp[:])
+}
+
+func (h *hashState) block(b []byte, tmp *[blockSize]byte) {
+	h.e.aes4(&h.J, &h.I, &h.e.L[h.i%8], b, tmp) // E(4,i) or E(5+k,i)
+	xorBytes1x16(h.acc[:], tmp[:], h.acc[:])
+	if h.i%8 == 0 {
+		doubleBlock(&h.I)
+	}
+	h.i++
+}
+
+// finishElement XORs the contribution of the buffered partial block of the
+// current element, if any, into sum.
+func (h *hashState) finishElement(sum *[blockSize]byte) {
+	var buf [blockSize]byte
+
+	if h.nBuf > 0 || h.n == 0 {
+		copy(buf[:], h.buf[:h.nBuf])
+		buf[h.nBuf] = 0x80
+		h.e.aes4(&h.J, &h.e.I[0], &h.e.L[0], buf[:], &buf) // E(4,0) or E(5+k,0)
+		xorBytes1x16(sum[:], buf[:], sum[:])
+		memwipe(buf[:])
+	}
+}
+
+func (h *hashState) reset() {
+	memwipe(h.acc[:])
+	memwipe(h.I[:])
+	memwipe(h.J[:])
+	memwipe(h.buf[:])
+}
+
+// sum writes the hash of the vector so far, with the current element being
+// the last, to result without changing the state.
+func (h *hashState) sum(result *[blockSize]byte) {
+	*result = h.acc
+	h.finishElement(result)
+}
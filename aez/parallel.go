@@ -0,0 +1,182 @@
+// parallel.go - Parallel AEZ core passes
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to aez, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package aez
+
+import (
+	"runtime"
+	"sync"
+)
+
+// shardAlign is the granularity at which the core passes may be split, as
+// the I multiple is doubled and the L multiples wrap every 8 block pairs.
+const shardAlign = 8 * 32
+
+// parallelMinShard is the smallest number of bytes that a core pass is split
+// into, so that messages shorter than twice this are always processed
+// serially.  The SetParallelism documentation quotes this value.
+const parallelMinShard = 128 * 1024
+
+// SetParallelism sets the number of goroutines that the two core passes over
+// long messages are split across.  If workers is <= 0, runtime.GOMAXPROCS(0)
+// is used, and a value of 1, the default, processes every message serially.
+// Messages are only split if each goroutine has at least 128 KiB of input,
+// and the output is identical to that of the serial implementation.  It must
+// not be called concurrently with any other method.
+func (c *Cipher) SetParallelism(workers int) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	c.e.workers = workers
+}
+
+// SetParallelism sets the number of goroutines that the two core passes over
+// long messages are split across.  See Cipher.SetParallelism for details.
+func (a *AeadAEZ) SetParallelism(workers int) {
+	a.c.SetParallelism(workers)
+}
+
+type coreShard struct {
+	sum [blockSize]byte // The shard's contribution to X or Y.
+	I   [blockSize]byte // The I multiple at the start of the shard.
+}
+
+// coreShards splits a core pass over sz bytes into shards of a multiple of
+// shardAlign bytes each, and at least minShard bytes, and derives the
+// starting I multiple of each shard.  It returns nil if the pass should be
+// done serially.
+func (e *eState) coreShards(sz, minShard int) ([]coreShard, int) {
+	n := e.workers
+	if max := sz / minShard; n > max {
+		n = max
+	}
+	if n <= 1 {
+		return nil, 0
+	}
+	shardSz := (sz/n + shardAlign - 1) &^ (shardAlign - 1)
+	n = (sz + shardSz - 1) / shardSz
+
+	// Each shard starts shardSz/shardAlign doublings of I after the previous
+	// one, so step the multiple along by x^(shardSz/shardAlign).
+	var step [blockSize]byte
+	powX(shardSz/shardAlign, &step)
+
+	shards := make([]coreShard, n)
+	shards[0].I = e.I[1]
+	for i := 1; i < n; i++ {
+		mulBlock(&shards[i-1].I, &step, &shards[i].I)
+	}
+	memwipe(step[:])
+
+	return shards, shardSz
+}
+
+// corePass1 is aezCorePass1, split across goroutines into shards of at least
+// minShard bytes.
+func (e *eState) corePass1(in, out []byte, X *[blockSize]byte, sz, minShard int) {
+	shards, shardSz := e.coreShards(sz, minShard)
+	if shards == nil {
+		e.aezCorePass1(in, out, X, &e.I[1], sz)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := range shards {
+		off := i * shardSz
+		n := sz - off
+		if n > shardSz {
+			n = shardSz
+		}
+		wg.Add(1)
+		go func(s *coreShard, in, out []byte, n int) {
+			defer wg.Done()
+			e.aezCorePass1(in, out, &s.sum, &s.I, n)
+		}(&shards[i], in[off:], out[off:], n)
+	}
+	wg.Wait()
+
+	combineShards(shards, X)
+}
+
+// corePass2 is aezCorePass2, split across goroutines into shards of at least
+// minShard bytes.
+func (e *eState) corePass2(in, out []byte, Y, S *[blockSize]byte, sz, minShard int) {
+	shards, shardSz := e.coreShards(sz, minShard)
+	if shards == nil {
+		e.aezCorePass2(in, out, Y, S, &e.I[1], sz)
+		return
+	}
+
+	// Share a copy of S with the goroutines, so that the caller's S stays
+	// on the stack for the serial case.
+	sCopy := new([blockSize]byte)
+	*sCopy = *S
+	defer memwipe(sCopy[:])
+
+	var wg sync.WaitGroup
+	for i := range shards {
+		off := i * shardSz
+		n := sz - off
+		if n > shardSz {
+			n = shardSz
+		}
+		wg.Add(1)
+		go func(s *coreShard, in, out []byte, n int) {
+			defer wg.Done()
+			e.aezCorePass2(in, out, &s.sum, sCopy, &s.I, n)
+		}(&shards[i], in[off:], out[off:], n)
+	}
+	wg.Wait()
+
+	combineShards(shards, Y)
+}
+
+// combineShards XORs the partial sums of each shard into sum, and clears the
+// shards.
+func combineShards(shards []coreShard, sum *[blockSize]byte) {
+	for i := range shards {
+		xorBytes1x16(sum[:], shards[i].sum[:], sum[:])
+		memwipe(shards[i].sum[:])
+		memwipe(shards[i].I[:])
+	}
+}
+
+// mulBlock sets dst to the product of a and b in GF(2^128), in the same
+// representation as doubleBlock.  It is constant time in both a and b.
+func mulBlock(a, b, dst *[blockSize]byte) {
+	var t, r [blockSize]byte
+
+	copy(t[:], a[:])
+	for i := blockSize - 1; i >= 0; i-- {
+		for j := uint(0); j < 8; j++ {
+			mask := -((b[i] >> j) & 1)
+			for k := range r {
+				r[k] ^= t[k] & mask
+			}
+			doubleBlock(&t)
+		}
+	}
+	copy(dst[:], r[:])
+
+	memwipe(t[:])
+	memwipe(r[:])
+}
+
+// powX sets dst to x^n in GF(2^128), that is, 1 doubled n times.
+func powX(n int, dst *[blockSize]byte) {
+	var sq [blockSize]byte
+
+	*dst = [blockSize]byte{}
+	dst[blockSize-1] = 1
+	sq[blockSize-1] = 2
+	for ; n != 0; n >>= 1 {
+		if n&1 != 0 { // This is fine, n isn't data/secret dependent.
+			mulBlock(dst, &sq, dst)
+		}
+		mulBlock(&sq, &sq, &sq)
+	}
+}
@@ -0,0 +1,98 @@
+// reader.go - AEZ with associated data read from io.Readers
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to aez, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package aez
+
+import "io"
+
+// EncryptReaders is Encrypt, with each element of the vector of additional
+// data read from an io.Reader until io.EOF.  The additional data is hashed
+// as it is read, so it never needs to be resident in memory.  If reading
+// from any of the readers fails, the error is returned, and nothing is
+// appended to dst.
+func (c *Cipher) EncryptReaders(nonce []byte, additionalData []io.Reader, tau int, plaintext, dst []byte) ([]byte, error) {
+	var delta [blockSize]byte
+	defer memwipe(delta[:])
+
+	if c.wasReset {
+		panic("aez: EncryptReaders() called after Reset()")
+	}
+	if err := c.e.aezHashReaders(nonce, additionalData, tau*8, &delta); err != nil {
+		return nil, err
+	}
+	return c.e.encryptDelta(&delta, tau, plaintext, dst), nil
+}
+
+// DecryptReaders is Decrypt, with each element of the vector of additional
+// data read from an io.Reader until io.EOF, as with EncryptReaders.  If the
+// ciphertext fails to authenticate, the error returned is the same as that
+// of Open.
+func (c *Cipher) DecryptReaders(nonce []byte, additionalData []io.Reader, tau int, ciphertext, dst []byte) ([]byte, error) {
+	var delta [blockSize]byte
+	defer memwipe(delta[:])
+
+	if c.wasReset {
+		panic("aez: DecryptReaders() called after Reset()")
+	}
+	if len(ciphertext) < tau {
+		return nil, errOpen
+	}
+	if err := c.e.aezHashReaders(nonce, additionalData, tau*8, &delta); err != nil {
+		return nil, err
+	}
+	ret, ok := c.e.decryptDelta(&delta, tau, ciphertext, dst)
+	if !ok {
+		return nil, errOpen
+	}
+	return ret, nil
+}
+
+// SealReaders is SealVector, with each element of the vector of additional
+// data read from an io.Reader, as with Cipher.EncryptReaders.
+func (a *AeadAEZ) SealReaders(dst, nonce, plaintext []byte, additionalData []io.Reader) ([]byte, error) {
+	if len(nonce) != a.nonceSize {
+		panic("aez: incorrect nonce length given to AEZ")
+	}
+	return a.c.EncryptReaders(nonce, additionalData, a.tagSize, plaintext, dst)
+}
+
+// OpenReaders is OpenVector, with each element of the vector of additional
+// data read from an io.Reader, as with Cipher.EncryptReaders.
+func (a *AeadAEZ) OpenReaders(dst, nonce, ciphertext []byte, additionalData []io.Reader) ([]byte, error) {
+	if len(nonce) != a.nonceSize {
+		panic("aez: incorrect nonce length given to AEZ")
+	}
+	return a.c.DecryptReaders(nonce, additionalData, a.tagSize, ciphertext, dst)
+}
+
+// aezHashReaders is aezHash, with the elements of the vector of additional
+// data read from readers, and hashed incrementally.
+func (e *eState) aezHashReaders(nonce []byte, additionalData []io.Reader, tau int, result *[blockSize]byte) error {
+	var h hashState
+	var buf [4096]byte
+	defer h.reset()
+	defer memwipe(buf[:])
+
+	h.init(e, tau)
+	h.write(nonce)
+	for _, r := range additionalData {
+		h.nextElement()
+		for {
+			n, err := r.Read(buf[:])
+			h.write(buf[:n])
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	h.sum(result)
+
+	return nil
+}
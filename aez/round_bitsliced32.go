@@ -76,6 +76,25 @@ func (r *roundB32) AES10(l *[blockSize]byte, src []byte, dst *[blockSize]byte) {
 	memwipeU32(q[:])
 }
 
+func (r *roundB32) aes10x2(
+	l0 *[blockSize]byte, src0 []byte, dst0 *[blockSize]byte,
+	l1 *[blockSize]byte, src1 []byte, dst1 *[blockSize]byte) {
+	var q [8]uint32
+	xorBytes1x16(src0, l0[:], dst0[:])
+	xorBytes1x16(src1, l1[:], dst1[:])
+
+	ct32.Load8xU32(&q, dst0[:], dst1[:])
+	for i := 0; i < 3; i++ {
+		r.round(&q, r.skey[0:])  // I
+		r.round(&q, r.skey[8:])  // J
+		r.round(&q, r.skey[16:]) // L
+	}
+	r.round(&q, r.skey[0:]) // I
+	ct32.Store8xU32(dst0[:], dst1[:], &q)
+
+	memwipeU32(q[:])
+}
+
 func (r *roundB32) round(q *[8]uint32, k []uint32) {
 	ct32.Sbox(q)
 	ct32.ShiftRows(q)
@@ -83,10 +102,10 @@ func (r *roundB32) round(q *[8]uint32, k []uint32) {
 	ct32.AddRoundKey(q, k)
 }
 
-func (r *roundB32) aezCorePass1(e *eState, in, out []byte, X *[blockSize]byte, sz int) {
+func (r *roundB32) aezCorePass1(e *eState, in, out []byte, X, startI *[blockSize]byte, sz int) {
 	var tmp0, tmp1, I [blockSize]byte
 
-	copy(I[:], e.I[1][:])
+	copy(I[:], startI[:])
 	i := 1
 
 	// Process 4 * 16 bytes at a time in a loop.
@@ -124,10 +143,10 @@ func (r *roundB32) aezCorePass1(e *eState, in, out []byte, X *[blockSize]byte, s
 	memwipe(I[:])
 }
 
-func (r *roundB32) aezCorePass2(e *eState, out []byte, Y, S *[blockSize]byte, sz int) {
+func (r *roundB32) aezCorePass2(e *eState, out []byte, Y, S, startI *[blockSize]byte, sz int) {
 	var tmp0, tmp1, I [blockSize]byte
 
-	copy(I[:], e.I[1][:])
+	copy(I[:], startI[:])
 	i := 1
 
 	// Process 4 * 16 bytes at a time in a loop.
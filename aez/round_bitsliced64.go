@@ -77,6 +77,29 @@ func (r *roundB64) AES10(l *[blockSize]byte, src []byte, dst *[blockSize]byte) {
 	memwipeU64(q[:])
 }
 
+func (r *roundB64) aes10x4(
+	l0 *[blockSize]byte, src0 []byte, dst0 *[blockSize]byte,
+	l1 *[blockSize]byte, src1 []byte, dst1 *[blockSize]byte,
+	l2 *[blockSize]byte, src2 []byte, dst2 *[blockSize]byte,
+	l3 *[blockSize]byte, src3 []byte, dst3 *[blockSize]byte) {
+	var q [8]uint64
+	xorBytes1x16(src0, l0[:], dst0[:])
+	xorBytes1x16(src1, l1[:], dst1[:])
+	xorBytes1x16(src2, l2[:], dst2[:])
+	xorBytes1x16(src3, l3[:], dst3[:])
+
+	ct64.Load16xU32(&q, dst0[:], dst1[:], dst2[:], dst3[:])
+	for i := 0; i < 3; i++ {
+		r.round(&q, r.skey[0:])  // I
+		r.round(&q, r.skey[8:])  // J
+		r.round(&q, r.skey[16:]) // L
+	}
+	r.round(&q, r.skey[0:]) // I
+	ct64.Store16xU32(dst0[:], dst1[:], dst2[:], dst3[:], &q)
+
+	memwipeU64(q[:])
+}
+
 func (r *roundB64) round(q *[8]uint64, k []uint64) {
 	ct64.Sbox(q)
 	ct64.ShiftRows(q)
@@ -84,10 +107,10 @@ func (r *roundB64) round(q *[8]uint64, k []uint64) {
 	ct64.AddRoundKey(q, k)
 }
 
-func (r *roundB64) aezCorePass1(e *eState, in, out []byte, X *[blockSize]byte, sz int) {
+func (r *roundB64) aezCorePass1(e *eState, in, out []byte, X, startI *[blockSize]byte, sz int) {
 	var tmp0, tmp1, tmp2, tmp3, I [blockSize]byte
 
-	copy(I[:], e.I[1][:])
+	copy(I[:], startI[:])
 	i := 1
 
 	// Process 8 * 16 bytes at a time in a loop.
@@ -147,10 +170,10 @@ func (r *roundB64) aezCorePass1(e *eState, in, out []byte, X *[blockSize]byte, s
 	memwipe(I[:])
 }
 
-func (r *roundB64) aezCorePass2(e *eState, out []byte, Y, S *[blockSize]byte, sz int) {
+func (r *roundB64) aezCorePass2(e *eState, out []byte, Y, S, startI *[blockSize]byte, sz int) {
 	var tmp0, tmp1, tmp2, tmp3, I [blockSize]byte
 
-	copy(I[:], e.I[1][:])
+	copy(I[:], startI[:])
 	i := 1
 
 	// Process 8 * 16 bytes at a time in a loop.
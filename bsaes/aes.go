@@ -26,6 +26,8 @@ package bsaes
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
+	"io"
 	"math"
 	"runtime"
 
@@ -39,32 +41,150 @@ const BlockSize = aes.BlockSize
 var (
 	useCryptoAES = false
 	ctor         = ct64.NewCipher
+	maskedCtor   = ct64.NewMaskedCipher
 )
 
 type resetAble interface {
 	Reset()
 }
 
+// bulkECBAble is the subset of the bitsliced implementations' bulk interface
+// used by the modes implemented in this package.
+type bulkECBAble interface {
+	cipher.Block
+
+	// Stride returns the number of BlockSize-ed blocks that should be
+	// passed to BulkEncrypt.
+	Stride() int
+
+	// BulkEncrypt encrypts the Stride blocks of plaintext src, and places
+	// the resulting output in the ciphertext dst.
+	BulkEncrypt(dst, src []byte)
+}
+
+// Option is an optional parameter to NewCipherWithOptions.
+type Option func(*options)
+
+type options struct {
+	masked      bool
+	maskRand    io.Reader
+	faultDetect bool
+}
+
+// WithMasking selects the first order Boolean masked bitsliced
+// implementation, which provides a degree of resistance to power analysis
+// at a significant performance cost.  rand is the source of the masks, and
+// is read from on every call.  If rand is nil, crypto/rand.Reader is used.
+// The runtime's AES implementation is never used when masking is enabled.
+func WithMasking(rand io.Reader) Option {
+	return func(o *options) {
+		o.masked = true
+		o.maskRand = rand
+	}
+}
+
+// WithFaultDetection enables checking every block cipher invocation by
+// applying the inverse operation to the output, and comparing the result with
+// the input in constant time.  On a mismatch the output is cleared, and the
+// call panics with ErrFaultDetected.  This more than halves throughput.  The
+// runtime's AES implementation is never used when fault detection is enabled.
+func WithFaultDetection() Option {
+	return func(o *options) {
+		o.faultDetect = true
+	}
+}
+
 // NewCipher creates and returns a new cipher.Block.  The key argument should
 // be the AES key, either 16, 24, or 32 bytes to select AES-128, AES-192, or
 // AES-256.
 func NewCipher(key []byte) (cipher.Block, error) {
+	return NewCipherWithOptions(key)
+}
+
+// NewCipherWithOptions creates and returns a new cipher.Block, with the
+// implementation altered by opts.  With no options, it is identical to
+// NewCipher.
+func NewCipherWithOptions(key []byte, opts ...Option) (cipher.Block, error) {
 	switch len(key) {
 	case 16, 24, 32:
 	default:
 		return nil, aes.KeySizeError(len(key))
 	}
-	if useCryptoAES {
-		return aes.NewCipher(key)
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
 	}
 
-	blk := ctor(key)
+	var blk cipher.Block
+	switch {
+	case o.masked:
+		r := o.maskRand
+		if r == nil {
+			r = rand.Reader
+		}
+		blk = maskedCtor(key, r)
+	case useCryptoAES && !o.faultDetect:
+		return aes.NewCipher(key)
+	default:
+		blk = ctor(key)
+	}
+	if o.faultDetect {
+		blk = newFaultDetectBlock(blk)
+	}
 	r := blk.(resetAble)
 	runtime.SetFinalizer(r, (resetAble).Reset)
 
 	return blk, nil
 }
 
+// BatchAEAD is a cipher.AEAD that can process many independent packets at
+// once.  The GCM instances returned by `crypto/cipher.NewGCM` and
+// `crypto/cipher.NewGCMWithNonceSize` for a bitsliced cipher.Block created by
+// NewCipher implement this interface, and pack the counter blocks from every
+// packet in a batch into shared bulk encryption calls.  When UsingRuntime
+// returns true, the runtime's GCM implementation is used instead, and this
+// interface is not available.
+type BatchAEAD interface {
+	cipher.AEAD
+
+	// SealBatch encrypts and authenticates each plaintexts[i] with
+	// nonces[i] and additionalData[i], appending the result to dst[i],
+	// and returns the updated slices.  dst and additionalData may be nil.
+	SealBatch(dst, nonces, plaintexts, additionalData [][]byte) [][]byte
+
+	// OpenBatch decrypts and authenticates each ciphertexts[i] with
+	// nonces[i] and additionalData[i], appending the result to dst[i],
+	// and returns the updated slices along with a per-packet error.  The
+	// plaintext of each packet that failed authentication is nil.  dst
+	// and additionalData may be nil.
+	OpenBatch(dst, nonces, ciphertexts, additionalData [][]byte) ([][]byte, []error)
+}
+
+// newBlock is NewCipher for callers that have already validated the key
+// length.
+func newBlock(key []byte) cipher.Block {
+	blk, err := NewCipher(key)
+	if err != nil {
+		panic("bsaes: newBlock: " + err.Error())
+	}
+	return blk
+}
+
+// resetIfAble clears the key material held by v (a cipher.Block or
+// cipher.Stream), if v supports it.
+func resetIfAble(v interface{}) {
+	if r, ok := v.(resetAble); ok {
+		r.Reset()
+	}
+}
+
+func memwipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
 // UsingRuntime returns true iff this package is falling through to the
 // runtime's implementation due to hardware support for constant time
 // operation on the current system.
@@ -79,8 +199,13 @@ func init() {
 	switch maxUintptr {
 	case math.MaxUint32:
 		ctor = ct32.NewCipher
+		maskedCtor = ct32.NewMaskedCipher
 	case math.MaxUint64:
 		ctor = ct64.NewCipher
+		if ct64.SupportsAVX2() {
+			ctor = ct64.NewCipherAVX2
+		}
+		maskedCtor = ct64.NewMaskedCipher
 	default:
 		panic("bsaes: unsupported architecture")
 	}
@@ -14,11 +14,12 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"math"
 	"testing"
 
-	"git.schwanenlied.me/yawning/bsaes.git/ct32"
-	"git.schwanenlied.me/yawning/bsaes.git/ct64"
+	"github.com/mad-day/Yawning-crypto/bsaes/ct32"
+	"github.com/mad-day/Yawning-crypto/bsaes/ct64"
 )
 
 type Impl struct {
@@ -27,8 +28,15 @@ type Impl struct {
 }
 
 var (
-	implCt32    = &Impl{"ct32", ct32.NewCipher}
-	implCt64    = &Impl{"ct64", ct64.NewCipher}
+	implCt32       = &Impl{"ct32", ct32.NewCipher}
+	implCt64       = &Impl{"ct64", ct64.NewCipher}
+	implCt64AVX2   = &Impl{"ct64-avx2", ct64.NewCipherAVX2}
+	implCt32Masked = &Impl{"ct32-masked", func(k []byte) cipher.Block {
+		return ct32.NewMaskedCipher(k, rand.Reader)
+	}}
+	implCt64Masked = &Impl{"ct64-masked", func(k []byte) cipher.Block {
+		return ct64.NewMaskedCipher(k, rand.Reader)
+	}}
 	implRuntime = &Impl{"runtime", func(k []byte) cipher.Block {
 		blk, err := NewCipher(k)
 		if err != nil {
@@ -37,7 +45,7 @@ var (
 		return blk
 	}}
 
-	impls      = []*Impl{implCt32, implCt64}
+	impls      = []*Impl{implCt32, implCt64, implCt32Masked, implCt64Masked}
 	nativeImpl = implCt64
 )
 
@@ -213,10 +221,12 @@ func TestCTR_keystream(t *testing.T) {
 	for _, impl := range impls {
 		strideSz := 0
 		switch impl.name {
-		case "ct32":
+		case "ct32", "ct32-masked":
 			strideSz = 2 * 16
-		case "ct64":
+		case "ct64", "ct64-masked":
 			strideSz = 4 * 16
+		case "ct64-avx2":
+			strideSz = 16 * 16
 		case "runtime":
 			// The CTR tests are tailored towards the bsaes CTR
 			// so there is not much sense in testing `crypto/aes`'s,
@@ -652,6 +662,40 @@ func implIsNative(impl *Impl) bool {
 	return impl == nativeImpl || impl == implRuntime
 }
 
+func implIsMasked(impl *Impl) bool {
+	return impl == implCt32Masked || impl == implCt64Masked
+}
+
+func TestNewCipherWithOptions(t *testing.T) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key[:]); err != nil {
+		t.Fatal(err)
+	}
+	refBlk, _ := aes.NewCipher(key)
+
+	for _, r := range []io.Reader{nil, rand.Reader} {
+		blk, err := NewCipherWithOptions(key, WithMasking(r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := blk.(bulkECBAble); !ok {
+			t.Fatalf("WithMasking did not return a bitsliced implementation")
+		}
+
+		var src, dst, check [BlockSize]byte
+		if _, err := rand.Read(src[:]); err != nil {
+			t.Fatal(err)
+		}
+		blk.Encrypt(dst[:], src[:])
+		refBlk.Encrypt(check[:], src[:])
+		assertEqual(t, 0, check[:], dst[:])
+	}
+
+	if _, err := NewCipherWithOptions(key[:15], WithMasking(nil)); err == nil {
+		t.Fatalf("NewCipherWithOptions accepted an invalid key")
+	}
+}
+
 func doBench(b *testing.B, impl *Impl) {
 	if testing.Short() && !implIsNative(impl) {
 		b.SkipNow()
@@ -687,6 +731,21 @@ func Benchmark_ct64(b *testing.B) {
 	doBench(b, implCt64)
 }
 
+func Benchmark_ct64_avx2(b *testing.B) {
+	if !ct64.SupportsAVX2() {
+		b.SkipNow()
+	}
+	doBench(b, implCt64AVX2)
+}
+
+func Benchmark_ct32_masked(b *testing.B) {
+	doBench(b, implCt32Masked)
+}
+
+func Benchmark_ct64_masked(b *testing.B) {
+	doBench(b, implCt64Masked)
+}
+
 func Benchmark_runtime(b *testing.B) {
 	if !useCryptoAES {
 		b.SkipNow()
@@ -701,6 +760,10 @@ func init() {
 		nativeImpl = implCt32
 	case math.MaxUint64:
 		nativeImpl = implCt64
+		if ct64.SupportsAVX2() {
+			nativeImpl = implCt64AVX2
+			impls = append(impls, implCt64AVX2)
+		}
 	default:
 		panic("bsaes: unsupported architecture")
 	}
@@ -0,0 +1,374 @@
+// Copyright (c) 2017 Yawning Angel <yawning at schwanenlied dot me>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS
+// BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN
+// ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package camellia is a constant time Camellia (RFC 3713) implementation.
+//
+// The Camellia S-boxes are all derived from s1, which is affine equivalent
+// to the AES S-box, so they are evaluated with the bitsliced AES S-box
+// circuit from the parent package wrapped in the appropriate affine
+// transforms, instead of with table lookups.  The round function of 4 blocks
+// is evaluated with a single pass through the circuit, which is exposed via
+// the bulk interface, allowing `crypto/cipher`'s CTR, CBC, and GCM to use the
+// less-slow implementations from the parent package.
+package camellia
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"math"
+	"runtime"
+	"strconv"
+
+	"github.com/mad-day/Yawning-crypto/bsaes/internal/modes"
+)
+
+const (
+	// BlockSize is the Camellia block size in bytes.
+	BlockSize = 16
+
+	maxRounds = 24
+	maxStride = 4
+
+	// sboxBufSize is the size of the S-box scratch buffer, which is large
+	// enough for the 64 bit circuit.  Only the first 8 * maxStride bytes
+	// carry data, the 64 bit circuit runs half empty, which on 64 bit
+	// targets costs about the same as the full 32 bit circuit.
+	sboxBufSize = 64
+)
+
+var sigma = [6]uint64{
+	0xa09e667f3bcc908b, 0xb67ae8584caa73b2, 0xc6ef372fe94f82be,
+	0x54ff53a5f1d36f1c, 0x10e527fade682d1d, 0xb05688c2b3e6c1fd,
+}
+
+var (
+	sboxes    = sboxes64
+	sboxBytes = 64
+)
+
+// KeySizeError is the error returned when the key is not 16, 24, or 32 bytes.
+type KeySizeError int
+
+func (k KeySizeError) Error() string {
+	return "bsaes/camellia: invalid key size " + strconv.Itoa(int(k))
+}
+
+// schedule is an expanded key schedule for one direction.
+type schedule struct {
+	kw [4]uint64
+	k  [maxRounds]uint64
+	ke [6]uint64
+}
+
+func (s *schedule) reset() {
+	*s = schedule{}
+}
+
+type block struct {
+	modes.BlockModesImpl
+
+	enc, dec schedule
+	rounds   int
+	wasReset bool
+}
+
+func (b *block) BlockSize() int {
+	return BlockSize
+}
+
+// Stride returns the number of blocks that should be passed to BulkEncrypt
+// and BulkDecrypt.
+func (b *block) Stride() int {
+	return maxStride
+}
+
+func (b *block) Encrypt(dst, src []byte) {
+	if b.wasReset {
+		panic("bsaes/camellia: Encrypt() called after Reset()")
+	}
+	b.crypt(dst, src, 1, &b.enc)
+}
+
+func (b *block) Decrypt(dst, src []byte) {
+	if b.wasReset {
+		panic("bsaes/camellia: Decrypt() called after Reset()")
+	}
+	b.crypt(dst, src, 1, &b.dec)
+}
+
+func (b *block) BulkEncrypt(dst, src []byte) {
+	if b.wasReset {
+		panic("bsaes/camellia: BulkEncrypt() called after Reset()")
+	}
+	b.crypt(dst, src, maxStride, &b.enc)
+}
+
+func (b *block) BulkDecrypt(dst, src []byte) {
+	if b.wasReset {
+		panic("bsaes/camellia: BulkDecrypt() called after Reset()")
+	}
+	b.crypt(dst, src, maxStride, &b.dec)
+}
+
+func (b *block) Reset() {
+	if !b.wasReset {
+		b.wasReset = true
+		b.enc.reset()
+		b.dec.reset()
+	}
+}
+
+// crypt encrypts or decrypts n blocks with the schedule sk, evaluating the
+// round function of every block with a single pass through the bitsliced
+// S-box each round.
+func (b *block) crypt(dst, src []byte, n int, sk *schedule) {
+	var d1, d2 [maxStride]uint64
+
+	for i := 0; i < n; i++ {
+		s := src[i*BlockSize:]
+		d1[i] = binary.BigEndian.Uint64(s[0:]) ^ sk.kw[0]
+		d2[i] = binary.BigEndian.Uint64(s[8:]) ^ sk.kw[1]
+	}
+
+	for r := 0; r < b.rounds; r += 2 {
+		if r > 0 && r%6 == 0 {
+			ke := sk.ke[(r/6-1)*2:]
+			for i := 0; i < n; i++ {
+				d1[i] = fl(d1[i], ke[0])
+				d2[i] = flInv(d2[i], ke[1])
+			}
+		}
+		f(&d2, &d1, sk.k[r], n)
+		f(&d1, &d2, sk.k[r+1], n)
+	}
+
+	for i := 0; i < n; i++ {
+		d := dst[i*BlockSize:]
+		binary.BigEndian.PutUint64(d[0:], d2[i]^sk.kw[2])
+		binary.BigEndian.PutUint64(d[8:], d1[i]^sk.kw[3])
+	}
+
+	d1, d2 = [maxStride]uint64{}, [maxStride]uint64{}
+}
+
+// f xors the F function of each of the first n words of src keyed with k
+// into the corresponding word of dst.
+func f(dst, src *[maxStride]uint64, k uint64, n int) {
+	var t [sboxBufSize]byte
+
+	for i := 0; i < n; i++ {
+		binary.BigEndian.PutUint64(t[i*8:], src[i]^k)
+	}
+
+	// s4(x) = s1(x <<< 1), s2(x) = s1(x) <<< 1, and s3(x) = s1(x) >>> 1,
+	// so every byte goes through s1, with the rotations done out here.
+	for i := 0; i < n; i++ {
+		x := t[i*8 : i*8+8]
+		x[3], x[6] = rotl8(x[3], 1), rotl8(x[6], 1)
+	}
+	sboxes(t[:sboxBytes])
+	for i := 0; i < n; i++ {
+		x := t[i*8 : i*8+8]
+		x[1], x[4] = rotl8(x[1], 1), rotl8(x[4], 1)
+		x[2], x[5] = rotl8(x[2], 7), rotl8(x[5], 7)
+		dst[i] ^= p(x)
+	}
+
+	for i := range t {
+		t[i] = 0
+	}
+}
+
+// p is the P function, applied to the 8 S-box outputs t.
+func p(t []byte) uint64 {
+	t1, t2, t3, t4, t5, t6, t7, t8 := t[0], t[1], t[2], t[3], t[4], t[5], t[6], t[7]
+
+	var y [8]byte
+	y[0] = t1 ^ t3 ^ t4 ^ t6 ^ t7 ^ t8
+	y[1] = t1 ^ t2 ^ t4 ^ t5 ^ t7 ^ t8
+	y[2] = t1 ^ t2 ^ t3 ^ t5 ^ t6 ^ t8
+	y[3] = t2 ^ t3 ^ t4 ^ t5 ^ t6 ^ t7
+	y[4] = t1 ^ t2 ^ t6 ^ t7 ^ t8
+	y[5] = t2 ^ t3 ^ t5 ^ t7 ^ t8
+	y[6] = t3 ^ t4 ^ t5 ^ t6 ^ t8
+	y[7] = t1 ^ t4 ^ t5 ^ t6 ^ t7
+
+	return binary.BigEndian.Uint64(y[:])
+}
+
+func fl(x, ke uint64) uint64 {
+	x1, x2 := uint32(x>>32), uint32(x)
+	k1, k2 := uint32(ke>>32), uint32(ke)
+	x2 ^= rotl32(x1&k1, 1)
+	x1 ^= x2 | k2
+	return uint64(x1)<<32 | uint64(x2)
+}
+
+func flInv(y, ke uint64) uint64 {
+	y1, y2 := uint32(y>>32), uint32(y)
+	k1, k2 := uint32(ke>>32), uint32(ke)
+	y1 ^= y2 | k2
+	y2 ^= rotl32(y1&k1, 1)
+	return uint64(y1)<<32 | uint64(y2)
+}
+
+func rotl8(x byte, n uint) byte {
+	return (x << n) | (x >> (8 - n))
+}
+
+func rotl32(x uint32, n uint) uint32 {
+	return (x << n) | (x >> (32 - n))
+}
+
+// rotl128 returns the 128 bit value x rotated left by n bits, as a pair of
+// 64 bit halves.
+func rotl128(x [2]uint64, n uint) (uint64, uint64) {
+	hi, lo := x[0], x[1]
+	if n >= 64 {
+		hi, lo, n = lo, hi, n-64
+	}
+	if n == 0 {
+		return hi, lo
+	}
+	return hi<<n | lo>>(64-n), lo<<n | hi>>(64-n)
+}
+
+// feistel xors the F function of the word at src keyed with k into the word
+// at dst, for the key schedule.
+func feistel(dst, src *uint64, k uint64) {
+	var d, s [maxStride]uint64
+
+	d[0], s[0] = *dst, *src
+	f(&d, &s, k, 1)
+	*dst = d[0]
+}
+
+func (b *block) keysched(key []byte) {
+	var kl, kr, ka, kb [2]uint64
+
+	kl[0] = binary.BigEndian.Uint64(key[0:])
+	kl[1] = binary.BigEndian.Uint64(key[8:])
+	switch len(key) {
+	case 24:
+		kr[0] = binary.BigEndian.Uint64(key[16:])
+		kr[1] = ^kr[0]
+	case 32:
+		kr[0] = binary.BigEndian.Uint64(key[16:])
+		kr[1] = binary.BigEndian.Uint64(key[24:])
+	}
+
+	d1, d2 := kl[0]^kr[0], kl[1]^kr[1]
+	feistel(&d2, &d1, sigma[0])
+	feistel(&d1, &d2, sigma[1])
+	d1, d2 = d1^kl[0], d2^kl[1]
+	feistel(&d2, &d1, sigma[2])
+	feistel(&d1, &d2, sigma[3])
+	ka = [2]uint64{d1, d2}
+
+	e := &b.enc
+	if len(key) == 16 {
+		b.rounds = 18
+		e.kw[0], e.kw[1] = kl[0], kl[1]
+		e.k[0], e.k[1] = ka[0], ka[1]
+		e.k[2], e.k[3] = rotl128(kl, 15)
+		e.k[4], e.k[5] = rotl128(ka, 15)
+		e.ke[0], e.ke[1] = rotl128(ka, 30)
+		e.k[6], e.k[7] = rotl128(kl, 45)
+		e.k[8], _ = rotl128(ka, 45)
+		_, e.k[9] = rotl128(kl, 60)
+		e.k[10], e.k[11] = rotl128(ka, 60)
+		e.ke[2], e.ke[3] = rotl128(kl, 77)
+		e.k[12], e.k[13] = rotl128(kl, 94)
+		e.k[14], e.k[15] = rotl128(ka, 94)
+		e.k[16], e.k[17] = rotl128(kl, 111)
+		e.kw[2], e.kw[3] = rotl128(ka, 111)
+	} else {
+		d1, d2 = ka[0]^kr[0], ka[1]^kr[1]
+		feistel(&d2, &d1, sigma[4])
+		feistel(&d1, &d2, sigma[5])
+		kb = [2]uint64{d1, d2}
+
+		b.rounds = 24
+		e.kw[0], e.kw[1] = kl[0], kl[1]
+		e.k[0], e.k[1] = kb[0], kb[1]
+		e.k[2], e.k[3] = rotl128(kr, 15)
+		e.k[4], e.k[5] = rotl128(ka, 15)
+		e.ke[0], e.ke[1] = rotl128(kr, 30)
+		e.k[6], e.k[7] = rotl128(kb, 30)
+		e.k[8], e.k[9] = rotl128(kl, 45)
+		e.k[10], e.k[11] = rotl128(ka, 45)
+		e.ke[2], e.ke[3] = rotl128(kl, 60)
+		e.k[12], e.k[13] = rotl128(kr, 60)
+		e.k[14], e.k[15] = rotl128(kb, 60)
+		e.k[16], e.k[17] = rotl128(kl, 77)
+		e.ke[4], e.ke[5] = rotl128(ka, 77)
+		e.k[18], e.k[19] = rotl128(kr, 94)
+		e.k[20], e.k[21] = rotl128(ka, 94)
+		e.k[22], e.k[23] = rotl128(kl, 111)
+		e.kw[2], e.kw[3] = rotl128(kb, 111)
+	}
+
+	// Decryption is encryption with the subkeys in reverse order, which
+	// swaps the whitening keys and the FL/FL^-1 subkey of each layer.
+	d := &b.dec
+	d.kw[0], d.kw[1], d.kw[2], d.kw[3] = e.kw[2], e.kw[3], e.kw[0], e.kw[1]
+	for i := 0; i < b.rounds; i++ {
+		d.k[i] = e.k[b.rounds-1-i]
+	}
+	nrLayers := b.rounds/6 - 1
+	for i := 0; i < nrLayers; i++ {
+		j := nrLayers - 1 - i
+		d.ke[2*i], d.ke[2*i+1] = e.ke[2*j+1], e.ke[2*j]
+	}
+
+	kl, kr, ka, kb = [2]uint64{}, [2]uint64{}, [2]uint64{}, [2]uint64{}
+}
+
+// NewCipher creates and returns a new cipher.Block.  The key argument should
+// be the 16, 24, or 32 byte Camellia key, to select Camellia-128,
+// Camellia-192, or Camellia-256.
+func NewCipher(key []byte) (cipher.Block, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, KeySizeError(len(key))
+	}
+
+	b := new(block)
+	b.keysched(key)
+	b.BlockModesImpl.Init(b)
+	runtime.SetFinalizer(b, (*block).Reset)
+
+	return b, nil
+}
+
+func init() {
+	maxUintptr := uint64(^uintptr(0))
+	switch maxUintptr {
+	case math.MaxUint32:
+		sboxes, sboxBytes = sboxes32, 32
+	case math.MaxUint64:
+		sboxes, sboxBytes = sboxes64, 64
+	default:
+		panic("bsaes/camellia: unsupported architecture")
+	}
+}
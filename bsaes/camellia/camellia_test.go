@@ -0,0 +1,170 @@
+// camellia_test.go - Camellia tests.
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to camellia_test.go, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package camellia
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+// The vectors are from RFC 3713 Appendix A, one per key size.
+var ecbVectors = []struct {
+	key        string
+	plaintext  string
+	ciphertext string
+}{
+	{
+		"0123456789abcdeffedcba9876543210",
+		"0123456789abcdeffedcba9876543210",
+		"67673138549669730857065648eabe43",
+	},
+	{
+		"0123456789abcdeffedcba98765432100011223344556677",
+		"0123456789abcdeffedcba9876543210",
+		"b4993401b3e996f84ee5cee7d79b09b9",
+	},
+	{
+		"0123456789abcdeffedcba987654321000112233445566778899aabbccddeeff",
+		"0123456789abcdeffedcba9876543210",
+		"9acc237dff16d76c20ef7c919e3a7509",
+	},
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic("mustDecodeHex: " + err.Error())
+	}
+	return b
+}
+
+func assertEqual(t *testing.T, idx int, expected, actual []byte) {
+	if !bytes.Equal(expected, actual) {
+		t.Fatalf("[%d]: expected %x, actual %x", idx, expected, actual)
+	}
+}
+
+func TestECB(t *testing.T) {
+	for i, vec := range ecbVectors {
+		key := mustDecodeHex(vec.key)
+		pt := mustDecodeHex(vec.plaintext)
+		ct := mustDecodeHex(vec.ciphertext)
+
+		b, err := NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dst := make([]byte, BlockSize)
+		b.Encrypt(dst, pt)
+		assertEqual(t, i, ct, dst)
+		b.Decrypt(dst, dst)
+		assertEqual(t, i, pt, dst)
+
+		// The bulk path runs every block through the S-box together, so
+		// check it with the vector in each of the block positions.
+		blk := b.(*block)
+		bulkPt := bytes.Repeat(pt, blk.Stride())
+		bulkCt := bytes.Repeat(ct, blk.Stride())
+		bulkDst := make([]byte, len(bulkPt))
+		blk.BulkEncrypt(bulkDst, bulkPt)
+		assertEqual(t, i, bulkCt, bulkDst)
+		blk.BulkDecrypt(bulkDst, bulkDst)
+		assertEqual(t, i, bulkPt, bulkDst)
+	}
+
+	if _, err := NewCipher(make([]byte, 15)); err == nil {
+		t.Fatalf("NewCipher accepted an invalid key")
+	}
+}
+
+func TestFL(t *testing.T) {
+	var buf [16]byte
+	for i := 0; i < 1000; i++ {
+		if _, err := rand.Read(buf[:]); err != nil {
+			t.Fatal(err)
+		}
+		x, ke := binary.BigEndian.Uint64(buf[0:]), binary.BigEndian.Uint64(buf[8:])
+
+		if y := flInv(fl(x, ke), ke); y != x {
+			t.Fatalf("[%d]: FL^-1(FL(%016x)) = %016x", i, x, y)
+		}
+		if y := fl(flInv(x, ke), ke); y != x {
+			t.Fatalf("[%d]: FL(FL^-1(%016x)) = %016x", i, x, y)
+		}
+	}
+
+	// RFC 3713 2.4.3: with KL = kl1 || kl2,
+	//   x2 = x2 ^ ((x1 & kl1) <<< 1)
+	//   x1 = x1 ^ (x2 | kl2)
+	x := uint64(0x0123456789abcdef)
+	ke := uint64(0xfedcba9876543210)
+	x2 := uint32(0x89abcdef) ^ rotl32(0x01234567&0xfedcba98, 1)
+	x1 := uint32(0x01234567) ^ (x2 | 0x76543210)
+	if y := fl(x, ke); y != uint64(x1)<<32|uint64(x2) {
+		t.Fatalf("FL(%016x) = %016x, expected %08x%08x", x, y, x1, x2)
+	}
+}
+
+func TestKeySchedule192(t *testing.T) {
+	// A 192 bit key K is expanded with KR = K[128:192] || ^K[128:192], so it
+	// behaves exactly like the corresponding 256 bit key.
+	key := make([]byte, 32)
+	if _, err := rand.Read(key[:24]); err != nil {
+		t.Fatal(err)
+	}
+	for i := 24; i < 32; i++ {
+		key[i] = ^key[i-8]
+	}
+
+	b192, err := NewCipher(key[:24])
+	if err != nil {
+		t.Fatal(err)
+	}
+	b256, err := NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pt := make([]byte, BlockSize)
+	if _, err := rand.Read(pt); err != nil {
+		t.Fatal(err)
+	}
+	ct192, ct256 := make([]byte, BlockSize), make([]byte, BlockSize)
+	b192.Encrypt(ct192, pt)
+	b256.Encrypt(ct256, pt)
+	assertEqual(t, 0, ct256, ct192)
+
+	// Otherwise the comparison above would also pass if the second half of
+	// the 256 bit KR was ignored.
+	key[31] ^= 0x01
+	b256, _ = NewCipher(key)
+	b256.Encrypt(ct256, pt)
+	if bytes.Equal(ct192, ct256) {
+		t.Fatalf("192 bit key schedule ignores the second half of KR")
+	}
+}
+
+var benchOutput []byte
+
+func BenchmarkCTR(b *testing.B) {
+	var key, iv [16]byte
+	blk, _ := NewCipher(key[:])
+	buf := make([]byte, 16384)
+
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cipher.NewCTR(blk, iv[:]).XORKeyStream(buf, buf)
+	}
+	benchOutput = buf
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2017 Yawning Angel <yawning at schwanenlied dot me>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS
+// BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN
+// ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package camellia
+
+import "github.com/mad-day/Yawning-crypto/bsaes/internal/sbox"
+
+// s1 is the Camellia s1 S-box, expressed in terms of the AES S-box.
+var s1 = sbox.Sbox{In: s1In, Out: s1Out}
+
+// sboxes32 applies s1 to each of the 32 bytes of b.
+func sboxes32(b []byte) {
+	s1.Apply32(b)
+}
+
+// sboxes64 applies s1 to each of the 64 bytes of b.
+func sboxes64(b []byte) {
+	s1.Apply64(b)
+}
+
+// s1In maps the Camellia s1 S-box input to the AES S-box input.
+func s1In(q [8]uint64) [8]uint64 {
+	q0, q1, q2, q3, q4, q5, q6, q7 := q[0], q[1], q[2], q[3], q[4], q[5], q[6], q[7]
+
+	q[0] = q0 ^ q1 ^ q2 ^ q3 ^ q4 ^ q5 ^ q6 ^ q7
+	q[1] = q3 ^ q4 ^ q5
+	q[2] = q4
+	q[3] = ^(q1 ^ q3 ^ q7)
+	q[4] = q1 ^ q2 ^ q5 ^ q7
+	q[5] = q2 ^ q3 ^ q4 ^ q6
+	q[6] = q6 ^ q7
+	q[7] = q2 ^ q3 ^ q4 ^ q5 ^ q7
+
+	return q
+}
+
+// s1Out maps the AES S-box output to the Camellia s1 S-box output.
+func s1Out(q [8]uint64) [8]uint64 {
+	q0, q1, q2, q3, q4, q5, q6, q7 := q[0], q[1], q[2], q[3], q[4], q[5], q[6], q[7]
+
+	q[0] = ^(q0 ^ q1 ^ q2 ^ q5)
+	q[1] = q0 ^ q2
+	q[2] = q1 ^ q2
+	q[3] = q2 ^ q4 ^ q5 ^ q7
+	q[4] = ^(q0 ^ q1 ^ q4 ^ q5)
+	q[5] = q2 ^ q3 ^ q4 ^ q6 ^ q7
+	q[6] = q3 ^ q5
+	q[7] = q0 ^ q1 ^ q4 ^ q5 ^ q6 ^ q7
+
+	return q
+}
@@ -295,18 +295,22 @@ func Keysched(compSkey []uint32, key []byte) int {
 			k++
 		}
 	}
-	for i := 0; i < nkf; i += 4 {
-		Ortho(skey[i<<1:])
-	}
-	for i, j := 0, 0; i < nkf; i, j = i+1, j+2 {
-		compSkey[i] = (skey[j+0] & 0x55555555) | (skey[j+1] & 0xAAAAAAAA)
-	}
+	compressSkey(compSkey, skey[:nkf<<1])
 
 	memwipeU32(skey[:])
 
 	return numRounds
 }
 
+func compressSkey(compSkey []uint32, skey []uint32) {
+	for i := 0; i < len(skey); i += 8 {
+		Ortho(skey[i:])
+	}
+	for i, j := 0, 0; j < len(skey); i, j = i+1, j+2 {
+		compSkey[i] = (skey[j+0] & 0x55555555) | (skey[j+1] & 0xAAAAAAAA)
+	}
+}
+
 func SkeyExpand(skey []uint32, numRounds int, compSkey []uint32) {
 	n := (numRounds + 1) << 2
 	for u, v := 0, 0; u < n; u, v = u+1, v+2 {
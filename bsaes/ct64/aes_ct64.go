@@ -334,8 +334,18 @@ func Keysched(compSkey []uint64, key []byte) int {
 		}
 	}
 
+	compressSkey(compSkey, skey[:nkf])
+
+	for i := range skey {
+		skey[i] = 0
+	}
+
+	return numRounds
+}
+
+func compressSkey(compSkey []uint64, skey []uint32) {
 	var q [8]uint64
-	for i, j := 0, 0; i < nkf; i, j = i+4, j+2 {
+	for i, j := 0, 0; i < len(skey); i, j = i+4, j+2 {
 		InterleaveIn(&q[0], &q[4], skey[i:])
 		q[1] = q[0]
 		q[2] = q[0]
@@ -351,13 +361,7 @@ func Keysched(compSkey []uint64, key []byte) int {
 			(q[5] & 0x2222222222222222) | (q[6] & 0x4444444444444444) |
 			(q[7] & 0x8888888888888888)
 	}
-
-	for i := range skey {
-		skey[i] = 0
-	}
 	memwipeU64(q[:])
-
-	return numRounds
 }
 
 func SkeyExpand(skey []uint64, numRounds int, compSkey []uint64) {
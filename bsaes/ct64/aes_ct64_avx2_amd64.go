@@ -0,0 +1,158 @@
+// Copyright (c) 2016 Thomas Pornin <pornin@bolet.org>
+// Copyright (c) 2017 Yawning Angel <yawning at schwanenlied dot me>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS
+// BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN
+// ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+//go:build amd64 && !gccgo && !appengine && !noasm
+// +build amd64,!gccgo,!appengine,!noasm
+
+package ct64
+
+import "crypto/cipher"
+
+//go:noescape
+func cpuidAMD64(cpuidParams *uint32)
+
+//go:noescape
+func xgetbv0AMD64(xcrVec *uint32)
+
+//go:noescape
+func encryptAVX2(numRounds int, skey *uint64, q *[32]uint64)
+
+//go:noescape
+func decryptAVX2(numRounds int, skey *uint64, q *[32]uint64)
+
+// SupportsAVX2 returns true iff the AVX2 backend is usable on the current
+// system.
+func SupportsAVX2() bool {
+	const (
+		osXsaveBit = 1 << 27
+		avx2Bit    = 1 << 5
+	)
+
+	// Check to see if CPUID actually supports the leaf that indicates AVX2.
+	// CPUID.(EAX=0H, ECX=0H) >= 7
+	regs := [4]uint32{0x00}
+	cpuidAMD64(&regs[0])
+	if regs[0] < 7 {
+		return false
+	}
+
+	// Check to see if the OS knows how to save/restore XMM/YMM state.
+	// CPUID.(EAX=01H, ECX=0H):ECX.OSXSAVE[bit 27]==1
+	regs = [4]uint32{0x01}
+	cpuidAMD64(&regs[0])
+	if regs[2]&osXsaveBit == 0 {
+		return false
+	}
+	xcrRegs := [2]uint32{}
+	xgetbv0AMD64(&xcrRegs[0])
+	if xcrRegs[0]&6 != 6 {
+		return false
+	}
+
+	// Check for AVX2 support.
+	// CPUID.(EAX=07H, ECX=0H):EBX.AVX2[bit 5]==1
+	regs = [4]uint32{0x07}
+	cpuidAMD64(&regs[0])
+	return regs[1]&avx2Bit != 0
+}
+
+// blockAVX2 is the ct64 algorithm with each uint64 widened to a YMM
+// register, so that the bulk interface processes 16 blocks at a time.  The
+// single block calls use the portable code.
+type blockAVX2 struct {
+	block
+}
+
+func (b *blockAVX2) Stride() int {
+	return 16
+}
+
+func (b *blockAVX2) BulkEncrypt(dst, src []byte) {
+	var q [32]uint64
+
+	if b.wasReset {
+		panic("bsaes/ct64: BulkEncrypt() called after Reset()")
+	}
+
+	loadAVX2(&q, src)
+	encryptAVX2(b.numRounds, &b.skExp[0], &q)
+	storeAVX2(dst, &q)
+}
+
+func (b *blockAVX2) BulkDecrypt(dst, src []byte) {
+	var q [32]uint64
+
+	if b.wasReset {
+		panic("bsaes/ct64: BulkDecrypt() called after Reset()")
+	}
+
+	loadAVX2(&q, src)
+	decryptAVX2(b.numRounds, &b.skExp[0], &q)
+	storeAVX2(dst, &q)
+}
+
+// loadAVX2 bitslices 16 blocks, with blocks 4*j to 4*j+3 in lane j of each
+// of the 8 registers.
+func loadAVX2(q *[32]uint64, src []byte) {
+	var t [8]uint64
+
+	_ = src[255]
+	for j := 0; j < 4; j++ {
+		s := src[j*64:]
+		Load16xU32(&t, s[0:], s[16:], s[32:], s[48:])
+		for i, v := range t {
+			q[i*4+j] = v
+		}
+	}
+	memwipeU64(t[:])
+}
+
+func storeAVX2(dst []byte, q *[32]uint64) {
+	var t [8]uint64
+
+	_ = dst[255]
+	for j := 0; j < 4; j++ {
+		for i := range t {
+			t[i] = q[i*4+j]
+		}
+		d := dst[j*64:]
+		Store16xU32(d[0:], d[16:], d[32:], d[48:], &t)
+	}
+	memwipeU64(t[:])
+	memwipeU64(q[:])
+}
+
+// NewCipherAVX2 creates and returns a new cipher.Block, backed by the AVX2
+// backend.  It must only be called if SupportsAVX2 returns true.
+func NewCipherAVX2(key []byte) cipher.Block {
+	var skey [30]uint64
+	defer memwipeU64(skey[:])
+
+	b := new(blockAVX2)
+	b.numRounds = Keysched(skey[:], key)
+	SkeyExpand(b.skExp[:], b.numRounds, skey[:])
+
+	b.BlockModesImpl.Init(b)
+
+	return b
+}
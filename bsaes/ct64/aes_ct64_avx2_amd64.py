@@ -0,0 +1,476 @@
+#!/usr/bin/env python3
+#
+# To the extent possible under law, Yawning Angel has waived all copyright
+# and related or neighboring rights to bsaes, using the Creative
+# Commons "CC0" public domain dedication. See LICENSE or
+# <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+#
+# Generates the AVX2 backend, which is the ct64 algorithm with every uint64
+# replaced by a YMM register holding 4 independent uint64 lanes, for 16
+# blocks at a time.  The S-box circuit is parsed from aes_ct64.go so that the
+# two can not diverge, and registers are allocated with Belady's algorithm,
+# spilling to the stack frame.
+#
+# python3 aes_ct64_avx2_amd64.py > aes_ct64_avx2_amd64.s
+#
+
+import re
+import sys
+
+NUM_REGS = 16
+LANE_BYTES = 32
+
+
+class Block:
+    """A straight line sequence of SSA operations."""
+
+    def __init__(self):
+        self.ops = []
+        self.n = 0
+
+    def _emit(self, op, *args):
+        self.n += 1
+        dst = 'v%d' % self.n
+        self.ops.append((dst, op, args))
+        return dst
+
+    def load(self, i):
+        return self._emit('load', i)
+
+    def store(self, i, v):
+        self.ops.append((None, 'store', (i, v)))
+
+    def key(self, i):
+        return self._emit('key', i)
+
+    def xor(self, a, b):
+        return self._emit('xor', a, b)
+
+    def andm(self, a, mask):
+        return self._emit('andm', a, mask)
+
+    def and_(self, a, b):
+        return self._emit('and', a, b)
+
+    def or_(self, a, b):
+        return self._emit('or', a, b)
+
+    def not_(self, a):
+        return self._emit('not', a)
+
+    def shl(self, a, n):
+        return self._emit('shl', a, n)
+
+    def shr(self, a, n):
+        return self._emit('shr', a, n)
+
+    def rotr16(self, a):
+        return self._emit('rotr16', a)
+
+    def rotr32(self, a):
+        return self._emit('rotr32', a)
+
+    def xor_all(self, vals):
+        acc = vals[0]
+        for v in vals[1:]:
+            acc = self.xor(acc, v)
+        return acc
+
+
+def parse_sbox(path):
+    """Returns the Boyar-Peralta circuit from ct64.Sbox as a list of
+    (dst, op, a, b) tuples, where op is '^', '&', or '~^' (xnor)."""
+    src = open(path).read()
+    body = src[src.index('func Sbox('):]
+    body = body[:body.index('\n}\n')]
+    gates = []
+    for line in body.split('\n'):
+        line = line.strip()
+        m = re.match(r'^(\w+) = (\w+) ([\^&]) (\w+)$', line)
+        if m:
+            gates.append((m.group(1), m.group(3), m.group(2), m.group(4)))
+            continue
+        m = re.match(r'^(\w+) = (\w+) \^ \(\^(\w+)\)$', line)
+        if m:
+            gates.append((m.group(1), '~^', m.group(2), m.group(3)))
+    return gates
+
+
+SBOX = parse_sbox('aes_ct64.go')
+
+
+def sbox(b, q):
+    env = {'x%d' % i: q[7 - i] for i in range(8)}
+    for dst, op, x, y in SBOX:
+        if op == '^':
+            env[dst] = b.xor(env[x], env[y])
+        elif op == '&':
+            env[dst] = b.and_(env[x], env[y])
+        else:
+            env[dst] = b.not_(b.xor(env[x], env[y]))
+    return [env['s%d' % (7 - i)] for i in range(8)]
+
+
+def inv_sbox_affine(b, q):
+    q = [b.not_(v) if i in (0, 1, 5, 6) else v for i, v in enumerate(q)]
+    r = [None] * 8
+    r[7] = b.xor_all([q[1], q[4], q[6]])
+    r[6] = b.xor_all([q[0], q[3], q[5]])
+    r[5] = b.xor_all([q[7], q[2], q[4]])
+    r[4] = b.xor_all([q[6], q[1], q[3]])
+    r[3] = b.xor_all([q[5], q[0], q[2]])
+    r[2] = b.xor_all([q[4], q[7], q[1]])
+    r[1] = b.xor_all([q[3], q[6], q[0]])
+    r[0] = b.xor_all([q[2], q[5], q[7]])
+    return r
+
+
+def inv_sbox(b, q):
+    return inv_sbox_affine(b, sbox(b, inv_sbox_affine(b, q)))
+
+
+# (mask, shift) terms, positive shifts are to the left.
+SHIFT_ROWS = [
+    ('0x000000000000FFFF', 0),
+    ('0x00000000FFF00000', -4),
+    ('0x00000000000F0000', 12),
+    ('0x0000FF0000000000', -8),
+    ('0x000000FF00000000', 8),
+    ('0xF000000000000000', -12),
+    ('0x0FFF000000000000', 4),
+]
+
+INV_SHIFT_ROWS = [
+    ('0x000000000000FFFF', 0),
+    ('0x000000000FFF0000', 4),
+    ('0x00000000F0000000', -12),
+    ('0x000000FF00000000', 8),
+    ('0x0000FF0000000000', -8),
+    ('0x000F000000000000', 12),
+    ('0xFFF0000000000000', -4),
+]
+
+
+def shift_rows(b, q, terms):
+    out = []
+    for x in q:
+        acc = None
+        for mask, shift in terms:
+            t = b.andm(x, mask)
+            if shift > 0:
+                t = b.shl(t, shift)
+            elif shift < 0:
+                t = b.shr(t, -shift)
+            acc = t if acc is None else b.or_(acc, t)
+        out.append(acc)
+    return out
+
+
+def parse_mix_columns(path, name):
+    """Returns the q[i] expressions of ct64.MixColumns or InvMixColumns as
+    lists of terms, where each term is a name or a ('rotr32', [names])."""
+    src = open(path).read()
+    body = src[src.index('func %s(' % name):]
+    body = body[:body.index('\n}\n')]
+    exprs = [None] * 8
+    for m in re.finditer(r'q\[(\d)\] = (.*)', body):
+        terms = []
+        expr = m.group(2)
+        rot = re.search(r'rotr32\(([^)]*)\)', expr)
+        if rot:
+            terms.append(('rotr32', [t.strip() for t in rot.group(1).split('^')]))
+            expr = expr[:rot.start()] + expr[rot.end():]
+        terms += [t.strip() for t in expr.split('^') if t.strip()]
+        exprs[int(m.group(1))] = terms
+    return exprs
+
+
+MIX_COLUMNS = parse_mix_columns('aes_ct64_enc.go', 'MixColumns')
+INV_MIX_COLUMNS = parse_mix_columns('aes_ct64_dec.go', 'InvMixColumns')
+
+
+def mix_columns(b, q, exprs):
+    env = {}
+    for i in range(8):
+        env['q%d' % i] = q[i]
+        env['r%d' % i] = b.rotr16(q[i])
+    out = []
+    for terms in exprs:
+        vals = []
+        for t in terms:
+            if isinstance(t, tuple):
+                vals.append(b.rotr32(b.xor_all([env[n] for n in t[1]])))
+            else:
+                vals.append(env[t])
+        out.append(b.xor_all(vals))
+    return out
+
+
+def add_round_key(b, q):
+    return [b.xor(q[i], b.key(i)) for i in range(8)]
+
+
+class Allocator:
+    def __init__(self, block, out):
+        self.block = block
+        self.out = out
+        self.uses = {}
+        for idx, (_, op, args) in enumerate(block.ops):
+            for a in self._reg_args(op, args):
+                self.uses.setdefault(a, []).append(idx)
+        self.reg_of = {}
+        self.regs = [None] * NUM_REGS
+        self.slot_of = {}
+        self.free_slots = []
+        self.num_slots = 0
+
+    @staticmethod
+    def _reg_args(op, args):
+        if op in ('load', 'key'):
+            return []
+        if op == 'store':
+            return [args[1]]
+        if op in ('andm', 'shl', 'shr'):
+            return [args[0]]
+        return list(args)
+
+    def _next_use(self, v, idx):
+        for u in self.uses.get(v, []):
+            if u > idx:
+                return u
+        return None
+
+    def _slot(self, v):
+        if v not in self.slot_of:
+            if self.free_slots:
+                s = self.free_slots.pop()
+            else:
+                s = self.num_slots
+                self.num_slots += 1
+            self.slot_of[v] = s
+        return self.slot_of[v]
+
+    def _get_reg(self, idx, pinned):
+        for r in range(NUM_REGS):
+            if self.regs[r] is None:
+                return r
+        victim, victim_next = None, -1
+        for r in range(NUM_REGS):
+            v = self.regs[r]
+            if v in pinned:
+                continue
+            n = self._next_use(v, idx)
+            if n is None:
+                n = 1 << 30
+            if n > victim_next:
+                victim, victim_next = r, n
+        v = self.regs[victim]
+        if v not in self.slot_of:
+            s = self._slot(v)
+            self.out.append('\tVMOVDQU Y%d, %d(SP)' % (victim, s * LANE_BYTES))
+        del self.reg_of[v]
+        self.regs[victim] = None
+        return victim
+
+    def _free(self, v):
+        r = self.reg_of.pop(v, None)
+        if r is not None:
+            self.regs[r] = None
+        s = self.slot_of.pop(v, None)
+        if s is not None:
+            self.free_slots.append(s)
+
+    def run(self):
+        out = self.out
+        for idx, (dst, op, args) in enumerate(self.block.ops):
+            rargs = self._reg_args(op, args)
+            pinned = set(rargs)
+            for a in rargs:
+                if a not in self.reg_of:
+                    r = self._get_reg(idx, pinned)
+                    out.append('\tVMOVDQU %d(SP), Y%d' % (self.slot_of[a] * LANE_BYTES, r))
+                    self.reg_of[a] = r
+                    self.regs[r] = a
+            src = [self.reg_of[a] for a in rargs]
+
+            dying = [a for a in dict.fromkeys(rargs) if self._next_use(a, idx) is None]
+            if op == 'store':
+                out.append('\tVMOVDQU Y%d, %d(DI)' % (src[0], args[0] * LANE_BYTES))
+            else:
+                if dying:
+                    d = self.reg_of[dying[0]]
+                else:
+                    d = self._get_reg(idx, pinned)
+                if op == 'load':
+                    out.append('\tVMOVDQU %d(DI), Y%d' % (args[0] * LANE_BYTES, d))
+                elif op == 'key':
+                    out.append('\tVPBROADCASTQ %d(SI), Y%d' % (args[0] * 8, d))
+                elif op in ('xor', 'and', 'or'):
+                    ins = {'xor': 'VPXOR', 'and': 'VPAND', 'or': 'VPOR'}[op]
+                    out.append('\t%s Y%d, Y%d, Y%d' % (ins, src[1], src[0], d))
+                elif op == 'andm':
+                    out.append('\tVPAND %s(SB), Y%d, Y%d' % (mask_sym(args[1]), src[0], d))
+                elif op == 'not':
+                    out.append('\tVPXOR ones<>(SB), Y%d, Y%d' % (src[0], d))
+                elif op == 'shl':
+                    out.append('\tVPSLLQ $%d, Y%d, Y%d' % (args[1], src[0], d))
+                elif op == 'shr':
+                    out.append('\tVPSRLQ $%d, Y%d, Y%d' % (args[1], src[0], d))
+                elif op == 'rotr16':
+                    out.append('\tVPSHUFB rotr16<>(SB), Y%d, Y%d' % (src[0], d))
+                elif op == 'rotr32':
+                    out.append('\tVPSHUFD $0xb1, Y%d, Y%d' % (src[0], d))
+                else:
+                    raise ValueError(op)
+            for a in dying:
+                self._free(a)
+            if dst is not None:
+                self.reg_of[dst] = d
+                self.regs[d] = dst
+        return self.num_slots
+
+
+MASKS = {}
+
+
+def mask_sym(mask):
+    if mask not in MASKS:
+        MASKS[mask] = 'mask%d<>' % len(MASKS)
+    return MASKS[mask]
+
+
+def gen_block(out, fn):
+    b = Block()
+    q = [b.load(i) for i in range(8)]
+    q = fn(b, q)
+    for i in range(8):
+        b.store(i, q[i])
+    return Allocator(b, out).run()
+
+
+def enc_round(b, q):
+    q = sbox(b, q)
+    q = shift_rows(b, q, SHIFT_ROWS)
+    q = mix_columns(b, q, MIX_COLUMNS)
+    return add_round_key(b, q)
+
+
+def enc_final(b, q):
+    q = sbox(b, q)
+    q = shift_rows(b, q, SHIFT_ROWS)
+    return add_round_key(b, q)
+
+
+def dec_round(b, q):
+    q = shift_rows(b, q, INV_SHIFT_ROWS)
+    q = inv_sbox(b, q)
+    q = add_round_key(b, q)
+    return mix_columns(b, q, INV_MIX_COLUMNS)
+
+
+def dec_final(b, q):
+    q = shift_rows(b, q, INV_SHIFT_ROWS)
+    q = inv_sbox(b, q)
+    return add_round_key(b, q)
+
+
+def gen_function(name, first, body, last, decrypt):
+    out = []
+    slots = 0
+    out.append('\tMOVQ numRounds+0(FP), CX')
+    out.append('\tMOVQ skey+8(FP), SI')
+    out.append('\tMOVQ q+16(FP), DI')
+    if decrypt:
+        out.append('\tMOVQ CX, AX')
+        out.append('\tSHLQ $6, AX')
+        out.append('\tADDQ AX, SI')
+    slots = max(slots, gen_block(out, first))
+    out.append('\tDECQ CX')
+    out.append('')
+    out.append('%sLoop:' % name)
+    out.append('\t%s $64, SI' % ('SUBQ' if decrypt else 'ADDQ'))
+    slots = max(slots, gen_block(out, body))
+    out.append('\tDECQ CX')
+    out.append('\tJNZ %sLoop' % name)
+    out.append('')
+    out.append('\t%s $64, SI' % ('SUBQ' if decrypt else 'ADDQ'))
+    slots = max(slots, gen_block(out, last))
+    out.append('')
+
+    # Clear the registers and the spill slots.
+    out.append('\tVPXOR Y0, Y0, Y0')
+    for s in range(slots):
+        out.append('\tVMOVDQU Y0, %d(SP)' % (s * LANE_BYTES))
+    out.append('\tVZEROALL')
+    out.append('\tRET')
+
+    frame = slots * LANE_BYTES
+    hdr = [
+        '',
+        '// func %s(numRounds int, skey *uint64, q *[32]uint64)' % name,
+        'TEXT ·%s(SB), 0, $%d-24' % (name, frame),
+    ]
+    return hdr + out
+
+
+def main():
+    enc = gen_function('encryptAVX2', add_round_key, enc_round, enc_final, False)
+    dec = gen_function('decryptAVX2', add_round_key, dec_round, dec_final, True)
+
+    p = print
+    p('// Generated by aes_ct64_avx2_amd64.py, DO NOT EDIT.')
+    p('')
+    p('//go:build amd64 && !gccgo && !appengine && !noasm')
+    p('// +build amd64,!gccgo,!appengine,!noasm')
+    p('')
+    p('#include "textflag.h"')
+    p('')
+    p('// func cpuidAMD64(cpuidParams *uint32)')
+    p('TEXT ·cpuidAMD64(SB), NOSPLIT, $0-8')
+    p('\tMOVQ cpuidParams+0(FP), R15')
+    p('\tMOVL 0(R15), AX')
+    p('\tMOVL 8(R15), CX')
+    p('\tCPUID')
+    p('\tMOVL AX, 0(R15)')
+    p('\tMOVL BX, 4(R15)')
+    p('\tMOVL CX, 8(R15)')
+    p('\tMOVL DX, 12(R15)')
+    p('\tRET')
+    p('')
+    p('// func xgetbv0AMD64(xcrVec *uint32)')
+    p('TEXT ·xgetbv0AMD64(SB), NOSPLIT, $0-8')
+    p('\tMOVQ xcrVec+0(FP), BX')
+    p('\tXORL CX, CX')
+    p('\tXGETBV')
+    p('\tMOVL AX, 0(BX)')
+    p('\tMOVL DX, 4(BX)')
+    p('\tRET')
+    for line in enc + dec:
+        p(line)
+
+    p('')
+    p('DATA ones<>+0(SB)/8, $0xFFFFFFFFFFFFFFFF')
+    p('DATA ones<>+8(SB)/8, $0xFFFFFFFFFFFFFFFF')
+    p('DATA ones<>+16(SB)/8, $0xFFFFFFFFFFFFFFFF')
+    p('DATA ones<>+24(SB)/8, $0xFFFFFFFFFFFFFFFF')
+    p('GLOBL ones<>(SB), RODATA|NOPTR, $32')
+    p('')
+    # Rotate each 64 bit lane right by 16 bits.
+    rot = [(j + 2) % 8 + 8 * (i // 8) for i in range(16) for j in [i % 8]]
+    for half in range(2):
+        for w in range(2):
+            v = 0
+            for k in range(8):
+                v |= rot[w * 8 + k] << (8 * k)
+            p('DATA rotr16<>+%d(SB)/8, $0x%016X' % (half * 16 + w * 8, v))
+    p('GLOBL rotr16<>(SB), RODATA|NOPTR, $32')
+    for mask, sym in sorted(MASKS.items(), key=lambda kv: int(kv[1][4:-2])):
+        p('')
+        for k in range(4):
+            p('DATA %s+%d(SB)/8, $%s' % (sym, k * 8, mask))
+        p('GLOBL %s(SB), RODATA|NOPTR, $32' % sym)
+
+
+if __name__ == '__main__':
+    main()
@@ -0,0 +1,62 @@
+// aes_ct64_avx2_test.go - AVX2 backend tests.
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to aes_ct64_avx2_test.go, using the
+// Creative Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package ct64
+
+import (
+	"bytes"
+	"testing"
+)
+
+type bulkBlock interface {
+	Stride() int
+	BulkEncrypt(dst, src []byte)
+	BulkDecrypt(dst, src []byte)
+}
+
+func TestAVX2(t *testing.T) {
+	if !SupportsAVX2() {
+		t.Skip("AVX2 not supported")
+	}
+
+	for _, keyLen := range []int{16, 24, 32} {
+		for i := 0; i < 64; i++ {
+			key := mustRandBytes(t, keyLen)
+			src := mustRandBytes(t, 16*16)
+
+			ref := NewCipher(key).(bulkBlock)
+			avx2 := NewCipherAVX2(key).(bulkBlock)
+			if s := avx2.Stride(); s != 16 {
+				t.Fatalf("Stride() = %d", s)
+			}
+
+			expected := make([]byte, len(src))
+			for off := 0; off < len(src); off += 64 {
+				ref.BulkEncrypt(expected[off:], src[off:])
+			}
+			ct := make([]byte, len(src))
+			avx2.BulkEncrypt(ct, src)
+			if !bytes.Equal(ct, expected) {
+				t.Fatalf("BulkEncrypt(%d): mismatch", keyLen)
+			}
+
+			for off := 0; off < len(src); off += 64 {
+				ref.BulkDecrypt(expected[off:], src[off:])
+			}
+			pt := make([]byte, len(src))
+			avx2.BulkDecrypt(pt, src)
+			if !bytes.Equal(pt, expected) {
+				t.Fatalf("BulkDecrypt(%d): mismatch", keyLen)
+			}
+
+			avx2.BulkDecrypt(pt, ct)
+			if !bytes.Equal(pt, src) {
+				t.Fatalf("BulkDecrypt(BulkEncrypt(%d)): mismatch", keyLen)
+			}
+		}
+	}
+}
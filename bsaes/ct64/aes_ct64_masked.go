@@ -0,0 +1,570 @@
+// Copyright (c) 2016 Thomas Pornin <pornin@bolet.org>
+// Copyright (c) 2017 Yawning Angel <yawning at schwanenlied dot me>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS
+// BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN
+// ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package ct64
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/mad-day/Yawning-crypto/bsaes/internal/modes"
+)
+
+const maskRNGBufSize = 4096
+
+// maskRNG supplies the fresh randomness consumed by the masked
+// implementation, buffering reads from the caller provided source.
+type maskRNG struct {
+	r   io.Reader
+	buf [maskRNGBufSize]byte
+	off int
+}
+
+func (m *maskRNG) next() uint64 {
+	if m.off == len(m.buf) {
+		if _, err := io.ReadFull(m.r, m.buf[:]); err != nil {
+			panic("bsaes/ct64: failed to read mask entropy: " + err.Error())
+		}
+		m.off = 0
+	}
+	v := binary.LittleEndian.Uint64(m.buf[m.off:])
+	m.off += 8
+	return v
+}
+
+// and is Trichina's masked AND gate.  The fresh random value is folded in
+// first, so that none of the partial products are ever combined into a value
+// that depends on the unmasked operands.
+func (m *maskRNG) and(a, b shares) shares {
+	r := m.next()
+	c := r ^ (a[0] & b[0])
+	c ^= a[0] & b[1]
+	c ^= a[1] & b[0]
+	c ^= a[1] & b[1]
+	return shares{r, c}
+}
+
+func (m *maskRNG) wipe() {
+	for i := range m.buf {
+		m.buf[i] = 0
+	}
+	m.off = len(m.buf)
+}
+
+// shares is a first-order Boolean masked value, where the unmasked value is
+// shares[0] ^ shares[1].
+type shares [2]uint64
+
+func mxor(a, b shares) shares {
+	return shares{a[0] ^ b[0], a[1] ^ b[1]}
+}
+
+func mnot(a shares) shares {
+	return shares{^a[0], a[1]}
+}
+
+func maskedSbox(rng *maskRNG, q *[2][8]uint64) {
+	// This is Sbox(), with every intermediate value split into two shares.
+	// XORs are applied share-wise, NOTs are applied to the first share only,
+	// and each AND consumes fresh randomness.
+
+	var x0, x1, x2, x3, x4, x5, x6, x7 shares
+	var y1, y2, y3, y4, y5, y6, y7, y8, y9 shares
+	var y10, y11, y12, y13, y14, y15, y16, y17, y18, y19 shares
+	var y20, y21 shares
+	var z0, z1, z2, z3, z4, z5, z6, z7, z8, z9 shares
+	var z10, z11, z12, z13, z14, z15, z16, z17 shares
+	var t0, t1, t2, t3, t4, t5, t6, t7, t8, t9 shares
+	var t10, t11, t12, t13, t14, t15, t16, t17, t18, t19 shares
+	var t20, t21, t22, t23, t24, t25, t26, t27, t28, t29 shares
+	var t30, t31, t32, t33, t34, t35, t36, t37, t38, t39 shares
+	var t40, t41, t42, t43, t44, t45, t46, t47, t48, t49 shares
+	var t50, t51, t52, t53, t54, t55, t56, t57, t58, t59 shares
+	var t60, t61, t62, t63, t64, t65, t66, t67 shares
+	var s0, s1, s2, s3, s4, s5, s6, s7 shares
+
+	x0 = shares{q[0][7], q[1][7]}
+	x1 = shares{q[0][6], q[1][6]}
+	x2 = shares{q[0][5], q[1][5]}
+	x3 = shares{q[0][4], q[1][4]}
+	x4 = shares{q[0][3], q[1][3]}
+	x5 = shares{q[0][2], q[1][2]}
+	x6 = shares{q[0][1], q[1][1]}
+	x7 = shares{q[0][0], q[1][0]}
+
+	//
+	// Top linear transformation.
+	//
+	y14 = mxor(x3, x5)
+	y13 = mxor(x0, x6)
+	y9 = mxor(x0, x3)
+	y8 = mxor(x0, x5)
+	t0 = mxor(x1, x2)
+	y1 = mxor(t0, x7)
+	y4 = mxor(y1, x3)
+	y12 = mxor(y13, y14)
+	y2 = mxor(y1, x0)
+	y5 = mxor(y1, x6)
+	y3 = mxor(y5, y8)
+	t1 = mxor(x4, y12)
+	y15 = mxor(t1, x5)
+	y20 = mxor(t1, x1)
+	y6 = mxor(y15, x7)
+	y10 = mxor(y15, t0)
+	y11 = mxor(y20, y9)
+	y7 = mxor(x7, y11)
+	y17 = mxor(y10, y11)
+	y19 = mxor(y10, y8)
+	y16 = mxor(t0, y11)
+	y21 = mxor(y13, y16)
+	y18 = mxor(x0, y16)
+
+	//
+	// Non-linear section.
+	//
+	t2 = rng.and(y12, y15)
+	t3 = rng.and(y3, y6)
+	t4 = mxor(t3, t2)
+	t5 = rng.and(y4, x7)
+	t6 = mxor(t5, t2)
+	t7 = rng.and(y13, y16)
+	t8 = rng.and(y5, y1)
+	t9 = mxor(t8, t7)
+	t10 = rng.and(y2, y7)
+	t11 = mxor(t10, t7)
+	t12 = rng.and(y9, y11)
+	t13 = rng.and(y14, y17)
+	t14 = mxor(t13, t12)
+	t15 = rng.and(y8, y10)
+	t16 = mxor(t15, t12)
+	t17 = mxor(t4, t14)
+	t18 = mxor(t6, t16)
+	t19 = mxor(t9, t14)
+	t20 = mxor(t11, t16)
+	t21 = mxor(t17, y20)
+	t22 = mxor(t18, y19)
+	t23 = mxor(t19, y21)
+	t24 = mxor(t20, y18)
+
+	t25 = mxor(t21, t22)
+	t26 = rng.and(t21, t23)
+	t27 = mxor(t24, t26)
+	t28 = rng.and(t25, t27)
+	t29 = mxor(t28, t22)
+	t30 = mxor(t23, t24)
+	t31 = mxor(t22, t26)
+	t32 = rng.and(t31, t30)
+	t33 = mxor(t32, t24)
+	t34 = mxor(t23, t33)
+	t35 = mxor(t27, t33)
+	t36 = rng.and(t24, t35)
+	t37 = mxor(t36, t34)
+	t38 = mxor(t27, t36)
+	t39 = rng.and(t29, t38)
+	t40 = mxor(t25, t39)
+
+	t41 = mxor(t40, t37)
+	t42 = mxor(t29, t33)
+	t43 = mxor(t29, t40)
+	t44 = mxor(t33, t37)
+	t45 = mxor(t42, t41)
+	z0 = rng.and(t44, y15)
+	z1 = rng.and(t37, y6)
+	z2 = rng.and(t33, x7)
+	z3 = rng.and(t43, y16)
+	z4 = rng.and(t40, y1)
+	z5 = rng.and(t29, y7)
+	z6 = rng.and(t42, y11)
+	z7 = rng.and(t45, y17)
+	z8 = rng.and(t41, y10)
+	z9 = rng.and(t44, y12)
+	z10 = rng.and(t37, y3)
+	z11 = rng.and(t33, y4)
+	z12 = rng.and(t43, y13)
+	z13 = rng.and(t40, y5)
+	z14 = rng.and(t29, y2)
+	z15 = rng.and(t42, y9)
+	z16 = rng.and(t45, y14)
+	z17 = rng.and(t41, y8)
+
+	//
+	// Bottom linear transformation.
+	//
+	t46 = mxor(z15, z16)
+	t47 = mxor(z10, z11)
+	t48 = mxor(z5, z13)
+	t49 = mxor(z9, z10)
+	t50 = mxor(z2, z12)
+	t51 = mxor(z2, z5)
+	t52 = mxor(z7, z8)
+	t53 = mxor(z0, z3)
+	t54 = mxor(z6, z7)
+	t55 = mxor(z16, z17)
+	t56 = mxor(z12, t48)
+	t57 = mxor(t50, t53)
+	t58 = mxor(z4, t46)
+	t59 = mxor(z3, t54)
+	t60 = mxor(t46, t57)
+	t61 = mxor(z14, t57)
+	t62 = mxor(t52, t58)
+	t63 = mxor(t49, t58)
+	t64 = mxor(z4, t59)
+	t65 = mxor(t61, t62)
+	t66 = mxor(z1, t63)
+	s0 = mxor(t59, t63)
+	s6 = mxor(t56, mnot(t62))
+	s7 = mxor(t48, mnot(t60))
+	t67 = mxor(t64, t65)
+	s3 = mxor(t53, t66)
+	s4 = mxor(t51, t66)
+	s5 = mxor(t47, t65)
+	s1 = mxor(t64, mnot(s3))
+	s2 = mxor(t55, mnot(t67))
+
+	q[0][7], q[1][7] = s0[0], s0[1]
+	q[0][6], q[1][6] = s1[0], s1[1]
+	q[0][5], q[1][5] = s2[0], s2[1]
+	q[0][4], q[1][4] = s3[0], s3[1]
+	q[0][3], q[1][3] = s4[0], s4[1]
+	q[0][2], q[1][2] = s5[0], s5[1]
+	q[0][1], q[1][1] = s6[0], s6[1]
+	q[0][0], q[1][0] = s7[0], s7[1]
+}
+
+func maskedInvSboxAffine(q *[8]uint64, c uint64) {
+	// See InvSbox().  c is all ones for the first share, and zero for the
+	// second, so that the complement is only applied once.
+	var q0, q1, q2, q3, q4, q5, q6, q7 uint64
+
+	q0 = q[0] ^ c
+	q1 = q[1] ^ c
+	q2 = q[2]
+	q3 = q[3]
+	q4 = q[4]
+	q5 = q[5] ^ c
+	q6 = q[6] ^ c
+	q7 = q[7]
+	q[7] = q1 ^ q4 ^ q6
+	q[6] = q0 ^ q3 ^ q5
+	q[5] = q7 ^ q2 ^ q4
+	q[4] = q6 ^ q1 ^ q3
+	q[3] = q5 ^ q0 ^ q2
+	q[2] = q4 ^ q7 ^ q1
+	q[1] = q3 ^ q6 ^ q0
+	q[0] = q2 ^ q5 ^ q7
+}
+
+func maskedInvSbox(rng *maskRNG, q *[2][8]uint64) {
+	maskedInvSboxAffine(&q[0], ^uint64(0))
+	maskedInvSboxAffine(&q[1], 0)
+	maskedSbox(rng, q)
+	maskedInvSboxAffine(&q[0], ^uint64(0))
+	maskedInvSboxAffine(&q[1], 0)
+}
+
+func maskedSubWord(rng *maskRNG, x0, x1 uint32) (uint32, uint32) {
+	var q [2][8]uint64
+
+	q[0][0], q[1][0] = uint64(x0), uint64(x1)
+	Ortho(q[0][:])
+	Ortho(q[1][:])
+	maskedSbox(rng, &q)
+	Ortho(q[0][:])
+	Ortho(q[1][:])
+	x0, x1 = uint32(q[0][0]), uint32(q[1][0])
+	memwipeU64(q[0][:])
+	memwipeU64(q[1][:])
+	return x0, x1
+}
+
+// maskedKeysched is Keysched(), with the key split into two shares before
+// the expansion, so that neither the raw key nor any of the round keys are
+// ever present in memory unmasked.
+func maskedKeysched(rng *maskRNG, compSkey *[2][30]uint64, key []byte) int {
+	numRounds := 0
+	keyLen := len(key)
+	switch keyLen {
+	case 16:
+		numRounds = 10
+	case 24:
+		numRounds = 12
+	case 32:
+		numRounds = 14
+	default:
+		panic("bsaes/ct64: maskedKeysched: invalid key length")
+	}
+
+	var skey [2][60]uint32
+	nk := keyLen >> 2
+	nkf := (numRounds + 1) << 2
+	for i := 0; i < nk; i++ {
+		m := uint32(rng.next())
+		skey[0][i] = m
+		skey[1][i] = m ^ binary.LittleEndian.Uint32(key[i<<2:])
+	}
+	tmp0, tmp1 := skey[0][nk-1], skey[1][nk-1]
+	for i, j, k := nk, 0, 0; i < nkf; i++ {
+		if j == 0 {
+			tmp0 = (tmp0 << 24) | (tmp0 >> 8)
+			tmp1 = (tmp1 << 24) | (tmp1 >> 8)
+			tmp0, tmp1 = maskedSubWord(rng, tmp0, tmp1)
+			tmp1 ^= uint32(rcon[k])
+		} else if nk > 6 && j == 4 {
+			tmp0, tmp1 = maskedSubWord(rng, tmp0, tmp1)
+		}
+		tmp0 ^= skey[0][i-nk]
+		tmp1 ^= skey[1][i-nk]
+		skey[0][i] = tmp0
+		skey[1][i] = tmp1
+		if j++; j == nk {
+			j = 0
+			k++
+		}
+	}
+
+	compressSkey(compSkey[0][:], skey[0][:nkf])
+	compressSkey(compSkey[1][:], skey[1][:nkf])
+
+	for i := range skey {
+		for j := range skey[i] {
+			skey[i][j] = 0
+		}
+	}
+
+	return numRounds
+}
+
+// maskState splits the freshly loaded state q[0] into two shares.
+func maskState(rng *maskRNG, q *[2][8]uint64) {
+	for i, v := range q[0] {
+		m := rng.next()
+		q[0][i] = m
+		q[1][i] = v ^ m
+	}
+}
+
+// unmaskState recombines the shares of q into q[0], so that it can be
+// stored.
+func unmaskState(q *[2][8]uint64) {
+	for i, v := range q[1] {
+		q[0][i] ^= v
+		q[1][i] = 0
+	}
+}
+
+func maskedAddRoundKey(q *[2][8]uint64, skey *[2][120]uint64, u int) {
+	AddRoundKey(&q[0], skey[0][u<<3:])
+	AddRoundKey(&q[1], skey[1][u<<3:])
+}
+
+func maskedEncrypt(rng *maskRNG, numRounds int, skey *[2][120]uint64, q *[2][8]uint64, probe func(*[2][8]uint64)) {
+	maskedAddRoundKey(q, skey, 0)
+	for u := 1; u < numRounds; u++ {
+		maskedSbox(rng, q)
+		ShiftRows(&q[0])
+		ShiftRows(&q[1])
+		MixColumns(&q[0])
+		MixColumns(&q[1])
+		maskedAddRoundKey(q, skey, u)
+		if probe != nil {
+			probe(q)
+		}
+	}
+	maskedSbox(rng, q)
+	ShiftRows(&q[0])
+	ShiftRows(&q[1])
+	maskedAddRoundKey(q, skey, numRounds)
+	if probe != nil {
+		probe(q)
+	}
+}
+
+func maskedDecrypt(rng *maskRNG, numRounds int, skey *[2][120]uint64, q *[2][8]uint64, probe func(*[2][8]uint64)) {
+	maskedAddRoundKey(q, skey, numRounds)
+	for u := numRounds - 1; u > 0; u-- {
+		InvShiftRows(&q[0])
+		InvShiftRows(&q[1])
+		maskedInvSbox(rng, q)
+		maskedAddRoundKey(q, skey, u)
+		InvMixColumns(&q[0])
+		InvMixColumns(&q[1])
+		if probe != nil {
+			probe(q)
+		}
+	}
+	InvShiftRows(&q[0])
+	InvShiftRows(&q[1])
+	maskedInvSbox(rng, q)
+	maskedAddRoundKey(q, skey, 0)
+	if probe != nil {
+		probe(q)
+	}
+}
+
+type maskedBlock struct {
+	modes.BlockModesImpl
+
+	// The mask state is updated on every call, so calls are serialized to
+	// keep the cipher.Block safe for concurrent use.
+	mu sync.Mutex
+
+	rng       maskRNG
+	skExp     [2][120]uint64
+	numRounds int
+	wasReset  bool
+
+	// probe, if set by the tests, is called with the masked state after
+	// every round, to check that the unmasked state is never produced.
+	probe func(q *[2][8]uint64)
+}
+
+func (b *maskedBlock) BlockSize() int {
+	return 16
+}
+
+func (b *maskedBlock) Stride() int {
+	return 4
+}
+
+// remask refreshes the masks of the round keys, so that each call operates
+// on a different sharing of the key schedule.
+func (b *maskedBlock) remask() {
+	n := (b.numRounds + 1) << 3
+	for i := 0; i < n; i++ {
+		r := b.rng.next()
+		b.skExp[0][i] ^= r
+		b.skExp[1][i] ^= r
+	}
+}
+
+func (b *maskedBlock) Encrypt(dst, src []byte) {
+	var q [2][8]uint64
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.wasReset {
+		panic("bsaes/ct64: Encrypt() called after Reset()")
+	}
+
+	b.remask()
+	Load4xU32(&q[0], src[:])
+	maskState(&b.rng, &q)
+	maskedEncrypt(&b.rng, b.numRounds, &b.skExp, &q, b.probe)
+	unmaskState(&q)
+	Store4xU32(dst[:], &q[0])
+}
+
+func (b *maskedBlock) Decrypt(dst, src []byte) {
+	var q [2][8]uint64
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.wasReset {
+		panic("bsaes/ct64: Decrypt() called after Reset()")
+	}
+
+	b.remask()
+	Load4xU32(&q[0], src[:])
+	maskState(&b.rng, &q)
+	maskedDecrypt(&b.rng, b.numRounds, &b.skExp, &q, b.probe)
+	unmaskState(&q)
+	Store4xU32(dst[:], &q[0])
+}
+
+func (b *maskedBlock) BulkEncrypt(dst, src []byte) {
+	var q [2][8]uint64
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.wasReset {
+		panic("bsaes/ct64: BulkEncrypt() called after Reset()")
+	}
+
+	b.remask()
+	Load16xU32(&q[0], src[0:], src[16:], src[32:], src[48:])
+	maskState(&b.rng, &q)
+	maskedEncrypt(&b.rng, b.numRounds, &b.skExp, &q, b.probe)
+	unmaskState(&q)
+	Store16xU32(dst[0:], dst[16:], dst[32:], dst[48:], &q[0])
+}
+
+func (b *maskedBlock) BulkDecrypt(dst, src []byte) {
+	var q [2][8]uint64
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.wasReset {
+		panic("bsaes/ct64: BulkDecrypt() called after Reset()")
+	}
+
+	b.remask()
+	Load16xU32(&q[0], src[0:], src[16:], src[32:], src[48:])
+	maskState(&b.rng, &q)
+	maskedDecrypt(&b.rng, b.numRounds, &b.skExp, &q, b.probe)
+	unmaskState(&q)
+	Store16xU32(dst[0:], dst[16:], dst[32:], dst[48:], &q[0])
+}
+
+func (b *maskedBlock) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.wasReset {
+		b.wasReset = true
+		memwipeU64(b.skExp[0][:])
+		memwipeU64(b.skExp[1][:])
+		b.rng.wipe()
+	}
+}
+
+// NewMaskedCipher creates and returns a new cipher.Block, backed by a first
+// order Boolean masked variant of the 64 bit implementation, intended to
+// frustrate power analysis.  rand is used to generate the masks, and is read
+// from on every call.  It is significantly slower than NewCipher.
+//
+// The masking is only proven against first order attacks in the value-based
+// leakage model.  It does not account for glitches, or for the compiler
+// combining shares, and should be considered as raising the bar rather than
+// a guarantee.
+func NewMaskedCipher(key []byte, rand io.Reader) cipher.Block {
+	var skey [2][30]uint64
+	defer memwipeU64(skey[0][:])
+	defer memwipeU64(skey[1][:])
+
+	b := new(maskedBlock)
+	b.rng.r = rand
+	b.rng.off = len(b.rng.buf)
+	b.numRounds = maskedKeysched(&b.rng, &skey, key)
+	SkeyExpand(b.skExp[0][:], b.numRounds, skey[0][:])
+	SkeyExpand(b.skExp[1][:], b.numRounds, skey[1][:])
+
+	b.BlockModesImpl.Init(b)
+
+	return b
+}
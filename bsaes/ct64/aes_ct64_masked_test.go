@@ -0,0 +1,232 @@
+// aes_ct64_masked_test.go - Masked AES tests.
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to aes_ct64_masked_test.go, using the
+// Creative Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package ct64
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+)
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += n
+	return n, err
+}
+
+type errReader struct{}
+
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("no entropy for you")
+}
+
+func mustRandBytes(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// refRoundStates returns the unmasked state at each of the points where
+// maskedEncrypt calls the probe.
+func refRoundStates(b *block, src []byte) [][8]uint64 {
+	var q [8]uint64
+	var states [][8]uint64
+
+	Load4xU32(&q, src)
+	AddRoundKey(&q, b.skExp[:])
+	for u := 1; u < b.numRounds; u++ {
+		Sbox(&q)
+		ShiftRows(&q)
+		MixColumns(&q)
+		AddRoundKey(&q, b.skExp[u<<3:])
+		states = append(states, q)
+	}
+	Sbox(&q)
+	ShiftRows(&q)
+	AddRoundKey(&q, b.skExp[b.numRounds<<3:])
+	states = append(states, q)
+
+	return states
+}
+
+func TestMaskedKeysched(t *testing.T) {
+	for _, sz := range []int{16, 24, 32} {
+		key := mustRandBytes(t, sz)
+		ref := NewCipher(key).(*block)
+		b1 := NewMaskedCipher(key, rand.Reader).(*maskedBlock)
+		b2 := NewMaskedCipher(key, rand.Reader).(*maskedBlock)
+
+		n := (ref.numRounds + 1) << 3
+		for _, b := range []*maskedBlock{b1, b2} {
+			for pass := 0; pass < 2; pass++ {
+				for i := 0; i < n; i++ {
+					if b.skExp[0][i]^b.skExp[1][i] != ref.skExp[i] {
+						t.Fatalf("[%d]: round key share mismatch at word %d", sz, i)
+					}
+
+					// The freshly expanded shares only have 16 bits of
+					// entropy per word, so only check for the unmasked
+					// round keys once the schedule has been remasked.
+					if pass > 0 && (b.skExp[0][i] == ref.skExp[i] || b.skExp[1][i] == ref.skExp[i]) {
+						t.Fatalf("[%d]: unmasked round key word %d", sz, i)
+					}
+				}
+
+				// Remasks the key schedule.
+				var tmp [16]byte
+				b.Encrypt(tmp[:], tmp[:])
+			}
+		}
+
+		if b1.skExp[0] == b2.skExp[0] {
+			t.Fatalf("[%d]: instances share masks", sz)
+		}
+	}
+}
+
+func TestMaskedShares(t *testing.T) {
+	// For a fixed key and plaintext, every bit of each share of the
+	// intermediate state should be uniformly distributed over many calls,
+	// and neither share should ever equal the unmasked state.
+	const (
+		nTrials = 2048
+		slack   = 6 * 23 // ~6 standard deviations.
+	)
+
+	key := mustRandBytes(t, 16)
+	src := mustRandBytes(t, 16)
+	ref := NewCipher(key).(*block)
+	refStates := refRoundStates(ref, src)
+	var expected [16]byte
+	ref.Encrypt(expected[:], src)
+
+	b := NewMaskedCipher(key, rand.Reader).(*maskedBlock)
+
+	counts := make([][2][8][64]int, len(refStates))
+	round := 0
+	b.probe = func(q *[2][8]uint64) {
+		for i := range q[0] {
+			v := refStates[round][i]
+			if q[0][i]^q[1][i] != v {
+				t.Fatalf("round %d: share mismatch at word %d", round, i)
+			}
+			if q[0][i] == v || q[1][i] == v {
+				t.Fatalf("round %d: unmasked word %d", round, i)
+			}
+			for s := range q {
+				for j := uint(0); j < 64; j++ {
+					counts[round][s][i][j] += int((q[s][i] >> j) & 1)
+				}
+			}
+		}
+		round++
+	}
+
+	var dst [16]byte
+	for n := 0; n < nTrials; n++ {
+		round = 0
+		b.Encrypt(dst[:], src)
+		if !bytes.Equal(expected[:], dst[:]) {
+			t.Fatalf("ciphertext mismatch")
+		}
+	}
+
+	for r := range counts {
+		for s := range counts[r] {
+			for i := range counts[r][s] {
+				for j, c := range counts[r][s][i] {
+					if c < nTrials/2-slack || c > nTrials/2+slack {
+						t.Errorf("round %d share %d word %d bit %d: biased (%d/%d)", r, s, i, j, c, nTrials)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMaskedRNG(t *testing.T) {
+	key := mustRandBytes(t, 16)
+	r := &countingReader{r: rand.Reader}
+	b := NewMaskedCipher(key, r)
+
+	var tmp [16]byte
+	n := r.n
+	for i := 0; i < 16; i++ {
+		b.Encrypt(tmp[:], tmp[:])
+	}
+	if r.n == n {
+		t.Fatalf("masks not drawn from the provided source")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("failing source did not panic")
+		}
+	}()
+	NewMaskedCipher(key, errReader{})
+}
+
+func TestMaskedConcurrent(t *testing.T) {
+	// The mask state is shared by every call, so concurrent use must not
+	// race (run with -race) or corrupt the output.
+	const (
+		nWorkers = 8
+		nIters   = 64
+	)
+
+	key := mustRandBytes(t, 16)
+	ref := NewCipher(key).(*block)
+	b := NewMaskedCipher(key, rand.Reader).(*maskedBlock)
+
+	src := mustRandBytes(t, b.Stride()*16)
+	expected := make([]byte, len(src))
+	ref.BulkEncrypt(expected, src)
+
+	var wg sync.WaitGroup
+	errCh := make(chan string, nWorkers)
+	for w := 0; w < nWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dst := make([]byte, len(src))
+			for i := 0; i < nIters; i++ {
+				b.Encrypt(dst[:16], src[:16])
+				if !bytes.Equal(expected[:16], dst[:16]) {
+					errCh <- "Encrypt: ciphertext mismatch"
+					return
+				}
+				b.BulkEncrypt(dst, src)
+				if !bytes.Equal(expected, dst) {
+					errCh <- "BulkEncrypt: ciphertext mismatch"
+					return
+				}
+				b.BulkDecrypt(dst, dst)
+				if !bytes.Equal(src, dst) {
+					errCh <- "BulkDecrypt: plaintext mismatch"
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		t.Fatal(err)
+	}
+}
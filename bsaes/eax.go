@@ -0,0 +1,209 @@
+// Copyright (c) 2017 Yawning Angel <yawning at schwanenlied dot me>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS
+// BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN
+// ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bsaes
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"runtime"
+)
+
+const (
+	eaxTagSize = 16
+
+	omacNonce  = 0
+	omacHeader = 1
+	omacCipher = 2
+)
+
+var errOpen = errors.New("cipher: message authentication failed")
+
+type eaxImpl struct {
+	b      cipher.Block
+	k1, k2 [BlockSize]byte
+
+	nonceSize int
+	tagSize   int
+}
+
+func (e *eaxImpl) Reset() {
+	memwipe(e.k1[:])
+	memwipe(e.k2[:])
+	resetIfAble(e.b)
+}
+
+func (e *eaxImpl) NonceSize() int {
+	return e.nonceSize
+}
+
+func (e *eaxImpl) Overhead() int {
+	return e.tagSize
+}
+
+// omac computes OMAC^t_K(data), which is CMAC over [t]_n || data.
+func (e *eaxImpl) omac(t byte, data []byte, dst *[BlockSize]byte) {
+	var x [BlockSize]byte
+
+	x[BlockSize-1] = t
+	if len(data) == 0 {
+		xorBlock(&x, e.k1[:])
+		e.b.Encrypt(dst[:], x[:])
+		return
+	}
+	e.b.Encrypt(x[:], x[:])
+
+	for len(data) > BlockSize {
+		xorBlock(&x, data[:BlockSize])
+		e.b.Encrypt(x[:], x[:])
+		data = data[BlockSize:]
+	}
+	for i, v := range data {
+		x[i] ^= v
+	}
+	if len(data) == BlockSize {
+		xorBlock(&x, e.k1[:])
+	} else {
+		x[len(data)] ^= 0x80
+		xorBlock(&x, e.k2[:])
+	}
+	e.b.Encrypt(dst[:], x[:])
+
+	memwipe(x[:])
+}
+
+func (e *eaxImpl) tag(n *[BlockSize]byte, ciphertext, additionalData []byte, dst *[BlockSize]byte) {
+	var h [BlockSize]byte
+
+	e.omac(omacHeader, additionalData, &h)
+	e.omac(omacCipher, ciphertext, dst)
+	xorBlock(dst, n[:])
+	xorBlock(dst, h[:])
+}
+
+func (e *eaxImpl) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	var n, t [BlockSize]byte
+
+	if len(nonce) != e.nonceSize {
+		panic("bsaes/eaxImpl.Seal: nonce with invalid size provided")
+	}
+
+	ret, out := sliceForAppend(dst, len(plaintext)+e.tagSize)
+	e.omac(omacNonce, nonce, &n)
+
+	// The CTR keystream is generated by the bulk `ctrImpl` when the block
+	// cipher is one of ours.
+	ctr := cipher.NewCTR(e.b, n[:])
+	ctr.XORKeyStream(out, plaintext)
+	resetIfAble(ctr)
+
+	e.tag(&n, out[:len(plaintext)], additionalData, &t)
+	copy(out[len(plaintext):], t[:e.tagSize])
+
+	return ret
+}
+
+func (e *eaxImpl) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	var n, t [BlockSize]byte
+
+	if len(nonce) != e.nonceSize {
+		panic("bsaes/eaxImpl.Open: nonce with invalid size provided")
+	}
+	if len(ciphertext) < e.tagSize {
+		return nil, errOpen
+	}
+
+	sz := len(ciphertext) - e.tagSize
+	e.omac(omacNonce, nonce, &n)
+	e.tag(&n, ciphertext[:sz], additionalData, &t)
+	if subtle.ConstantTimeCompare(t[:e.tagSize], ciphertext[sz:]) != 1 {
+		return nil, errOpen
+	}
+
+	ret, out := sliceForAppend(dst, sz)
+	ctr := cipher.NewCTR(e.b, n[:])
+	ctr.XORKeyStream(out, ciphertext[:sz])
+	resetIfAble(ctr)
+
+	return ret, nil
+}
+
+// NewEAX creates and returns a new EAX cipher.AEAD, as specified in "The EAX
+// Mode of Operation" by Bellare, Rogaway and Wagner.  The key argument should
+// be the AES key, either 16, 24, or 32 bytes to select AES-128, AES-192, or
+// AES-256.
+//
+// EAX accepts nonces of any length, and the authentication tag may be
+// truncated to anywhere between 1 and 16 bytes.  Short tags provide
+// correspondingly weak authenticity guarantees.
+func NewEAX(key []byte, nonceSize, tagSize int) (cipher.AEAD, error) {
+	blk, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return newEAX(blk, nonceSize, tagSize)
+}
+
+func newEAX(b cipher.Block, nonceSize, tagSize int) (cipher.AEAD, error) {
+	if nonceSize < 0 {
+		return nil, errors.New("bsaes/NewEAX: invalid nonce size")
+	}
+	if tagSize < 1 || tagSize > eaxTagSize {
+		return nil, errors.New("bsaes/NewEAX: invalid tag size")
+	}
+
+	e := new(eaxImpl)
+	e.b = b
+	e.nonceSize = nonceSize
+	e.tagSize = tagSize
+
+	e.b.Encrypt(e.k1[:], e.k1[:])
+	doubleBlock(&e.k1)
+	e.k2 = e.k1
+	doubleBlock(&e.k2)
+
+	runtime.SetFinalizer(e, (*eaxImpl).Reset)
+
+	return e, nil
+}
+
+func xorBlock(dst *[BlockSize]byte, src []byte) {
+	for i, v := range src[:BlockSize] {
+		dst[i] ^= v
+	}
+}
+
+// sliceForAppend takes a slice and a requested number of bytes.  It returns a
+// slice with the contents of the given slice followed by that many bytes and a
+// second slice that aliases into it and contains only the extra bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
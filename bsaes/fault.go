@@ -0,0 +1,133 @@
+// Copyright (c) 2017 Yawning Angel <yawning at schwanenlied dot me>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS
+// BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN
+// ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bsaes
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/mad-day/Yawning-crypto/bsaes/internal/modes"
+)
+
+// maxStride is the largest Stride of the bitsliced implementations.
+const maxStride = 16
+
+// ErrFaultDetected is the value passed to panic by a cipher.Block created
+// with WithFaultDetection, when the redundant computation does not match.
+var ErrFaultDetected = errors.New("bsaes: fault detected")
+
+// bulkBlockAble is the full bulk interface of the bitsliced implementations.
+type bulkBlockAble interface {
+	bulkECBAble
+
+	// BulkDecrypt decrypts the Stride blocks of ciphertext src, and places
+	// the resulting output in the plaintext dst.
+	BulkDecrypt(dst, src []byte)
+
+	// Reset clears the block cipher state such that key material no longer
+	// appears in process memory.
+	Reset()
+}
+
+// faultDetectBlock wraps a bitsliced cipher.Block, and verifies each
+// operation by applying the inverse operation to the output, and comparing
+// the result with the input.
+type faultDetectBlock struct {
+	modes.BlockModesImpl
+
+	b bulkBlockAble
+}
+
+func (f *faultDetectBlock) BlockSize() int {
+	return BlockSize
+}
+
+func (f *faultDetectBlock) Stride() int {
+	return f.b.Stride()
+}
+
+func (f *faultDetectBlock) Encrypt(dst, src []byte) {
+	var in, chk [BlockSize]byte
+	defer memwipe(in[:])
+
+	copy(in[:], src[:BlockSize])
+	f.b.Encrypt(dst, in[:])
+	f.b.Decrypt(chk[:], dst)
+	f.check(in[:], chk[:], dst[:BlockSize])
+}
+
+func (f *faultDetectBlock) Decrypt(dst, src []byte) {
+	var in, chk [BlockSize]byte
+	defer memwipe(in[:])
+
+	copy(in[:], src[:BlockSize])
+	f.b.Decrypt(dst, in[:])
+	f.b.Encrypt(chk[:], dst)
+	f.check(in[:], chk[:], dst[:BlockSize])
+}
+
+func (f *faultDetectBlock) BulkEncrypt(dst, src []byte) {
+	var in, chk [maxStride * BlockSize]byte
+	defer memwipe(in[:])
+
+	n := f.b.Stride() * BlockSize
+	copy(in[:], src[:n])
+	f.b.BulkEncrypt(dst, in[:n])
+	f.b.BulkDecrypt(chk[:n], dst)
+	f.check(in[:n], chk[:n], dst[:n])
+}
+
+func (f *faultDetectBlock) BulkDecrypt(dst, src []byte) {
+	var in, chk [maxStride * BlockSize]byte
+	defer memwipe(in[:])
+
+	n := f.b.Stride() * BlockSize
+	copy(in[:], src[:n])
+	f.b.BulkDecrypt(dst, in[:n])
+	f.b.BulkEncrypt(chk[:n], dst)
+	f.check(in[:n], chk[:n], dst[:n])
+}
+
+// check compares the input with the result of the inverse operation in
+// constant time, and on a mismatch clears the output and panics, so that a
+// faulty result is never released.
+func (f *faultDetectBlock) check(in, chk, dst []byte) {
+	ok := subtle.ConstantTimeCompare(in, chk)
+	memwipe(chk)
+	if ok != 1 {
+		memwipe(dst)
+		panic(ErrFaultDetected)
+	}
+}
+
+func (f *faultDetectBlock) Reset() {
+	f.b.Reset()
+}
+
+func newFaultDetectBlock(b cipher.Block) cipher.Block {
+	f := &faultDetectBlock{b: b.(bulkBlockAble)}
+	f.BlockModesImpl.Init(f)
+
+	return f
+}
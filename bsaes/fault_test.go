@@ -0,0 +1,156 @@
+// fault_test.go - Fault detection tests.
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to fault_test.go, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package bsaes
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+)
+
+// faultyBlock is a bitsliced cipher.Block that corrupts the output of the
+// next encryption or decryption when armed.
+type faultyBlock struct {
+	bulkBlockAble
+
+	armed bool
+}
+
+func (f *faultyBlock) fault(dst []byte) {
+	if f.armed {
+		f.armed = false
+		dst[len(dst)-1] ^= 0x01
+	}
+}
+
+func (f *faultyBlock) Encrypt(dst, src []byte) {
+	f.bulkBlockAble.Encrypt(dst, src)
+	f.fault(dst[:BlockSize])
+}
+
+func (f *faultyBlock) Decrypt(dst, src []byte) {
+	f.bulkBlockAble.Decrypt(dst, src)
+	f.fault(dst[:BlockSize])
+}
+
+func (f *faultyBlock) BulkEncrypt(dst, src []byte) {
+	f.bulkBlockAble.BulkEncrypt(dst, src)
+	f.fault(dst[:f.Stride()*BlockSize])
+}
+
+func (f *faultyBlock) BulkDecrypt(dst, src []byte) {
+	f.bulkBlockAble.BulkDecrypt(dst, src)
+	f.fault(dst[:f.Stride()*BlockSize])
+}
+
+func TestFaultDetection(t *testing.T) {
+	key := mustRandBytes(16)
+	refBlk, _ := aes.NewCipher(key)
+
+	for _, impl := range impls {
+		if impl == implRuntime {
+			continue
+		}
+		t.Logf("Testing implementation: %v\n", impl.name)
+
+		blk := newFaultDetectBlock(impl.ctor(key))
+		stride := blk.(bulkBlockAble).Stride()
+
+		src := mustRandBytes(stride * BlockSize)
+		dst := make([]byte, len(src))
+		check := make([]byte, len(src))
+
+		for i := 0; i < len(src); i += BlockSize {
+			refBlk.Encrypt(check[i:], src[i:])
+		}
+		blk.Encrypt(dst, src)
+		assertEqual(t, 0, check[:BlockSize], dst[:BlockSize])
+		blk.Decrypt(dst, dst)
+		assertEqual(t, 0, src[:BlockSize], dst[:BlockSize])
+		blk.(bulkBlockAble).BulkEncrypt(dst, src)
+		assertEqual(t, 0, check, dst)
+		blk.(bulkBlockAble).BulkDecrypt(dst, dst)
+		assertEqual(t, 0, src, dst)
+
+		// The modes must go through the checked bulk interface.
+		var iv [12]byte
+		pt := mustRandBytes(1031)
+		g, _ := cipher.NewGCM(blk)
+		refG, _ := cipher.NewGCM(refBlk)
+		if _, ok := g.(BatchAEAD); !ok {
+			t.Fatalf("GCM does not use the bitsliced implementation")
+		}
+		assertEqual(t, 0, refG.Seal(nil, iv[:], pt, nil), g.Seal(nil, iv[:], pt, nil))
+	}
+}
+
+func TestFaultDetection_Fault(t *testing.T) {
+	key := mustRandBytes(16)
+
+	for _, impl := range impls {
+		if impl == implRuntime {
+			continue
+		}
+		t.Logf("Testing implementation: %v\n", impl.name)
+
+		fb := &faultyBlock{bulkBlockAble: impl.ctor(key).(bulkBlockAble)}
+		blk := newFaultDetectBlock(fb).(bulkBlockAble)
+		n := blk.Stride() * BlockSize
+
+		for _, op := range []struct {
+			name string
+			fn   func(dst, src []byte)
+		}{
+			{"Encrypt", blk.Encrypt},
+			{"Decrypt", blk.Decrypt},
+			{"BulkEncrypt", blk.BulkEncrypt},
+			{"BulkDecrypt", blk.BulkDecrypt},
+			{"XORKeyStream", cipher.NewCTR(blk, make([]byte, BlockSize)).XORKeyStream},
+		} {
+			src := mustRandBytes(n)
+			dst := mustRandBytes(n)
+
+			// Without a fault, there is no panic.
+			op.fn(dst, src)
+
+			prev := append([]byte{}, dst...)
+			fb.armed = true
+			func() {
+				defer func() {
+					if r := recover(); r != ErrFaultDetected {
+						t.Fatalf("%s: unexpected recover() value: %v", op.name, r)
+					}
+				}()
+				op.fn(dst, src)
+			}()
+			// The faulty output must be cleared by the block, and never
+			// reach dst for the modes.
+			if !bytes.Equal(dst, prev) && !bytes.Equal(dst[:BlockSize], make([]byte, BlockSize)) {
+				t.Fatalf("%s: faulty output was released", op.name)
+			}
+		}
+	}
+}
+
+func TestNewCipherWithOptions_FaultDetection(t *testing.T) {
+	key := mustRandBytes(16)
+
+	for _, opts := range [][]Option{
+		{WithFaultDetection()},
+		{WithFaultDetection(), WithMasking(nil)},
+	} {
+		blk, err := NewCipherWithOptions(key, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := blk.(*faultDetectBlock); !ok {
+			t.Fatalf("WithFaultDetection did not enable fault detection")
+		}
+	}
+}
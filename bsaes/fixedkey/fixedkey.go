@@ -0,0 +1,182 @@
+// Copyright (c) 2017 Yawning Angel <yawning at schwanenlied dot me>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS
+// BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN
+// ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package fixedkey implements the fixed-key AES based hash functions from
+// "Efficient and Secure Multiparty Computation from Fixed-Key Block Ciphers"
+// by Guo, Katz, Wang, and Yu (https://eprint.iacr.org/2019/074), for use in
+// garbled circuits and OT extension.
+//
+// All of the functions operate on slices of 128 bit labels, and use a single
+// bitsliced key schedule and the bulk interface, without allocating.
+package fixedkey
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"runtime"
+
+	"github.com/mad-day/Yawning-crypto/bsaes/ct64"
+)
+
+// BlockSize is the size of a label in bytes.
+const BlockSize = 16
+
+const maxStride = 4
+
+type bulkECBAble interface {
+	cipher.Block
+
+	Stride() int
+	BulkEncrypt(dst, src []byte)
+	Reset()
+}
+
+// Hash is a fixed-key AES instance, modeled as a random permutation π.  The
+// key is public, and is shared by all parties to the protocol.  A Hash
+// holds scratch space so that hashing does not allocate, and is not safe for
+// concurrent use, so each goroutine should create its own.
+type Hash struct {
+	b      bulkECBAble
+	stride int
+
+	x, y [maxStride * BlockSize]byte
+}
+
+// New creates a new Hash with the fixed AES key key, which must be 16, 24,
+// or 32 bytes.
+func New(key []byte) (*Hash, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, aes.KeySizeError(len(key))
+	}
+
+	h := &Hash{b: ct64.NewCipher(key).(bulkECBAble)}
+	h.stride = h.b.Stride()
+	runtime.SetFinalizer(h, (*Hash).Reset)
+
+	return h, nil
+}
+
+// Reset clears the key schedule and the scratch space.  The Hash must not be
+// used after Reset is called.
+func (h *Hash) Reset() {
+	h.b.Reset()
+	for i := range h.x {
+		h.x[i], h.y[i] = 0, 0
+	}
+}
+
+const (
+	opPermute = iota
+	opCR
+	opCCR
+	opTCCR
+)
+
+// Permute sets dst to π(src), for each label in src.
+func (h *Hash) Permute(dst, src []byte) {
+	h.do(dst, src, opPermute, 0)
+}
+
+// CR sets dst to the correlation robust hash π(x) ⊕ x, for each label x in
+// src.
+func (h *Hash) CR(dst, src []byte) {
+	h.do(dst, src, opCR, 0)
+}
+
+// CCR sets dst to the circular correlation robust hash π(σ(x)) ⊕ σ(x), for
+// each label x in src, where σ(xL || xR) = (xL ⊕ xR) || xL.
+func (h *Hash) CCR(dst, src []byte) {
+	h.do(dst, src, opCCR, 0)
+}
+
+// TCCR sets dst to the tweakable circular correlation robust hash
+// π(π(x) ⊕ i) ⊕ π(x), for each label x in src.  The tweak i for the n-th
+// label is tweak + n, a 64 bit counter that wraps, zero extended to a 128 bit
+// little endian integer.
+func (h *Hash) TCCR(dst, src []byte, tweak uint64) {
+	h.do(dst, src, opTCCR, tweak)
+}
+
+// do processes src a Stride sized chunk at a time, through scratch buffers,
+// so dst and src may overlap entirely or not at all.
+func (h *Hash) do(dst, src []byte, op int, tweak uint64) {
+	x, y := &h.x, &h.y
+
+	if len(src)%BlockSize != 0 {
+		panic("bsaes/fixedkey: input not a multiple of the block size")
+	}
+	if len(dst) < len(src) {
+		panic("bsaes/fixedkey: output smaller than input")
+	}
+
+	n := h.stride * BlockSize
+	for len(src) > 0 {
+		sz := len(src)
+		if sz > n {
+			sz = n
+		}
+		copy(x[:], src[:sz])
+
+		switch op {
+		case opPermute:
+			h.b.BulkEncrypt(y[:n], x[:n])
+		case opCR:
+			h.b.BulkEncrypt(y[:n], x[:n])
+			xorBytes(y[:n], y[:n], x[:n])
+		case opCCR:
+			sigma(x[:n], x[:n])
+			h.b.BulkEncrypt(y[:n], x[:n])
+			xorBytes(y[:n], y[:n], x[:n])
+		case opTCCR:
+			h.b.BulkEncrypt(x[:n], x[:n])
+			for off := 0; off < n; off += BlockSize {
+				binary.LittleEndian.PutUint64(y[off:], tweak)
+				binary.LittleEndian.PutUint64(y[off+8:], 0)
+				tweak++
+			}
+			xorBytes(y[:n], y[:n], x[:n])
+			h.b.BulkEncrypt(y[:n], y[:n])
+			xorBytes(y[:n], y[:n], x[:n])
+		}
+		copy(dst, y[:sz])
+
+		dst, src = dst[sz:], src[sz:]
+	}
+}
+
+func sigma(dst, src []byte) {
+	for off := 0; off < len(src); off += BlockSize {
+		xL := binary.LittleEndian.Uint64(src[off:])
+		xR := binary.LittleEndian.Uint64(src[off+8:])
+		binary.LittleEndian.PutUint64(dst[off:], xL^xR)
+		binary.LittleEndian.PutUint64(dst[off+8:], xL)
+	}
+}
+
+func xorBytes(dst, a, b []byte) {
+	for i := range dst {
+		dst[i] = a[i] ^ b[i]
+	}
+}
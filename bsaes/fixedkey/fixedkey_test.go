@@ -0,0 +1,148 @@
+// fixedkey_test.go - Fixed-key AES hash tests.
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to fixedkey_test.go, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package fixedkey
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"testing"
+)
+
+func mustRandBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic("mustRandBytes: " + err.Error())
+	}
+	return b
+}
+
+// The reference implementations process a single label with crypto/aes.
+
+func refSigma(x []byte) []byte {
+	var s [BlockSize]byte
+	for i := 0; i < 8; i++ {
+		s[i] = x[i] ^ x[8+i]
+		s[8+i] = x[i]
+	}
+	return s[:]
+}
+
+func refCR(b cipher.Block, x []byte) []byte {
+	var y [BlockSize]byte
+	b.Encrypt(y[:], x)
+	xorBytes(y[:], y[:], x)
+	return y[:]
+}
+
+func refCCR(b cipher.Block, x []byte) []byte {
+	return refCR(b, refSigma(x))
+}
+
+func refTCCR(b cipher.Block, x []byte, i uint64) []byte {
+	var p, y [BlockSize]byte
+	b.Encrypt(p[:], x)
+	binary.LittleEndian.PutUint64(y[:], i)
+	xorBytes(y[:], y[:], p[:])
+	b.Encrypt(y[:], y[:])
+	xorBytes(y[:], y[:], p[:])
+	return y[:]
+}
+
+func TestHash(t *testing.T) {
+	key := mustRandBytes(16)
+	refBlk, _ := aes.NewCipher(key)
+	h, err := New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const tweak = 0xfffffffffffffffd // Exercise the 64 bit counter wrapping.
+
+	for n := 0; n <= 2*maxStride+1; n++ {
+		src := mustRandBytes(n * BlockSize)
+		dst := make([]byte, len(src))
+
+		for _, v := range []struct {
+			name string
+			fn   func(dst, src []byte)
+			ref  func(x []byte, i uint64) []byte
+		}{
+			{"Permute", h.Permute, func(x []byte, i uint64) []byte {
+				var y [BlockSize]byte
+				refBlk.Encrypt(y[:], x)
+				return y[:]
+			}},
+			{"CR", h.CR, func(x []byte, i uint64) []byte { return refCR(refBlk, x) }},
+			{"CCR", h.CCR, func(x []byte, i uint64) []byte { return refCCR(refBlk, x) }},
+			{"TCCR", func(dst, src []byte) { h.TCCR(dst, src, tweak) }, func(x []byte, i uint64) []byte {
+				return refTCCR(refBlk, x, tweak+i)
+			}},
+		} {
+			v.fn(dst, src)
+			for i := 0; i < n; i++ {
+				x := src[i*BlockSize : (i+1)*BlockSize]
+				if !bytes.Equal(v.ref(x, uint64(i)), dst[i*BlockSize:(i+1)*BlockSize]) {
+					t.Fatalf("%s: [%d/%d]: mismatch", v.name, i, n)
+				}
+			}
+
+			// In-place.
+			inPlace := append([]byte{}, src...)
+			v.fn(inPlace, inPlace)
+			if !bytes.Equal(dst, inPlace) {
+				t.Fatalf("%s: [%d]: in-place mismatch", v.name, n)
+			}
+		}
+	}
+
+	if _, err := New(key[:15]); err == nil {
+		t.Fatalf("New accepted an invalid key")
+	}
+}
+
+func TestHash_Allocations(t *testing.T) {
+	h, _ := New(mustRandBytes(16))
+	buf := mustRandBytes(1024)
+
+	if n := testing.AllocsPerRun(100, func() {
+		h.CR(buf, buf)
+		h.CCR(buf, buf)
+		h.TCCR(buf, buf, 0)
+	}); n != 0 {
+		t.Fatalf("allocations: %v", n)
+	}
+}
+
+var benchOutput []byte
+
+func BenchmarkHash(b *testing.B) {
+	h, _ := New(mustRandBytes(16))
+	src := mustRandBytes(16384)
+	dst := make([]byte, len(src))
+
+	for _, v := range []struct {
+		name string
+		fn   func(dst, src []byte)
+	}{
+		{"CR", h.CR},
+		{"CCR", h.CCR},
+		{"TCCR", func(dst, src []byte) { h.TCCR(dst, src, 0) }},
+	} {
+		b.Run(v.name, func(b *testing.B) {
+			b.SetBytes(int64(len(src)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				v.fn(dst, src)
+			}
+			benchOutput = dst
+		})
+	}
+}
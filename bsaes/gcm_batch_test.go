@@ -0,0 +1,165 @@
+// gcm_batch_test.go - Batched GCM tests.
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to gcm_batch_test.go, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package bsaes
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"testing"
+)
+
+func TestGCMBatch(t *testing.T) {
+	var key [16]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, impl := range impls {
+		for _, nonceSize := range []int{12, 8} {
+			g, err := cipher.NewGCMWithNonceSize(impl.ctor(key[:]), nonceSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bg, ok := g.(BatchAEAD)
+			if !ok {
+				if impl != implRuntime {
+					t.Fatalf("%v: GCM does not implement BatchAEAD", impl.name)
+				}
+				t.Logf("Skipping implementation: %v\n", impl.name)
+				break
+			}
+			t.Logf("Testing implementation: %v (nonce size %d)\n", impl.name, nonceSize)
+
+			const n = 37
+			nonces := make([][]byte, n)
+			pts := make([][]byte, n)
+			ads := make([][]byte, n)
+			for i := 0; i < n; i++ {
+				nonces[i] = mustRandBytes(nonceSize)
+				pts[i] = mustRandBytes(i * 5)
+				ads[i] = mustRandBytes(i % 20)
+			}
+
+			cts := bg.SealBatch(nil, nonces, pts, ads)
+			for i := range pts {
+				assertEqual(t, i, g.Seal(nil, nonces[i], pts[i], ads[i]), cts[i])
+			}
+
+			// Corrupt one packet, and truncate another.
+			cts[3][0] ^= 0x01
+			cts[7] = cts[7][:g.Overhead()-1]
+
+			dsts := make([][]byte, n)
+			for i := range dsts {
+				dsts[i] = []byte(fmt.Sprintf("prefix %d:", i))
+			}
+			out, errs := bg.OpenBatch(dsts, nonces, cts, ads)
+			for i := range pts {
+				switch i {
+				case 3, 7:
+					if errs[i] == nil || out[i] != nil {
+						t.Fatalf("[%d]: OpenBatch accepted a corrupted packet", i)
+					}
+				default:
+					if errs[i] != nil {
+						t.Fatalf("[%d]: OpenBatch: %v", i, errs[i])
+					}
+					expected := append([]byte(fmt.Sprintf("prefix %d:", i)), pts[i]...)
+					assertEqual(t, i, expected, out[i])
+				}
+			}
+
+			// Without additional data.
+			cts = bg.SealBatch(nil, nonces[:5], pts[:5], nil)
+			out, errs = bg.OpenBatch(nil, nonces[:5], cts, nil)
+			for i := range out {
+				if errs[i] != nil {
+					t.Fatalf("[%d]: OpenBatch: %v", i, errs[i])
+				}
+				assertEqual(t, i, pts[i], out[i])
+			}
+		}
+	}
+}
+
+func mustRandBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic("mustRandBytes: " + err.Error())
+	}
+	return b
+}
+
+func TestGCM_TagSize(t *testing.T) {
+	var key [16]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		t.Fatal(err)
+	}
+	refBlk, _ := aes.NewCipher(key[:])
+	nonce, pt, ad := mustRandBytes(12), mustRandBytes(100), mustRandBytes(7)
+
+	for _, impl := range impls {
+		t.Logf("Testing implementation: %v\n", impl.name)
+		for tagSize := 12; tagSize <= 16; tagSize++ {
+			ref, _ := cipher.NewGCMWithTagSize(refBlk, tagSize)
+			g, err := cipher.NewGCMWithTagSize(impl.ctor(key[:]), tagSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := g.(BatchAEAD); !ok && impl != implRuntime {
+				t.Fatalf("%v: GCM is not the bitsliced implementation", impl.name)
+			}
+
+			ct := g.Seal(nil, nonce, pt, ad)
+			assertEqual(t, tagSize, ref.Seal(nil, nonce, pt, ad), ct)
+			dst, err := g.Open(nil, nonce, ct, ad)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertEqual(t, tagSize, pt, dst)
+		}
+	}
+}
+
+func BenchmarkGCMBatch(b *testing.B) {
+	var key [16]byte
+	const n, sz = 64, 64
+
+	g, err := cipher.NewGCM(nativeImpl.ctor(key[:]))
+	if err != nil {
+		b.Fatal(err)
+	}
+	bg, ok := g.(BatchAEAD)
+	if !ok {
+		b.Skip("GCM is the runtime implementation")
+	}
+
+	nonces := make([][]byte, n)
+	pts := make([][]byte, n)
+	for i := range pts {
+		nonces[i] = make([]byte, 12)
+		pts[i] = make([]byte, sz)
+	}
+
+	b.Run("Seal", func(b *testing.B) {
+		b.SetBytes(n * sz)
+		for i := 0; i < b.N; i++ {
+			for j := range pts {
+				benchOutput = g.Seal(nil, nonces[j], pts[j], nil)
+			}
+		}
+	})
+	b.Run("SealBatch", func(b *testing.B) {
+		b.SetBytes(n * sz)
+		for i := 0; i < b.N; i++ {
+			benchOutput = bg.SealBatch(nil, nonces, pts, nil)[0]
+		}
+	})
+}
@@ -21,41 +21,21 @@
 // CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
 // SOFTWARE.
 
-// Package ghash is a constant time 64 bit optimized GHASH implementation.
+// Package ghash is a constant time GHASH implementation, with 64 bit and 32
+// bit optimized variants selected at compile time.
 package ghash
 
-import "encoding/binary"
+import (
+	"encoding/binary"
+	"unsafe"
+)
 
 const blockSize = 16
 
-func bmul64(x, y uint64) uint64 {
-	x0 := x & 0x1111111111111111
-	x1 := x & 0x2222222222222222
-	x2 := x & 0x4444444444444444
-	x3 := x & 0x8888888888888888
-	y0 := y & 0x1111111111111111
-	y1 := y & 0x2222222222222222
-	y2 := y & 0x4444444444444444
-	y3 := y & 0x8888888888888888
-	z0 := (x0 * y0) ^ (x1 * y3) ^ (x2 * y2) ^ (x3 * y1)
-	z1 := (x0 * y1) ^ (x1 * y0) ^ (x2 * y3) ^ (x3 * y2)
-	z2 := (x0 * y2) ^ (x1 * y1) ^ (x2 * y0) ^ (x3 * y3)
-	z3 := (x0 * y3) ^ (x1 * y2) ^ (x2 * y1) ^ (x3 * y0)
-	z0 &= 0x1111111111111111
-	z1 &= 0x2222222222222222
-	z2 &= 0x4444444444444444
-	z3 &= 0x8888888888888888
-	return z0 | z1 | z2 | z3
-}
-
-func rev64(x uint64) uint64 {
-	x = ((x & 0x5555555555555555) << 1) | ((x >> 1) & 0x5555555555555555)
-	x = ((x & 0x3333333333333333) << 2) | ((x >> 2) & 0x3333333333333333)
-	x = ((x & 0x0F0F0F0F0F0F0F0F) << 4) | ((x >> 4) & 0x0F0F0F0F0F0F0F0F)
-	x = ((x & 0x00FF00FF00FF00FF) << 8) | ((x >> 8) & 0x00FF00FF00FF00FF)
-	x = ((x & 0x0000FFFF0000FFFF) << 16) | ((x >> 16) & 0x0000FFFF0000FFFF)
-	return (x << 32) | (x >> 32)
-}
+// use32 selects the multiply that does not need to synthesize wide
+// multiplies on the target, based on the pointer size, the same way as the
+// parent package picks the AES implementation.
+const use32 = unsafe.Sizeof(uintptr(0)) == 4
 
 // Ghash calculates the GHASH of data, with key h, and input y, and stores the
 // resulting digest in y.
@@ -68,12 +48,7 @@ func Ghash(y, h *[blockSize]byte, data []byte) {
 
 	y1 := binary.BigEndian.Uint64(y[:])
 	y0 := binary.BigEndian.Uint64(y[8:])
-	h1 := binary.BigEndian.Uint64(h[:])
-	h0 := binary.BigEndian.Uint64(h[8:])
-	h0r := rev64(h0)
-	h1r := rev64(h1)
-	h2 := h0 ^ h1
-	h2r := h0r ^ h1r
+	k := newKey(binary.BigEndian.Uint64(h[:]), binary.BigEndian.Uint64(h[8:]))
 
 	for l > 0 {
 		if l >= blockSize {
@@ -87,43 +62,116 @@ func Ghash(y, h *[blockSize]byte, data []byte) {
 		}
 		y1 ^= binary.BigEndian.Uint64(src)
 		y0 ^= binary.BigEndian.Uint64(src[8:])
-
-		y0r := rev64(y0)
-		y1r := rev64(y1)
-		y2 := y0 ^ y1
-		y2r := y0r ^ y1r
-
-		z0 := bmul64(y0, h0)
-		z1 := bmul64(y1, h1)
-		z2 := bmul64(y2, h2)
-		z0h := bmul64(y0r, h0r)
-		z1h := bmul64(y1r, h1r)
-		z2h := bmul64(y2r, h2r)
-		z2 ^= z0 ^ z1
-		z2h ^= z0h ^ z1h
-		z0h = rev64(z0h) >> 1
-		z1h = rev64(z1h) >> 1
-		z2h = rev64(z2h) >> 1
-
-		v0 := z0
-		v1 := z0h ^ z2
-		v2 := z1 ^ z2h
-		v3 := z1h
-
-		v3 = (v3 << 1) | (v2 >> 63)
-		v2 = (v2 << 1) | (v1 >> 63)
-		v1 = (v1 << 1) | (v0 >> 63)
-		v0 = (v0 << 1)
-
-		v2 ^= v0 ^ (v0 >> 1) ^ (v0 >> 2) ^ (v0 >> 7)
-		v1 ^= (v0 << 63) ^ (v0 << 62) ^ (v0 << 57)
-		v3 ^= v1 ^ (v1 >> 1) ^ (v1 >> 2) ^ (v1 >> 7)
-		v2 ^= (v1 << 63) ^ (v1 << 62) ^ (v1 << 57)
-
-		y0 = v2
-		y1 = v3
+		if use32 {
+			y1, y0 = mul32(&k, y1, y0)
+		} else {
+			y1, y0 = mul64(&k, y1, y0)
+		}
 	}
 
 	binary.BigEndian.PutUint64(y[:], y1)
 	binary.BigEndian.PutUint64(y[8:], y0)
 }
+
+// Polyval calculates the POLYVAL (RFC 8452) of data, with key h, and input
+// y, and stores the resulting digest in y.  As with Ghash, a trailing
+// partial block is zero padded.
+func Polyval(y, h *[blockSize]byte, data []byte) {
+	var tmp [blockSize]byte
+	var src []byte
+
+	// POLYVAL(H, X_1, ..., X_n) =
+	//   ByteReverse(GHASH(mulX_GHASH(ByteReverse(H)), ByteReverse(X_1), ...,
+	//     ByteReverse(X_n)))
+	//
+	// Byte reversing a block and loading it big endian is the same as
+	// loading it little endian with the halves swapped.
+
+	buf := data
+	l := len(buf)
+
+	y1 := binary.LittleEndian.Uint64(y[8:])
+	y0 := binary.LittleEndian.Uint64(y[:])
+	h1 := binary.LittleEndian.Uint64(h[8:])
+	h0 := binary.LittleEndian.Uint64(h[:])
+
+	// mulX_GHASH
+	carry := h0 & 1
+	h0 = (h0 >> 1) | (h1 << 63)
+	h1 = (h1 >> 1) ^ (0xe100000000000000 & -carry)
+	k := newKey(h1, h0)
+
+	for l > 0 {
+		if l >= blockSize {
+			src = buf
+			buf = buf[blockSize:]
+			l -= blockSize
+		} else {
+			copy(tmp[:], buf)
+			src = tmp[:]
+			l = 0
+		}
+		y1 ^= binary.LittleEndian.Uint64(src[8:])
+		y0 ^= binary.LittleEndian.Uint64(src)
+		if use32 {
+			y1, y0 = mul32(&k, y1, y0)
+		} else {
+			y1, y0 = mul64(&k, y1, y0)
+		}
+	}
+
+	binary.LittleEndian.PutUint64(y[8:], y1)
+	binary.LittleEndian.PutUint64(y[:], y0)
+}
+
+type key struct {
+	// 64 bit variant.
+	h0, h1, h2    uint64
+	h0r, h1r, h2r uint64
+
+	// 32 bit variant, see operands32().
+	b, br [9]uint32
+}
+
+// newKey precomputes the operands of the multiply selected by use32.
+func newKey(h1, h0 uint64) key {
+	var k key
+
+	if use32 {
+		k.init32(h1, h0)
+	} else {
+		k.init64(h1, h0)
+	}
+
+	return k
+}
+
+func (k *key) init64(h1, h0 uint64) {
+	k.h0, k.h1 = h0, h1
+	k.h0r, k.h1r = rev64(h0), rev64(h1)
+	k.h2, k.h2r = h0^h1, k.h0r^k.h1r
+}
+
+func (k *key) init32(h1, h0 uint64) {
+	operands32(&k.b, h1, h0)
+	for i, v := range k.b {
+		k.br[i] = rev32(v)
+	}
+}
+
+// reduce shifts the 256 bit carry-less product (v3 || v2 || v1 || v0) left
+// by one bit to account for GHASH's bit reflected representation, and
+// reduces it modulo the GHASH polynomial.
+func reduce(v3, v2, v1, v0 uint64) (uint64, uint64) {
+	v3 = (v3 << 1) | (v2 >> 63)
+	v2 = (v2 << 1) | (v1 >> 63)
+	v1 = (v1 << 1) | (v0 >> 63)
+	v0 = (v0 << 1)
+
+	v2 ^= v0 ^ (v0 >> 1) ^ (v0 >> 2) ^ (v0 >> 7)
+	v1 ^= (v0 << 63) ^ (v0 << 62) ^ (v0 << 57)
+	v3 ^= v1 ^ (v1 >> 1) ^ (v1 >> 2) ^ (v1 >> 7)
+	v2 ^= (v1 << 63) ^ (v1 << 62) ^ (v1 << 57)
+
+	return v3, v2
+}
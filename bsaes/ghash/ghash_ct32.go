@@ -0,0 +1,101 @@
+// Copyright (c) 2016 Thomas Pornin <pornin@bolet.org>
+// Copyright (c) 2017 Yawning Angel <yawning at schwanenlied dot me>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS
+// BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN
+// ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package ghash
+
+func bmul32(x, y uint32) uint32 {
+	x0 := x & 0x11111111
+	x1 := x & 0x22222222
+	x2 := x & 0x44444444
+	x3 := x & 0x88888888
+	y0 := y & 0x11111111
+	y1 := y & 0x22222222
+	y2 := y & 0x44444444
+	y3 := y & 0x88888888
+	z0 := (x0 * y0) ^ (x1 * y3) ^ (x2 * y2) ^ (x3 * y1)
+	z1 := (x0 * y1) ^ (x1 * y0) ^ (x2 * y3) ^ (x3 * y2)
+	z2 := (x0 * y2) ^ (x1 * y1) ^ (x2 * y0) ^ (x3 * y3)
+	z3 := (x0 * y3) ^ (x1 * y2) ^ (x2 * y1) ^ (x3 * y0)
+	z0 &= 0x11111111
+	z1 &= 0x22222222
+	z2 &= 0x44444444
+	z3 &= 0x88888888
+	return z0 | z1 | z2 | z3
+}
+
+func rev32(x uint32) uint32 {
+	x = ((x & 0x55555555) << 1) | ((x >> 1) & 0x55555555)
+	x = ((x & 0x33333333) << 2) | ((x >> 2) & 0x33333333)
+	x = ((x & 0x0F0F0F0F) << 4) | ((x >> 4) & 0x0F0F0F0F)
+	x = ((x & 0x00FF00FF) << 8) | ((x >> 8) & 0x00FF00FF)
+	return (x << 16) | (x >> 16)
+}
+
+// clmul32 returns the full 63 bit carry-less product of x and y, given the
+// operands and their bit reversals.  bmul32 only produces the low half of
+// the product, but the high half is the bit reversed low half of the product
+// of the bit reversed operands.
+func clmul32(x, xr, y, yr uint32) uint64 {
+	lo := bmul32(x, y)
+	hi := rev32(bmul32(xr, yr)) >> 1
+	return uint64(hi)<<32 | uint64(lo)
+}
+
+// karatsuba64 combines the products of the low halves, the high halves, and
+// the sum of the halves of two 64 bit operands into their 128 bit product.
+func karatsuba64(ll, hh, mm uint64) (uint64, uint64) {
+	mm ^= ll ^ hh
+	return ll ^ (mm << 32), hh ^ (mm >> 32)
+}
+
+// operands32 splits (y1 || y0) into the nine 32 bit words multiplied by a
+// two level Karatsuba decomposition: y0, y1 and y0 ^ y1, each followed by
+// its low half, high half, and the sum of the two.
+func operands32(a *[9]uint32, y1, y0 uint64) {
+	for i, u := range [3]uint64{y0, y1, y0 ^ y1} {
+		a[3*i+0] = uint32(u)
+		a[3*i+1] = uint32(u >> 32)
+		a[3*i+2] = a[3*i+0] ^ a[3*i+1]
+	}
+}
+
+// mul32 returns (y1 || y0) * H, in GHASH's bit reflected representation,
+// using only 32 bit multiplies.  This is the approach taken by BearSSL's
+// ghash_ctmul32, and requires 18 multiplies per block.
+func mul32(k *key, y1, y0 uint64) (uint64, uint64) {
+	var a [9]uint32
+	var c [9]uint64
+
+	operands32(&a, y1, y0)
+	for i, v := range a {
+		c[i] = clmul32(v, rev32(v), k.b[i], k.br[i])
+	}
+
+	z0, z0h := karatsuba64(c[0], c[1], c[2]) // y0 * h0
+	z1, z1h := karatsuba64(c[3], c[4], c[5]) // y1 * h1
+	z2, z2h := karatsuba64(c[6], c[7], c[8]) // (y0 ^ y1) * (h0 ^ h1)
+	z2 ^= z0 ^ z1
+	z2h ^= z0h ^ z1h
+
+	return reduce(z1h, z1^z2h, z0h^z2, z0)
+}
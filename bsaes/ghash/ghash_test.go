@@ -116,6 +116,117 @@ func TestGHASH(t *testing.T) {
 	}
 }
 
+// The test vectors are from Appendix C of RFC 8452.
+//
+// https://tools.ietf.org/html/rfc8452
+
+var polyvalVectors = []struct {
+	h string
+	x string
+	y string
+}{
+	{
+		"25629347589242761d31f826ba4b757b",
+		"4f4f95668c83dfb6401762bb2d01a262d1a24ddd2721d006bbe45f20d3c9f362",
+		"f7a3b47b846119fae5b7866cf5e5b77e",
+	},
+	{
+		"d9b360279694941ac5dbc6987ada7377",
+		"00000000000000000000000000000000",
+		"00000000000000000000000000000000",
+	},
+	{
+		"d9b360279694941ac5dbc6987ada7377",
+		"01000000000000000000000000000000000000000000000040",
+		"eb93b7740962c5e49d2a90a7dc5cec74",
+	},
+	{
+		"d9b360279694941ac5dbc6987ada7377",
+		"01000000000000000000000000000000000000000000000060",
+		"48eb6c6c5a2dbe4a1dde508fee06361b",
+	},
+	{
+		"d9b360279694941ac5dbc6987ada7377",
+		"01000000000000000000000000000000000000000000000080",
+		"20806c26e3c1de019e111255708031d6",
+	},
+	{
+		"d9b360279694941ac5dbc6987ada7377",
+		"010000000000000000000000000000000200000000000000000000000000000000000000000000000001",
+		"ce6edc9a50b36d9a98986bbf6a261c3b",
+	},
+	{
+		"0533fd71f4119257361a3ff1469dd4e5",
+		"489c8fde2be2cf97e74e932d4ed87d00c9882e5386fd9f92ec00000000000000780000000000000048",
+		"bf160bc9ded8c63057d2c38aae552fb4",
+	},
+	{
+		"64779ab10ee8a280272f14cc8851b727",
+		"0da55210cc1c1b0abde3b2f204d1e9f8b06bc47f0000000000000000000000001db2316fd568378da107b52b00000000a00000000000000060",
+		"cc86ee22c861e1fd474c84676b42739c",
+	},
+	{
+		"27c2959ed4daea3b1f52e849478de376",
+		"f37de21c7ff901cfe8a69615a93fdf7a98cad481796245709f0000000000000021702de0de18baa9c9596291b0846600c80000000000000078",
+		"c4fa5e5b713853703bcf8e6424505fa5",
+	},
+	{
+		"670b98154076ddb59b7a9137d0dcc0f0",
+		"9c2159058b1f0fe91433a5bdc20e214eab7fecef4454a10ef0657df21ac70000b202b370ef9768ec6561c4fe6b7e7296fa850000000000000000000000000000f00000000000000090",
+		"4e4108f09f41d797dc9256f8da8d58c7",
+	},
+	{
+		"cb8c3aa3f8dbaeb4b28a3e86ff6625f8",
+		"734320ccc9d9bbbb19cb81b2af4ecbc3e72834321f7aa0f70b7282b4f33df23f16754100000000000000000000000000ced532ce4159b035277d4dfbb7db62968b13cd4eec00000000000000000000001801000000000000a8",
+		"ffd503c7dd712eb3791b7114b17bb0cf",
+	},
+}
+
+func TestPOLYVAL(t *testing.T) {
+	for i, vec := range polyvalVectors {
+		hh, err := hex.DecodeString(vec.h[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, err := hex.DecodeString(vec.x[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		yy, err := hex.DecodeString(vec.y[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var h, y [blockSize]byte
+		copy(h[:], hh)
+
+		Polyval(&y, &h, x)
+		assertEqual(t, i, yy[:], y[:])
+	}
+}
+
+func TestMulImpls(t *testing.T) {
+	var buf [32]byte
+	for i := 0; i < 10000; i++ {
+		if _, err := rand.Read(buf[:]); err != nil {
+			t.Fatal(err)
+		}
+		// Ghash and Polyval only use the multiply selected at compile time,
+		// so check that the other one agrees with it.
+		var k key
+		h1, h0 := binary.BigEndian.Uint64(buf[0:]), binary.BigEndian.Uint64(buf[8:])
+		k.init64(h1, h0)
+		k.init32(h1, h0)
+		y1, y0 := binary.BigEndian.Uint64(buf[16:]), binary.BigEndian.Uint64(buf[24:])
+
+		z1, z0 := mul64(&k, y1, y0)
+		w1, w0 := mul32(&k, y1, y0)
+		if z1 != w1 || z0 != w0 {
+			t.Fatalf("[%d] mul32 != mul64 (%016x%016x != %016x%016x)", i, w1, w0, z1, z0)
+		}
+	}
+}
+
 func assertEqual(t *testing.T, idx int, expected, actual []byte) {
 	if !bytes.Equal(expected, actual) {
 		for i, v := range actual {
@@ -0,0 +1,235 @@
+// Copyright (c) 2017 Yawning Angel <yawning at schwanenlied dot me>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS
+// BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN
+// ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bsaes
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"runtime"
+
+	"github.com/mad-day/Yawning-crypto/bsaes/ghash"
+)
+
+// HCTR2MinSize is the minimum HCTR2 message size in bytes.
+const HCTR2MinSize = BlockSize
+
+// HCTR2 is an instance of the HCTR2 length-preserving tweakable wide-block
+// cipher, as specified in "Length-preserving encryption with HCTR2" by
+// Crowley, Huckleberry and Biggers, and used by Linux's fscrypt for filename
+// encryption.
+//
+// Any change to any bit of the message or tweak changes the entire
+// ciphertext.  There is no authentication, and the same plaintext encrypted
+// with the same tweak will always produce the same ciphertext.
+type HCTR2 struct {
+	b      cipher.Block
+	bulk   bulkECBAble
+	stride int
+
+	h [BlockSize]byte // POLYVAL key, E(K, bin(0))
+	l [BlockSize]byte // E(K, bin(1))
+}
+
+// Encrypt encrypts src under tweak, and places the resulting ciphertext,
+// which is the same length as src, in dst.  The src and dst slices may
+// overlap exactly.  Encrypt will panic if src is shorter than HCTR2MinSize.
+func (c *HCTR2) Encrypt(dst, src, tweak []byte) {
+	var hT, tmp, mm, uu, s [BlockSize]byte
+
+	if len(src) < HCTR2MinSize {
+		panic("bsaes/HCTR2.Encrypt: message too short")
+	}
+	if len(dst) < len(src) {
+		panic("bsaes/HCTR2.Encrypt: dst too short")
+	}
+	m, n := src[:BlockSize], src[BlockSize:]
+	u, v := dst[:BlockSize], dst[BlockSize:len(src)]
+
+	c.hashTweak(&hT, tweak, len(n))
+
+	// MM = M ^ H(T, N), UU = E(K, MM), S = MM ^ UU ^ L
+	c.hashMessage(&tmp, &hT, n)
+	for i := range mm {
+		mm[i] = m[i] ^ tmp[i]
+	}
+	c.b.Encrypt(uu[:], mm[:])
+	for i := range s {
+		s[i] = mm[i] ^ uu[i] ^ c.l[i]
+	}
+
+	// V = N ^ XCTR(K, S), U = UU ^ H(T, V)
+	c.xctr(v, n, &s)
+	c.hashMessage(&tmp, &hT, v)
+	for i := range u {
+		u[i] = uu[i] ^ tmp[i]
+	}
+
+	memwipe(tmp[:])
+	memwipe(mm[:])
+	memwipe(uu[:])
+	memwipe(s[:])
+}
+
+// Decrypt decrypts src under tweak, and places the resulting plaintext,
+// which is the same length as src, in dst.  The src and dst slices may
+// overlap exactly.  Decrypt will panic if src is shorter than HCTR2MinSize.
+func (c *HCTR2) Decrypt(dst, src, tweak []byte) {
+	var hT, tmp, mm, uu, s [BlockSize]byte
+
+	if len(src) < HCTR2MinSize {
+		panic("bsaes/HCTR2.Decrypt: message too short")
+	}
+	if len(dst) < len(src) {
+		panic("bsaes/HCTR2.Decrypt: dst too short")
+	}
+	u, v := src[:BlockSize], src[BlockSize:]
+	m, n := dst[:BlockSize], dst[BlockSize:len(src)]
+
+	c.hashTweak(&hT, tweak, len(v))
+
+	// UU = U ^ H(T, V), MM = D(K, UU), S = MM ^ UU ^ L
+	c.hashMessage(&tmp, &hT, v)
+	for i := range uu {
+		uu[i] = u[i] ^ tmp[i]
+	}
+	c.b.Decrypt(mm[:], uu[:])
+	for i := range s {
+		s[i] = mm[i] ^ uu[i] ^ c.l[i]
+	}
+
+	// N = V ^ XCTR(K, S), M = MM ^ H(T, N)
+	c.xctr(n, v, &s)
+	c.hashMessage(&tmp, &hT, n)
+	for i := range m {
+		m[i] = mm[i] ^ tmp[i]
+	}
+
+	memwipe(tmp[:])
+	memwipe(mm[:])
+	memwipe(uu[:])
+	memwipe(s[:])
+}
+
+// hashTweak absorbs the length block and the (zero padded) tweak into a fresh
+// POLYVAL state.  Both hash invocations in HCTR2 are over messages of the
+// same length with the same tweak, so this prefix is shared.
+func (c *HCTR2) hashTweak(dst *[BlockSize]byte, tweak []byte, msgLen int) {
+	var blk [BlockSize]byte
+
+	// bin(2 * |T| + 2), or bin(2 * |T| + 3) if the message is padded, with
+	// |T| in bits.
+	l := uint64(len(tweak))*8*2 + 2
+	if msgLen%BlockSize != 0 {
+		l++
+	}
+	binary.LittleEndian.PutUint64(blk[:], l)
+
+	memwipe(dst[:])
+	ghash.Polyval(dst, &c.h, blk[:])
+	ghash.Polyval(dst, &c.h, tweak)
+}
+
+// hashMessage finishes H(T, msg) from the state returned by hashTweak.
+func (c *HCTR2) hashMessage(dst, hT *[BlockSize]byte, msg []byte) {
+	var blk [BlockSize]byte
+
+	*dst = *hT
+	full := len(msg) &^ (BlockSize - 1)
+	ghash.Polyval(dst, &c.h, msg[:full])
+	if rem := msg[full:]; len(rem) > 0 {
+		copy(blk[:], rem)
+		blk[len(rem)] = 0x01
+		ghash.Polyval(dst, &c.h, blk[:])
+		memwipe(blk[:])
+	}
+}
+
+// xctr XORs src with the XCTR keystream E(K, S ^ bin(1)), E(K, S ^ bin(2)),
+// ..., and places the result in dst.
+func (c *HCTR2) xctr(dst, src []byte, s *[BlockSize]byte) {
+	buf := make([]byte, c.stride*BlockSize)
+	ctr := uint64(1)
+
+	for len(src) > 0 {
+		for i := 0; i < c.stride; i++ {
+			blk := buf[i*BlockSize : (i+1)*BlockSize]
+			copy(blk, s[:])
+			binary.LittleEndian.PutUint64(blk, binary.LittleEndian.Uint64(s[:])^ctr)
+			ctr++
+		}
+		if c.bulk != nil {
+			c.bulk.BulkEncrypt(buf, buf)
+		} else {
+			c.b.Encrypt(buf, buf)
+		}
+
+		n := len(buf)
+		if len(src) < n {
+			n = len(src)
+		}
+		for i, v := range src[:n] {
+			dst[i] = v ^ buf[i]
+		}
+		dst, src = dst[n:], src[n:]
+	}
+
+	memwipe(buf)
+}
+
+// Reset clears the HCTR2 state such that key material no longer appears in
+// process memory.
+func (c *HCTR2) Reset() {
+	memwipe(c.h[:])
+	memwipe(c.l[:])
+	resetIfAble(c.b)
+}
+
+// NewHCTR2 creates and returns a new HCTR2 instance.  The key argument should
+// be the AES key, either 16, 24, or 32 bytes to select AES-128, AES-192, or
+// AES-256.
+func NewHCTR2(key []byte) (*HCTR2, error) {
+	blk, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return newHCTR2(blk), nil
+}
+
+func newHCTR2(b cipher.Block) *HCTR2 {
+	c := new(HCTR2)
+	c.b = b
+	c.stride = 1
+	if bulk, ok := b.(bulkECBAble); ok {
+		c.bulk = bulk
+		c.stride = bulk.Stride()
+	}
+
+	b.Encrypt(c.h[:], c.h[:])
+	c.l[0] = 1
+	b.Encrypt(c.l[:], c.l[:])
+
+	runtime.SetFinalizer(c, (*HCTR2).Reset)
+
+	return c
+}
@@ -0,0 +1,275 @@
+// hctr2_test.go - HCTR2 tests.
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to hctr2_test.go, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package bsaes
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+// TODO: Add the reference test vectors from https://github.com/google/hctr2.
+// Until then, HCTR2 is checked against refHCTR2, a direct transcription of
+// the definition in the paper that shares no code with the HCTR2 type or the
+// ghash package, and the bitsliced bulk paths are cross-checked against the
+// serial path and crypto/aes.
+
+// refPolyval is POLYVAL computed bit by bit from the definition in RFC 8452,
+// with field elements as little endian 128 bit integers, where bit i is the
+// coefficient of x^i.
+func refPolyval(h, data []byte) []byte {
+	type elem struct{ lo, hi uint64 }
+	load := func(b []byte) elem {
+		return elem{binary.LittleEndian.Uint64(b[0:]), binary.LittleEndian.Uint64(b[8:])}
+	}
+	// x^128 = x^127 + x^126 + x^121 + 1
+	const red = 1<<63 | 1<<62 | 1<<57
+
+	// dot(a, b) = a * b * x^-128 mod x^128 + x^127 + x^126 + x^121 + 1
+	dot := func(a, b elem) elem {
+		var r elem
+		for i := 127; i >= 0; i-- {
+			carry := r.hi >> 63
+			r.hi, r.lo = r.hi<<1|r.lo>>63, r.lo<<1
+			r.hi ^= red & -carry
+			r.lo ^= carry
+
+			bit := b.lo >> uint(i) & 1
+			if i >= 64 {
+				bit = b.hi >> uint(i-64) & 1
+			}
+			r.hi ^= a.hi & -bit
+			r.lo ^= a.lo & -bit
+		}
+		for i := 0; i < 128; i++ {
+			// Add the polynomial if needed so that r is divisible by x,
+			// then divide by x.
+			b0 := r.lo & 1
+			r.lo ^= b0
+			r.hi ^= red & -b0
+			r.hi, r.lo = r.hi>>1|b0<<63, r.lo>>1|r.hi<<63
+		}
+		return r
+	}
+
+	var s elem
+	hh := load(h)
+	for len(data) > 0 {
+		var blk [BlockSize]byte
+		n := copy(blk[:], data)
+		data = data[n:]
+		x := load(blk[:])
+		s = dot(elem{s.lo ^ x.lo, s.hi ^ x.hi}, hh)
+	}
+
+	var out [BlockSize]byte
+	binary.LittleEndian.PutUint64(out[0:], s.lo)
+	binary.LittleEndian.PutUint64(out[8:], s.hi)
+	return out[:]
+}
+
+// refHCTR2 encrypts src with HCTR2, written as literally as possible from
+// the definition in the paper, using crypto/aes and refPolyval, and sharing
+// no code with the HCTR2 type.
+func refHCTR2(key, tweak, src []byte) []byte {
+	blk, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	pad := func(b []byte) []byte {
+		return append(append([]byte{}, b...), make([]byte, (BlockSize-len(b)%BlockSize)%BlockSize)...)
+	}
+	xor := func(dst, a, b []byte) {
+		for i := range dst {
+			dst[i] = a[i] ^ b[i]
+		}
+	}
+
+	// h = E(K, bin(0)), L = E(K, bin(1))
+	var h, l [BlockSize]byte
+	blk.Encrypt(h[:], h[:])
+	l[0] = 1
+	blk.Encrypt(l[:], l[:])
+
+	// H(T, M) = POLYVAL(h, bin(2|T| + 2) || pad(T) || M) if |M| is a
+	// multiple of the block size, and otherwise
+	// POLYVAL(h, bin(2|T| + 3) || pad(T) || pad(M || 1)).
+	hash := func(m []byte) []byte {
+		lenBlk := make([]byte, BlockSize)
+		if len(m)%BlockSize == 0 {
+			binary.LittleEndian.PutUint64(lenBlk, uint64(2*8*len(tweak)+2))
+		} else {
+			binary.LittleEndian.PutUint64(lenBlk, uint64(2*8*len(tweak)+3))
+			m = pad(append(append([]byte{}, m...), 0x01))
+		}
+		in := append(append(lenBlk, pad(tweak)...), m...)
+		return refPolyval(h[:], in)
+	}
+
+	m, n := src[:BlockSize], src[BlockSize:]
+	mm := make([]byte, BlockSize)
+	uu := make([]byte, BlockSize)
+	s := make([]byte, BlockSize)
+	u := make([]byte, BlockSize)
+	v := make([]byte, len(n))
+
+	xor(mm, m, hash(n))
+	blk.Encrypt(uu, mm)
+	xor(s, mm, uu)
+	xor(s, s, l[:])
+
+	// XCTR(K, S)[i] = E(K, S ^ bin(i)), for i = 1, 2, ...
+	for i := 0; i < len(n); i += BlockSize {
+		var ctr, ks [BlockSize]byte
+		binary.LittleEndian.PutUint64(ctr[:], uint64(i/BlockSize+1))
+		xor(ctr[:], ctr[:], s)
+		blk.Encrypt(ks[:], ctr[:])
+		end := i + BlockSize
+		if end > len(n) {
+			end = len(n)
+		}
+		xor(v[i:end], n[i:end], ks[:])
+	}
+
+	xor(u, uu, hash(v))
+
+	return append(u, v...)
+}
+
+func TestHCTR2_Reference(t *testing.T) {
+	// Check refPolyval itself against the example in Appendix A of RFC 8452.
+	h, _ := hex.DecodeString("25629347589242761d31f826ba4b757b")
+	x, _ := hex.DecodeString("4f4f95668c83dfb6401762bb2d01a262d1a24ddd2721d006bbe45f20d3c9f362")
+	y, _ := hex.DecodeString("f7a3b47b846119fae5b7866cf5e5b77e")
+	assertEqual(t, 0, y, refPolyval(h, x))
+
+	key := mustRandBytes(32)
+	msg := mustRandBytes(16*33 + 5)
+	tweak := mustRandBytes(100)
+
+	for _, ksz := range []int{16, 24, 32} {
+		c, err := NewHCTR2(key[:ksz])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Single block, partial final block, and multi-block messages, with
+		// empty, short, block sized and long tweaks.
+		for _, sz := range []int{16, 17, 31, 32, 33, 48, 255, 16 * 33, len(msg)} {
+			for _, tsz := range []int{0, 1, 15, 16, 32, 33, 100} {
+				pt, tw := msg[:sz], tweak[:tsz]
+
+				expected := refHCTR2(key[:ksz], tw, pt)
+				ct := make([]byte, sz)
+				c.Encrypt(ct, pt, tw)
+				assertEqual(t, sz, expected, ct)
+
+				dst := make([]byte, sz)
+				c.Decrypt(dst, ct, tw)
+				assertEqual(t, sz, pt, dst)
+			}
+		}
+	}
+}
+
+func TestHCTR2(t *testing.T) {
+	var key [32]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		t.Fatal(err)
+	}
+	msg := make([]byte, 255)
+	if _, err := rand.Read(msg); err != nil {
+		t.Fatal(err)
+	}
+	tweak := make([]byte, 33)
+	if _, err := rand.Read(tweak); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ksz := range []int{16, 24, 32} {
+		// The serial code path only uses Encrypt/Decrypt, and serves as
+		// the reference for the bulk paths.
+		ref := newHCTR2(serialBlock{newBlock(key[:ksz])})
+
+		for _, impl := range impls {
+			t.Logf("Testing implementation: %v (AES-%d)\n", impl.name, ksz*8)
+
+			c := newHCTR2(impl.ctor(key[:ksz]))
+			for sz := HCTR2MinSize; sz <= len(msg); sz += 7 {
+				for _, tsz := range []int{0, 1, 16, 32, 33} {
+					pt, tw := msg[:sz], tweak[:tsz]
+
+					expected := make([]byte, sz)
+					ref.Encrypt(expected, pt, tw)
+
+					ct := make([]byte, sz)
+					c.Encrypt(ct, pt, tw)
+					assertEqual(t, sz, expected, ct)
+					if bytes.Equal(ct, pt) {
+						t.Fatalf("[%d]: Encrypt is the identity", sz)
+					}
+
+					dst := make([]byte, sz)
+					c.Decrypt(dst, ct, tw)
+					assertEqual(t, sz, pt, dst)
+
+					// In-place, exactly overlapping.
+					copy(dst, pt)
+					c.Encrypt(dst, dst, tw)
+					assertEqual(t, sz, ct, dst)
+					c.Decrypt(dst, dst, tw)
+					assertEqual(t, sz, pt, dst)
+				}
+			}
+		}
+	}
+}
+
+func TestHCTR2_Diffusion(t *testing.T) {
+	c, err := NewHCTR2(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pt := make([]byte, 64)
+	tweak := make([]byte, 32)
+	ct := make([]byte, len(pt))
+	c.Encrypt(ct, pt, tweak)
+
+	// Flipping any bit of the message or the tweak should change every
+	// block of the ciphertext.
+	checkDiffusion := func(pt, tweak []byte, what string) {
+		ct2 := make([]byte, len(pt))
+		c.Encrypt(ct2, pt, tweak)
+		for i := 0; i < len(ct); i += BlockSize {
+			if bytes.Equal(ct[i:i+BlockSize], ct2[i:i+BlockSize]) {
+				t.Fatalf("%s: ciphertext block %d unchanged", what, i/BlockSize)
+			}
+		}
+	}
+
+	pt[len(pt)-1] ^= 0x01
+	checkDiffusion(pt, tweak, "message")
+	pt[len(pt)-1] ^= 0x01
+
+	tweak[0] ^= 0x80
+	checkDiffusion(pt, tweak, "tweak")
+	tweak[0] ^= 0x80
+
+	checkDiffusion(pt, tweak[:31], "tweak length")
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Encrypt accepted a short message")
+		}
+	}()
+	c.Encrypt(ct, pt[:HCTR2MinSize-1], tweak)
+}
@@ -33,7 +33,7 @@ func (m *BlockModesImpl) NewCTR(iv []byte) cipher.Stream {
 		panic("bsaes/NewCTR: iv size does not match block size")
 	}
 
-	return newCTRImpl(ecb, iv)
+	return newCTRImpl(ecb, iv, 1)
 }
 
 type ctrImpl struct {
@@ -42,7 +42,8 @@ type ctrImpl struct {
 	buf []byte
 	idx int
 
-	stride int
+	stride  int
+	workers int
 }
 
 func (c *ctrImpl) Reset() {
@@ -54,6 +55,12 @@ func (c *ctrImpl) Reset() {
 func (c *ctrImpl) XORKeyStream(dst, src []byte) {
 	for len(src) > 0 {
 		if c.idx >= len(c.buf) {
+			n := len(src) - len(src)%len(c.buf)
+			if segSz := parallelSegmentSize(n, c.stride, c.workers); segSz > 0 {
+				c.xorKeyStreamParallel(dst[:n], src[:n], segSz)
+				dst, src = dst[n:], src[n:]
+				continue
+			}
 			c.generateKeyStream()
 			c.idx = 0
 		}
@@ -72,24 +79,31 @@ func (c *ctrImpl) XORKeyStream(dst, src []byte) {
 }
 
 func (c *ctrImpl) generateKeyStream() {
-	for i := 0; i < c.stride; i++ {
-		copy(c.buf[i*blockSize:], c.ctr[:])
+	ctrFill(c.buf, &c.ctr)
+	c.ecb.BulkEncrypt(c.buf, c.buf)
+}
+
+// ctrFill fills buf with successive values of the 128 bit big endian
+// counter ctr, incrementing it once per block.
+func ctrFill(buf []byte, ctr *[blockSize]byte) {
+	for i := 0; i < len(buf); i += blockSize {
+		copy(buf[i:], ctr[:])
 
 		// Increment counter.
 		for j := blockSize; j > 0; j-- {
-			c.ctr[j-1]++
-			if c.ctr[j-1] != 0 {
+			ctr[j-1]++
+			if ctr[j-1] != 0 {
 				break
 			}
 		}
 	}
-	c.ecb.BulkEncrypt(c.buf, c.buf)
 }
 
-func newCTRImpl(ecb bulkECBAble, iv []byte) cipher.Stream {
+func newCTRImpl(ecb bulkECBAble, iv []byte, workers int) cipher.Stream {
 	c := new(ctrImpl)
 	c.ecb = ecb
 	c.stride = ecb.Stride()
+	c.workers = workers
 	copy(c.ctr[:], iv)
 	c.buf = make([]byte, c.stride*blockSize)
 	c.idx = len(c.buf)
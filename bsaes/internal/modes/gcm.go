@@ -33,24 +33,30 @@ import (
 )
 
 const (
-	gcmNonceSize = 96 / 8
-	gcmTagSize   = 16
+	gcmNonceSize  = 96 / 8
+	gcmTagSize    = 16
+	gcmMinTagSize = 12
 )
 
-func (m *BlockModesImpl) NewGCM(size int) (cipher.AEAD, error) {
+func (m *BlockModesImpl) NewGCM(nonceSize, tagSize int) (cipher.AEAD, error) {
 	ecb := m.b.(bulkECBAble)
 	if ecb.BlockSize() != blockSize {
 		return nil, errors.New("bsaes/NewGCM: GCM requires 128 bit block sizes")
 	}
+	if tagSize < gcmMinTagSize || tagSize > gcmTagSize {
+		return nil, errors.New("bsaes/NewGCM: invalid tag size")
+	}
 
-	return newGCMImpl(ecb, size), nil
+	return newGCMImpl(ecb, nonceSize, tagSize, 1), nil
 }
 
 type gcmImpl struct {
 	ecb bulkECBAble
 
 	nonceSize int
+	tagSize   int
 	stride    int
+	workers   int
 }
 
 func (g *gcmImpl) NonceSize() int {
@@ -58,11 +64,16 @@ func (g *gcmImpl) NonceSize() int {
 }
 
 func (g *gcmImpl) Overhead() int {
-	return gcmTagSize
+	return g.tagSize
 }
 
 func (g *gcmImpl) deriveNonceVals(h, j, preCounterBlock *[blockSize]byte, nonce []byte) {
 	g.ecb.Encrypt(h[:], h[:])
+	deriveJ0(j, h, nonce)
+	g.ecb.Encrypt(preCounterBlock[:], j[:])
+}
+
+func deriveJ0(j, h *[blockSize]byte, nonce []byte) {
 	if len(nonce) == gcmNonceSize {
 		copy(j[:], nonce[:gcmNonceSize])
 		j[blockSize-1] = 1
@@ -72,7 +83,17 @@ func (g *gcmImpl) deriveNonceVals(h, j, preCounterBlock *[blockSize]byte, nonce
 		binary.BigEndian.PutUint32(p[12:], uint32(len(nonce))<<3)
 		ghash.Ghash(j, h, p[:])
 	}
-	g.ecb.Encrypt(preCounterBlock[:], j[:])
+}
+
+// ghashAD calculates S = GHASH H (A || 0 v || C || 0 u || [len(A)] 64 ||
+// [len(C)] 64).
+func ghashAD(s, h *[blockSize]byte, additionalData, ciphertext []byte) {
+	var p [blockSize]byte
+	ghash.Ghash(s, h, additionalData)
+	ghash.Ghash(s, h, ciphertext)
+	binary.BigEndian.PutUint32(p[4:], uint32(len(additionalData))<<3)
+	binary.BigEndian.PutUint32(p[12:], uint32(len(ciphertext))<<3)
+	ghash.Ghash(s, h, p[:])
 }
 
 func (g *gcmImpl) gctr(iv *[blockSize]byte, dst, src []byte) {
@@ -117,25 +138,25 @@ func (g *gcmImpl) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
 	if uint64(sz) > 0xfffffffe0 { // len(P) <= 2^39 - 256 (bits)
 		panic("bsaes/gcmImpl.Seal: plaintext too large")
 	}
-	out := make([]byte, sz+gcmTagSize)
+	out := make([]byte, sz+g.tagSize)
 
 	// Define H, block J0, and the pre-counter block.
 	var h, j, preCounterBlock [blockSize]byte
 	g.deriveNonceVals(&h, &j, &preCounterBlock, nonce)
 
-	// Let C=GCTR K(inc32(J0), P).
-	g.gctr(&j, out, plaintext)
+	var s [blockSize]byte
+	if segSz := parallelSegmentSize(sz, g.stride, g.workers); segSz > 0 {
+		g.sealParallel(&s, &h, &j, out[:sz], plaintext, additionalData, segSz)
+	} else {
+		// Let C=GCTR K(inc32(J0), P).
+		g.gctr(&j, out, plaintext)
 
-	// S = GHASH H (A || 0 v || C || 0 u || [len(A)] 64 || [len(C)] 64).
-	var s, p [blockSize]byte
-	ghash.Ghash(&s, &h, additionalData)
-	ghash.Ghash(&s, &h, out[:sz])
-	binary.BigEndian.PutUint32(p[4:], uint32(len(additionalData))<<3)
-	binary.BigEndian.PutUint32(p[12:], uint32(sz)<<3)
-	ghash.Ghash(&s, &h, p[:])
+		// S = GHASH H (A || 0 v || C || 0 u || [len(A)] 64 || [len(C)] 64).
+		ghashAD(&s, &h, additionalData, out[:sz])
+	}
 
 	// Let T = MSB t(GCTR K(J0, S))
-	for i, v := range preCounterBlock {
+	for i, v := range preCounterBlock[:g.tagSize] {
 		out[sz+i] = s[i] ^ v
 	}
 
@@ -151,10 +172,10 @@ func (g *gcmImpl) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, e
 	}
 
 	sz := len(ciphertext)
-	if sz < gcmTagSize {
+	if sz < g.tagSize {
 		return nil, errFail
 	}
-	sz -= gcmTagSize
+	sz -= g.tagSize
 	if uint64(sz) > 0xfffffffe0 {
 		return nil, errFail
 	}
@@ -164,22 +185,27 @@ func (g *gcmImpl) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, e
 	g.deriveNonceVals(&h, &j, &preCounterBlock, nonce)
 
 	// S = GHASH H (A || 0 v || C || 0 u || [len(A)] 64 || [len(C)] 64).
-	var s, p [blockSize]byte
-	ghash.Ghash(&s, &h, additionalData)
-	ghash.Ghash(&s, &h, ciphertext[:sz])
-	binary.BigEndian.PutUint32(p[4:], uint32(len(additionalData))<<3)
-	binary.BigEndian.PutUint32(p[12:], uint32(sz)<<3)
-	ghash.Ghash(&s, &h, p[:])
+	var s [blockSize]byte
+	segSz := parallelSegmentSize(sz, g.stride, g.workers)
+	if segSz > 0 {
+		ghashADParallel(&s, &h, additionalData, ciphertext[:sz], segSz)
+	} else {
+		ghashAD(&s, &h, additionalData, ciphertext[:sz])
+	}
 	for i, v := range preCounterBlock {
 		s[i] ^= v
 	}
 
-	if subtle.ConstantTimeCompare(s[:], ciphertext[sz:]) != 1 {
+	if subtle.ConstantTimeCompare(s[:g.tagSize], ciphertext[sz:]) != 1 {
 		return nil, errFail
 	}
 
 	out := make([]byte, sz)
-	g.gctr(&j, out, ciphertext[:sz])
+	if segSz > 0 {
+		g.gctrParallel(&j, out, ciphertext[:sz], segSz)
+	} else {
+		g.gctr(&j, out, ciphertext[:sz])
+	}
 	dst = append(dst, out...)
 
 	return dst, nil
@@ -190,10 +216,12 @@ func inc32(ctr *[blockSize]byte) {
 	binary.BigEndian.PutUint32(ctr[12:], v)
 }
 
-func newGCMImpl(ecb bulkECBAble, size int) cipher.AEAD {
+func newGCMImpl(ecb bulkECBAble, nonceSize, tagSize, workers int) cipher.AEAD {
 	g := new(gcmImpl)
 	g.ecb = ecb
-	g.nonceSize = size
+	g.nonceSize = nonceSize
+	g.tagSize = tagSize
 	g.stride = g.ecb.Stride()
+	g.workers = workers
 	return g
 }
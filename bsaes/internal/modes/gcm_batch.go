@@ -0,0 +1,204 @@
+// Copyright (c) 2017 Yawning Angel <yawning at schwanenlied dot me>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS
+// BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN
+// ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package modes
+
+import "crypto/subtle"
+
+// SealBatch encrypts and authenticates each plaintexts[i] with nonces[i] and
+// additionalData[i], appending the result to dst[i].  The counter blocks for
+// every packet are packed into shared bulk encryption calls, which is
+// considerably faster than calling Seal for each packet when the packets are
+// small.  dst and additionalData may be nil, otherwise all slices must be the
+// same length.
+func (g *gcmImpl) SealBatch(dst, nonces, plaintexts, additionalData [][]byte) [][]byte {
+	n := len(nonces)
+	if len(plaintexts) != n || (dst != nil && len(dst) != n) || (additionalData != nil && len(additionalData) != n) {
+		panic("bsaes/gcmImpl.SealBatch: mismatched batch lengths")
+	}
+
+	var h [blockSize]byte
+	ks := g.batchKeyStream(&h, nonces, plaintexts, "Seal")
+	defer memwipe(ks)
+
+	ret := make([][]byte, n)
+	for i, plaintext := range plaintexts {
+		var d, ad []byte
+		if dst != nil {
+			d = dst[i]
+		}
+		if additionalData != nil {
+			ad = additionalData[i]
+		}
+
+		// The first keystream block is E(K, J0), the rest are
+		// GCTR K(inc32(J0), P).
+		sz := len(plaintext)
+		ret[i], d = sliceForAppend(d, sz+g.tagSize)
+		for j, v := range plaintext {
+			d[j] = v ^ ks[blockSize+j]
+		}
+
+		var s [blockSize]byte
+		ghashAD(&s, &h, ad, d[:sz])
+		for j, v := range ks[:g.tagSize] {
+			d[sz+j] = s[j] ^ v
+		}
+
+		ks = ks[blocksFor(sz)*blockSize:]
+	}
+
+	return ret
+}
+
+// OpenBatch decrypts and authenticates each ciphertexts[i] with nonces[i] and
+// additionalData[i], appending the result to dst[i].  The returned slices
+// hold the per-packet plaintexts and errors, with a nil plaintext for each
+// packet that failed to authenticate.  dst and additionalData may be nil,
+// otherwise all slices must be the same length.
+func (g *gcmImpl) OpenBatch(dst, nonces, ciphertexts, additionalData [][]byte) ([][]byte, []error) {
+	n := len(nonces)
+	if len(ciphertexts) != n || (dst != nil && len(dst) != n) || (additionalData != nil && len(additionalData) != n) {
+		panic("bsaes/gcmImpl.OpenBatch: mismatched batch lengths")
+	}
+
+	// Malformed packets still get a J0 block so that keystream offsets
+	// stay trivial to track.
+	ret, errs := make([][]byte, n), make([]error, n)
+	bodies := make([][]byte, n)
+	for i, ciphertext := range ciphertexts {
+		sz := len(ciphertext) - g.tagSize
+		if sz < 0 || uint64(sz) > 0xfffffffe0 {
+			errs[i] = errFail
+			continue
+		}
+		bodies[i] = ciphertext[:sz]
+	}
+
+	var h [blockSize]byte
+	ks := g.batchKeyStream(&h, nonces, bodies, "Open")
+	defer memwipe(ks)
+
+	for i, ciphertext := range ciphertexts {
+		var d, ad []byte
+		if dst != nil {
+			d = dst[i]
+		}
+		if additionalData != nil {
+			ad = additionalData[i]
+		}
+
+		body := bodies[i]
+		sz := len(body)
+		pktKs := ks[:blocksFor(sz)*blockSize]
+		ks = ks[len(pktKs):]
+
+		if errs[i] != nil {
+			continue
+		}
+
+		var s [blockSize]byte
+		ghashAD(&s, &h, ad, body)
+		for j, v := range pktKs[:blockSize] {
+			s[j] ^= v
+		}
+		if subtle.ConstantTimeCompare(s[:g.tagSize], ciphertext[sz:]) != 1 {
+			errs[i] = errFail
+			continue
+		}
+
+		ret[i], d = sliceForAppend(d, sz)
+		for j, v := range body {
+			d[j] = v ^ pktKs[blockSize+j]
+		}
+	}
+
+	return ret, errs
+}
+
+// batchKeyStream derives H, and returns the concatenation of E(K, J0) and the
+// GCTR keystream covering each of msgs, padded out to a whole number of
+// strides.  This is the only part of GCM that touches the block cipher, and
+// packing every packet's counter blocks together keeps all of the bitsliced
+// lanes busy.
+func (g *gcmImpl) batchKeyStream(h *[blockSize]byte, nonces, msgs [][]byte, op string) []byte {
+	nBlocks := 0
+	for i, msg := range msgs {
+		if len(nonces[i]) != g.nonceSize {
+			panic("bsaes/gcmImpl." + op + "Batch: nonce with invalid size provided")
+		}
+		if uint64(len(msg)) > 0xfffffffe0 { // len(P) <= 2^39 - 256 (bits)
+			panic("bsaes/gcmImpl." + op + "Batch: message too large")
+		}
+		nBlocks += blocksFor(len(msg))
+	}
+	if r := nBlocks % g.stride; r != 0 {
+		nBlocks += g.stride - r
+	}
+
+	g.ecb.Encrypt(h[:], h[:])
+
+	ks := make([]byte, nBlocks*blockSize)
+	off := 0
+	for i, msg := range msgs {
+		var j [blockSize]byte
+		deriveJ0(&j, h, nonces[i])
+
+		copy(ks[off:], j[:])
+		off += blockSize
+		for n := blocksFor(len(msg)) - 1; n > 0; n-- {
+			inc32(&j)
+			copy(ks[off:], j[:])
+			off += blockSize
+		}
+	}
+
+	strideSz := g.stride * blockSize
+	for off = 0; off < len(ks); off += strideSz {
+		g.ecb.BulkEncrypt(ks[off:off+strideSz], ks[off:off+strideSz])
+	}
+
+	return ks
+}
+
+// blocksFor returns the number of keystream blocks needed to process a sz
+// byte message, including the E(K, J0) block used to mask the tag.
+func blocksFor(sz int) int {
+	return 1 + (sz+blockSize-1)/blockSize
+}
+
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
+
+func memwipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2016 Thomas Pornin <pornin@bolet.org>
+// Copyright (c) 2017 Yawning Angel <yawning at schwanenlied dot me>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS
+// BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN
+// ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package modes
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/mad-day/Yawning-crypto/bsaes/ghash"
+)
+
+// parallelMinSegment is the smallest amount of data in bytes that will be
+// handed to a goroutine, so that the cost of spawning it is amortized.
+const parallelMinSegment = 64 * 1024
+
+// NewParallelCTR returns a CTR mode cipher.Stream that splits large
+// XORKeyStream calls into counter aligned segments processed on up to
+// workers goroutines.  The output is identical to that of NewCTR.
+func (m *BlockModesImpl) NewParallelCTR(iv []byte, workers int) cipher.Stream {
+	ecb := m.b.(bulkECBAble)
+	if len(iv) != ecb.BlockSize() {
+		panic("bsaes/NewParallelCTR: iv size does not match block size")
+	}
+
+	return newCTRImpl(ecb, iv, workers)
+}
+
+// NewParallelGCM returns a GCM cipher.AEAD that splits large messages into
+// counter aligned segments processed on up to workers goroutines, combining
+// the GHASH of each segment via powers of H.  The output is identical to
+// that of NewGCM.
+func (m *BlockModesImpl) NewParallelGCM(nonceSize, tagSize, workers int) (cipher.AEAD, error) {
+	ecb := m.b.(bulkECBAble)
+	if ecb.BlockSize() != blockSize {
+		return nil, errors.New("bsaes/NewParallelGCM: GCM requires 128 bit block sizes")
+	}
+	if tagSize < gcmMinTagSize || tagSize > gcmTagSize {
+		return nil, errors.New("bsaes/NewParallelGCM: invalid tag size")
+	}
+
+	return newGCMImpl(ecb, nonceSize, tagSize, workers), nil
+}
+
+// parallelSegmentSize returns the size of the segments that n bytes should
+// be split into, or 0 if n bytes should be processed serially.  Segments are
+// always a multiple of the stride, so that every segment starts on a counter
+// and keystream buffer boundary.
+func parallelSegmentSize(n, stride, workers int) int {
+	if workers < 2 || n < 2*parallelMinSegment {
+		return 0
+	}
+
+	align := stride * blockSize
+	sz := (n + workers - 1) / workers
+	sz = (sz + align - 1) / align * align
+	if sz < parallelMinSegment {
+		sz = parallelMinSegment
+	}
+	return sz
+}
+
+// forEachSegment calls fn concurrently for each segSz sized segment of n
+// bytes, with the segment index and bounds, and waits for all of the calls
+// to complete.
+func forEachSegment(n, segSz int, fn func(i, off, end int)) {
+	var wg sync.WaitGroup
+	for i, off := 0, 0; off < n; i, off = i+1, off+segSz {
+		end := off + segSz
+		if end > n {
+			end = n
+		}
+		wg.Add(1)
+		go func(i, off, end int) {
+			defer wg.Done()
+			fn(i, off, end)
+		}(i, off, end)
+	}
+	wg.Wait()
+}
+
+func numSegments(n, segSz int) int {
+	return (n + segSz - 1) / segSz
+}
+
+func (c *ctrImpl) xorKeyStreamParallel(dst, src []byte, segSz int) {
+	base := c.ctr
+	forEachSegment(len(src), segSz, func(_, off, end int) {
+		ctr := base
+		add128(&ctr, uint64(off/blockSize))
+
+		buf := make([]byte, len(c.buf))
+		for i := off; i < end; i += len(buf) {
+			ctrFill(buf, &ctr)
+			c.ecb.BulkEncrypt(buf, buf)
+			for j, v := range src[i : i+len(buf)] {
+				dst[i+j] = v ^ buf[j]
+			}
+		}
+		memwipe(buf)
+	})
+	add128(&c.ctr, uint64(len(src)/blockSize))
+}
+
+func (g *gcmImpl) gctrParallel(j *[blockSize]byte, dst, src []byte, segSz int) {
+	forEachSegment(len(src), segSz, func(_, off, end int) {
+		iv := *j
+		add32(&iv, uint32(off/blockSize))
+		g.gctr(&iv, dst[off:end], src[off:end])
+	})
+}
+
+func (g *gcmImpl) sealParallel(s, h, j *[blockSize]byte, dst, plaintext, additionalData []byte, segSz int) {
+	partials := make([][blockSize]byte, numSegments(len(plaintext), segSz))
+	forEachSegment(len(plaintext), segSz, func(i, off, end int) {
+		iv := *j
+		add32(&iv, uint32(off/blockSize))
+		g.gctr(&iv, dst[off:end], plaintext[off:end])
+		ghash.Ghash(&partials[i], h, dst[off:end])
+	})
+	ghashCombine(s, h, additionalData, partials, len(plaintext), segSz)
+}
+
+// ghashADParallel is ghashAD, with the GHASH of the ciphertext calculated
+// concurrently in segSz sized segments.
+func ghashADParallel(s, h *[blockSize]byte, additionalData, ciphertext []byte, segSz int) {
+	partials := make([][blockSize]byte, numSegments(len(ciphertext), segSz))
+	forEachSegment(len(ciphertext), segSz, func(i, off, end int) {
+		ghash.Ghash(&partials[i], h, ciphertext[off:end])
+	})
+	ghashCombine(s, h, additionalData, partials, len(ciphertext), segSz)
+}
+
+// ghashCombine calculates S as per ghashAD, given the GHASH of each segSz
+// sized segment of the ciphertext, each started from zero.  Since GHASH is
+// Horner's rule evaluation of a polynomial in H, the state after a segment
+// of n blocks is the state before it multiplied by H^n, XORed with the
+// GHASH of the segment on its own.
+func ghashCombine(s, h *[blockSize]byte, additionalData []byte, partials [][blockSize]byte, ctLen, segSz int) {
+	var p, hn [blockSize]byte
+
+	ghash.Ghash(s, h, additionalData)
+
+	gfPow(&hn, h, segSz/blockSize)
+	for i := range partials {
+		if i == len(partials)-1 {
+			n := ctLen - i*segSz
+			gfPow(&hn, h, (n+blockSize-1)/blockSize)
+		}
+		gfMul(s, &hn)
+		for k, v := range partials[i] {
+			s[k] ^= v
+		}
+	}
+
+	binary.BigEndian.PutUint32(p[4:], uint32(len(additionalData))<<3)
+	binary.BigEndian.PutUint32(p[12:], uint32(ctLen)<<3)
+	ghash.Ghash(s, h, p[:])
+}
+
+var gfZero [blockSize]byte
+
+// gfMul sets x = x * y in GF(2^128), using the GHASH representation.
+func gfMul(x, y *[blockSize]byte) {
+	ghash.Ghash(x, y, gfZero[:])
+}
+
+// gfPow sets z = h^n in GF(2^128), using the GHASH representation.
+func gfPow(z, h *[blockSize]byte, n int) {
+	var z2 [blockSize]byte
+	for i := range z {
+		z[i] = 0
+	}
+	z[0] = 0x80 // 1
+
+	x := *h
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			gfMul(z, &x)
+		}
+		z2 = x
+		gfMul(&x, &z2)
+	}
+}
+
+// add32 adds n to the 32 bit big endian counter at the end of ctr, modulo
+// 2^32, as with n calls to inc32.
+func add32(ctr *[blockSize]byte, n uint32) {
+	v := binary.BigEndian.Uint32(ctr[12:]) + n
+	binary.BigEndian.PutUint32(ctr[12:], v)
+}
+
+// add128 adds n to the 128 bit big endian counter ctr, modulo 2^128.
+func add128(ctr *[blockSize]byte, n uint64) {
+	lo := binary.BigEndian.Uint64(ctr[8:])
+	hi := binary.BigEndian.Uint64(ctr[:8])
+	sum := lo + n
+	if sum < lo {
+		hi++
+	}
+	binary.BigEndian.PutUint64(ctr[:8], hi)
+	binary.BigEndian.PutUint64(ctr[8:], sum)
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2017 Yawning Angel <yawning at schwanenlied dot me>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS
+// BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN
+// ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package sbox implements the bitsliced byte substitutions of ciphers whose
+// S-box is affine equivalent to the AES S-box, such as SM4 and Camellia, on
+// top of the constant time AES S-box circuits.
+package sbox
+
+import (
+	"encoding/binary"
+
+	"github.com/mad-day/Yawning-crypto/bsaes/ct32"
+	"github.com/mad-day/Yawning-crypto/bsaes/ct64"
+)
+
+// Affine is an affine map over GF(2)^8, applied to bitsliced bytes, where
+// q[i] holds bit i of every byte.  The 32 bit circuit passes its lanes zero
+// extended to 64 bits, and discards the high halves of the results, so the
+// maps may be written as plain boolean circuits.  The state is passed by
+// value so that it does not escape to the heap.
+type Affine func(q [8]uint64) [8]uint64
+
+// Sbox is the S-box S(x) = Out(S_AES(In(x))), where In maps the S-box input
+// to the AES S-box input, and Out maps the AES S-box output to the S-box
+// output.
+type Sbox struct {
+	In, Out Affine
+}
+
+// Apply64 applies the S-box to each of the 64 bytes of b, using the 64 bit
+// bitsliced AES S-box circuit.
+func (s *Sbox) Apply64(b []byte) {
+	var q [8]uint64
+
+	for i := range q {
+		q[i] = binary.LittleEndian.Uint64(b[i*8:])
+	}
+	ct64.Ortho(q[:])
+	q = s.In(q)
+	ct64.Sbox(&q)
+	q = s.Out(q)
+	ct64.Ortho(q[:])
+	for i, v := range q {
+		binary.LittleEndian.PutUint64(b[i*8:], v)
+		q[i] = 0
+	}
+}
+
+// Apply32 applies the S-box to each of the 32 bytes of b, using the 32 bit
+// bitsliced AES S-box circuit.
+func (s *Sbox) Apply32(b []byte) {
+	var q [8]uint32
+	var w [8]uint64
+
+	for i := range q {
+		q[i] = binary.LittleEndian.Uint32(b[i*4:])
+	}
+	ct32.Ortho(q[:])
+	apply32(s.In, &q, &w)
+	ct32.Sbox(&q)
+	apply32(s.Out, &q, &w)
+	ct32.Ortho(q[:])
+	for i, v := range q {
+		binary.LittleEndian.PutUint32(b[i*4:], v)
+		q[i], w[i] = 0, 0
+	}
+}
+
+// apply32 applies the affine map a to the 32 bit wide lanes q, using w as
+// scratch space.
+func apply32(a Affine, q *[8]uint32, w *[8]uint64) {
+	for i, v := range q {
+		w[i] = uint64(v)
+	}
+	*w = a(*w)
+	for i, v := range w {
+		q[i] = uint32(v)
+	}
+}
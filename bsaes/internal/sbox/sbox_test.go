@@ -0,0 +1,100 @@
+// sbox_test.go - Affine equivalent S-box tests.
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to sbox_test.go, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package sbox
+
+import "testing"
+
+func rotl8(x byte, n uint) byte {
+	return (x << n) | (x >> (8 - n))
+}
+
+// aesSbox is the AES S-box, computed from its definition.
+func aesSbox(x byte) byte {
+	mul := func(a, b byte) byte {
+		var p byte
+		for ; b != 0; b >>= 1 {
+			p ^= a & -(b & 1)
+			a = (a << 1) ^ (0x1b & -(a >> 7))
+		}
+		return p
+	}
+
+	// x^-1 = x^254, with 0 mapping to 0.
+	inv := x
+	for i := 0; i < 6; i++ {
+		inv = mul(mul(inv, inv), x)
+	}
+	inv = mul(inv, inv)
+
+	return inv ^ rotl8(inv, 1) ^ rotl8(inv, 2) ^ rotl8(inv, 3) ^ rotl8(inv, 4) ^ 0x63
+}
+
+func identity(q [8]uint64) [8]uint64 {
+	return q
+}
+
+// testIn is x -> (x <<< 1) ^ 0x01.
+func testIn(q [8]uint64) [8]uint64 {
+	q0, q1, q2, q3, q4, q5, q6, q7 := q[0], q[1], q[2], q[3], q[4], q[5], q[6], q[7]
+
+	q[0] = ^q7
+	q[1] = q0
+	q[2] = q1
+	q[3] = q2
+	q[4] = q3
+	q[5] = q4
+	q[6] = q5
+	q[7] = q6
+
+	return q
+}
+
+// testOut is x -> x ^ (x >> 1).
+func testOut(q [8]uint64) [8]uint64 {
+	for i := 0; i < 7; i++ {
+		q[i] ^= q[i+1]
+	}
+	return q
+}
+
+func TestSbox(t *testing.T) {
+	for _, s := range []struct {
+		name string
+		sbox Sbox
+		ref  func(byte) byte
+	}{
+		{"AES", Sbox{identity, identity}, aesSbox},
+		{"Affine", Sbox{testIn, testOut}, func(x byte) byte {
+			y := aesSbox(rotl8(x, 1) ^ 0x01)
+			return y ^ (y >> 1)
+		}},
+	} {
+		for _, v := range []struct {
+			name string
+			fn   func([]byte)
+			n    int
+		}{
+			{"ct32", s.sbox.Apply32, 32},
+			{"ct64", s.sbox.Apply64, 64},
+		} {
+			for base := 0; base < 256; base += v.n {
+				b := make([]byte, v.n)
+				for i := range b {
+					b[i] = byte(base + i)
+				}
+				v.fn(b)
+				for i, y := range b {
+					x := byte(base + i)
+					if expected := s.ref(x); y != expected {
+						t.Fatalf("%s/%s: S(%02x) = %02x, expected %02x", s.name, v.name, x, y, expected)
+					}
+				}
+			}
+		}
+	}
+}
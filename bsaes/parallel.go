@@ -0,0 +1,73 @@
+// Copyright (c) 2017 Yawning Angel <yawning at schwanenlied dot me>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS
+// BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN
+// ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bsaes
+
+import (
+	"crypto/cipher"
+	"runtime"
+)
+
+const (
+	gcmStandardNonceSize = 12
+	gcmTagSize           = 16
+)
+
+type parallelCTRAble interface {
+	NewParallelCTR(iv []byte, workers int) cipher.Stream
+}
+
+type parallelGCMAble interface {
+	NewParallelGCM(nonceSize, tagSize, workers int) (cipher.AEAD, error)
+}
+
+func numWorkers(workers int) int {
+	if workers <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return workers
+}
+
+// NewParallelCTR returns a CTR mode cipher.Stream for b, that splits large
+// XORKeyStream calls into counter aligned segments processed on up to
+// workers goroutines.  If workers is <= 0, runtime.GOMAXPROCS(0) is used.
+// The output is identical to that of `crypto/cipher.NewCTR`, which is used
+// instead if b is not a bitsliced cipher.Block created by NewCipher.
+func NewParallelCTR(b cipher.Block, iv []byte, workers int) cipher.Stream {
+	if p, ok := b.(parallelCTRAble); ok {
+		return p.NewParallelCTR(iv, numWorkers(workers))
+	}
+	return cipher.NewCTR(b, iv)
+}
+
+// NewParallelGCM returns a GCM cipher.AEAD for b with the standard nonce and
+// tag sizes, that splits large messages into counter aligned segments
+// processed on up to workers goroutines, combining the GHASH of each segment
+// via powers of H.  If workers is <= 0, runtime.GOMAXPROCS(0) is used.  The
+// output is identical to that of `crypto/cipher.NewGCM`, which is used
+// instead if b is not a bitsliced cipher.Block created by NewCipher.
+func NewParallelGCM(b cipher.Block, workers int) (cipher.AEAD, error) {
+	if p, ok := b.(parallelGCMAble); ok {
+		return p.NewParallelGCM(gcmStandardNonceSize, gcmTagSize, numWorkers(workers))
+	}
+	return cipher.NewGCM(b)
+}
@@ -0,0 +1,118 @@
+// parallel_test.go - Parallel CTR/GCM tests.
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to parallel_test.go, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package bsaes
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"testing"
+)
+
+var parallelTestSizes = []int{0, 1, 131071, 131072, 131072 + 17, 1<<20 + 5}
+
+func TestParallelCTR(t *testing.T) {
+	key := mustRandBytes(16)
+	refBlk, _ := aes.NewCipher(key)
+
+	for _, impl := range impls {
+		if implIsMasked(impl) {
+			// Far too slow, and the parallel code is shared.
+			continue
+		}
+		t.Logf("Testing implementation: %v\n", impl.name)
+		blk := impl.ctor(key)
+
+		for _, iv := range [][]byte{
+			mustRandBytes(BlockSize),
+			bytes.Repeat([]byte{0xff}, BlockSize),                     // Wraps.
+			append(make([]byte, 8), bytes.Repeat([]byte{0xff}, 8)...), // Carries.
+		} {
+			for _, sz := range parallelTestSizes {
+				for _, workers := range []int{1, 3, 8} {
+					src := mustRandBytes(sz)
+					dst := make([]byte, sz)
+					check := make([]byte, sz)
+
+					cipher.NewCTR(refBlk, iv).XORKeyStream(check, src)
+
+					// Start with a partial block, so that the bulk of the
+					// data is not aligned to the keystream buffer.
+					split := 7
+					if split > sz {
+						split = sz
+					}
+					ctr := NewParallelCTR(blk, iv, workers)
+					ctr.XORKeyStream(dst[:split], src[:split])
+					ctr.XORKeyStream(dst[split:], src[split:])
+					assertEqual(t, sz, check, dst)
+				}
+			}
+		}
+	}
+}
+
+func TestParallelGCM(t *testing.T) {
+	key := mustRandBytes(16)
+	refBlk, _ := aes.NewCipher(key)
+	refGCM, _ := cipher.NewGCM(refBlk)
+
+	for _, impl := range impls {
+		if implIsMasked(impl) {
+			continue
+		}
+		t.Logf("Testing implementation: %v\n", impl.name)
+		blk := impl.ctor(key)
+
+		for _, sz := range parallelTestSizes {
+			for _, workers := range []int{1, 3, 8} {
+				nonce := mustRandBytes(refGCM.NonceSize())
+				pt := mustRandBytes(sz)
+				ad := mustRandBytes(sz % 61)
+
+				g, err := NewParallelGCM(blk, workers)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ct := g.Seal(nil, nonce, pt, ad)
+				assertEqual(t, sz, refGCM.Seal(nil, nonce, pt, ad), ct)
+
+				dst, err := g.Open(nil, nonce, ct, ad)
+				if err != nil {
+					t.Fatalf("[%d]: Open failed: %v", sz, err)
+				}
+				assertEqual(t, sz, pt, dst)
+
+				ct[len(ct)/2] ^= 0x80
+				if _, err = g.Open(nil, nonce, ct, ad); err == nil {
+					t.Fatalf("[%d]: Open succeeded with a corrupted ciphertext", sz)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkParallelGCM(b *testing.B) {
+	blk := nativeImpl.ctor(mustRandBytes(16))
+	nonce := make([]byte, 12)
+	src := mustRandBytes(8 << 20)
+	var dst []byte
+
+	for _, workers := range []int{1, 0} {
+		g, _ := NewParallelGCM(blk, workers)
+		b.Run(fmt.Sprintf("workers_%d", workers), func(b *testing.B) {
+			b.SetBytes(int64(len(src)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				dst = g.Seal(dst[:0], nonce, src, nil)
+			}
+		})
+	}
+	benchOutput = dst
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2017 Yawning Angel <yawning at schwanenlied dot me>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS
+// BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN
+// ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bsaes
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"math/bits"
+	"runtime"
+)
+
+// PMACSize is the size of a PMAC tag in bytes.
+const PMACSize = BlockSize
+
+// PMAC is an instance of the PMAC1 message authentication code, as specified
+// in "A Block-Cipher Mode of Operation for Parallelizable Message
+// Authentication" by Black and Rogaway, and refined in "Efficient
+// Instantiations of Tweakable Blockciphers and Refinements to Modes OCB and
+// PMAC" by Rogaway.  It implements hash.Hash.
+//
+// Unlike CMAC, every full block except the last is processed independently,
+// so the bitsliced implementations encrypt Stride blocks per call.
+type PMAC struct {
+	b      cipher.Block
+	bulk   bulkECBAble
+	stride int
+
+	l    [64][BlockSize]byte // L(i) = L * x^i
+	lInv [BlockSize]byte     // L(-1) = L * x^-1
+
+	offset [BlockSize]byte
+	sum    [BlockSize]byte
+	ctr    uint64
+
+	buf  []byte
+	nBuf int
+}
+
+// Size returns the PMAC tag size in bytes.
+func (p *PMAC) Size() int {
+	return PMACSize
+}
+
+// BlockSize returns the underlying block size in bytes.
+func (p *PMAC) BlockSize() int {
+	return BlockSize
+}
+
+// Reset resets the PMAC to its initial state, ready to authenticate a new
+// message under the same key.
+func (p *PMAC) Reset() {
+	memwipe(p.offset[:])
+	memwipe(p.sum[:])
+	memwipe(p.buf)
+	p.ctr = 0
+	p.nBuf = 0
+}
+
+// Write adds more data to the running MAC.  It never returns an error.
+func (p *PMAC) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		// The final block is handled differently, so a full buffer is
+		// only processed once it is known that more data follows.
+		if p.nBuf == len(p.buf) {
+			p.processBuffer()
+		}
+		c := copy(p.buf[p.nBuf:], b)
+		p.nBuf += c
+		b = b[c:]
+	}
+	return n, nil
+}
+
+func (p *PMAC) processBuffer() {
+	buf := p.buf
+	for i := 0; i < p.stride; i++ {
+		p.ctr++
+		xorBlock(&p.offset, p.l[bits.TrailingZeros64(p.ctr)][:])
+		for j, v := range p.offset {
+			buf[j] ^= v
+		}
+		buf = buf[BlockSize:]
+	}
+
+	if p.bulk != nil {
+		p.bulk.BulkEncrypt(p.buf, p.buf)
+	} else {
+		p.b.Encrypt(p.buf, p.buf)
+	}
+
+	for i := 0; i < len(p.buf); i += BlockSize {
+		xorBlock(&p.sum, p.buf[i:])
+	}
+	p.nBuf = 0
+}
+
+// Sum appends the current PMAC tag to b and returns the resulting slice.  It
+// does not change the underlying MAC state.
+func (p *PMAC) Sum(b []byte) []byte {
+	var tag [PMACSize]byte
+
+	p.sumTo(&tag)
+	b = append(b, tag[:]...)
+	memwipe(tag[:])
+
+	return b
+}
+
+func (p *PMAC) sumTo(tag *[PMACSize]byte) {
+	var offset, sum, tmp [BlockSize]byte
+
+	offset, sum, ctr := p.offset, p.sum, p.ctr
+
+	// Process all but the last buffered block.
+	buf := p.buf[:p.nBuf]
+	for len(buf) > BlockSize {
+		ctr++
+		xorBlock(&offset, p.l[bits.TrailingZeros64(ctr)][:])
+		copy(tmp[:], buf)
+		xorBlock(&tmp, offset[:])
+		p.b.Encrypt(tmp[:], tmp[:])
+		xorBlock(&sum, tmp[:])
+		buf = buf[BlockSize:]
+	}
+
+	// Process the last block, which is either complete, or padded.
+	for i, v := range buf {
+		sum[i] ^= v
+	}
+	if len(buf) == BlockSize {
+		xorBlock(&sum, p.lInv[:])
+	} else {
+		sum[len(buf)] ^= 0x80
+	}
+	p.b.Encrypt(tag[:], sum[:])
+
+	memwipe(offset[:])
+	memwipe(sum[:])
+	memwipe(tmp[:])
+}
+
+// Verify returns true iff tag is the PMAC of the data written so far.  The
+// comparison is done in constant time.
+func (p *PMAC) Verify(tag []byte) bool {
+	var expected [PMACSize]byte
+	defer memwipe(expected[:])
+
+	p.sumTo(&expected)
+	return subtle.ConstantTimeCompare(expected[:], tag) == 1
+}
+
+func (p *PMAC) wipe() {
+	for i := range p.l {
+		memwipe(p.l[i][:])
+	}
+	memwipe(p.lInv[:])
+	p.Reset()
+	resetIfAble(p.b)
+}
+
+// NewPMAC creates and returns a new PMAC instance.  The key argument should
+// be the AES key, either 16, 24, or 32 bytes to select AES-128, AES-192, or
+// AES-256.
+func NewPMAC(key []byte) (*PMAC, error) {
+	blk, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPMAC(blk), nil
+}
+
+// VerifyPMAC returns true iff tag is the PMAC of message under key.  The
+// comparison is done in constant time.
+func VerifyPMAC(key, message, tag []byte) (bool, error) {
+	p, err := NewPMAC(key)
+	if err != nil {
+		return false, err
+	}
+	defer p.wipe()
+
+	p.Write(message)
+	return p.Verify(tag), nil
+}
+
+func newPMAC(b cipher.Block) *PMAC {
+	p := new(PMAC)
+	p.b = b
+	p.stride = 1
+	if bulk, ok := b.(bulkECBAble); ok {
+		p.bulk = bulk
+		p.stride = bulk.Stride()
+	}
+	p.buf = make([]byte, p.stride*BlockSize)
+
+	// L(0) = E(K, 0^n), L(i) = L(i-1) * x, L(-1) = L(0) * x^-1.
+	b.Encrypt(p.l[0][:], p.l[0][:])
+	for i := 1; i < len(p.l); i++ {
+		p.l[i] = p.l[i-1]
+		doubleBlock(&p.l[i])
+	}
+	p.lInv = p.l[0]
+	halveBlock(&p.lInv)
+
+	runtime.SetFinalizer(p, (*PMAC).wipe)
+
+	return p
+}
+
+// halveBlock multiplies p by x^-1 in GF(2^128), and is the inverse of
+// doubleBlock.
+func halveBlock(p *[BlockSize]byte) {
+	lsb := p[BlockSize-1] & 1
+	for i := BlockSize - 1; i > 0; i-- {
+		p[i] = (p[i] >> 1) | (p[i-1] << 7)
+	}
+	p[0] >>= 1
+
+	// x^-1 = x^127 + x^6 + x^1 + x^0 (0x80...43), applied iff lsb is set.
+	mask := -lsb
+	p[0] ^= 0x80 & mask
+	p[BlockSize-1] ^= 0x43 & mask
+}
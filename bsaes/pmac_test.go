@@ -0,0 +1,155 @@
+// pmac_test.go - PMAC tests.
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to pmac_test.go, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package bsaes
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"hash"
+	"strings"
+	"testing"
+)
+
+// The test vectors are from the miscreant project's AES-PMAC vectors, which
+// were generated with the PMAC reference implementation.
+//
+// https://github.com/miscreant/meta/blob/master/vectors/aes_pmac.tjson
+
+var pmacVectors = []struct {
+	key     string
+	message string
+	tag     string
+}{
+	{
+		"000102030405060708090a0b0c0d0e0f",
+		"",
+		"4399572cd6ea5341b8d35876a7098af7",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f",
+		"000102",
+		"256ba5193c1b991b4df0c51f388a9e27",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f",
+		"000102030405060708090a0b0c0d0e0f",
+		"ebbd822fa458daf6dfdad7c27da76338",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f",
+		"000102030405060708090a0b0c0d0e0f10111213",
+		"0412ca150bbf79058d8c75a58c993f55",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f",
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		"e97ac04e9e5e3399ce5355cd7407bc75",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f",
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2021",
+		"5cba7d5eb24f7c86ccc54604e53d5512",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f",
+		strings.Repeat("00", 1000),
+		"c2c9fa1d9985f6f0d2aff915a0e8d910",
+	},
+}
+
+// serialBlock hides the bulk interface of a block cipher, forcing PMAC to
+// process one block at a time.
+type serialBlock struct {
+	cipher.Block
+}
+
+func TestPMAC(t *testing.T) {
+	for _, impl := range impls {
+		t.Logf("Testing implementation: %v\n", impl.name)
+		for i, vec := range pmacVectors {
+			key, err := hex.DecodeString(vec.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, err := hex.DecodeString(vec.message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tag, err := hex.DecodeString(vec.tag)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var h hash.Hash = newPMAC(impl.ctor(key))
+			h.Write(msg)
+			assertEqual(t, i, tag, h.Sum(nil))
+			assertEqual(t, i, tag, h.Sum(nil)) // Sum is idempotent.
+
+			p := h.(*PMAC)
+			if !p.Verify(tag) {
+				t.Fatalf("[%d]: Verify failed", i)
+			}
+			tag[0] ^= 0x01
+			if p.Verify(tag) {
+				t.Fatalf("[%d]: Verify succeeded with a corrupted tag", i)
+			}
+		}
+	}
+}
+
+func TestPMAC_Incremental(t *testing.T) {
+	var key [16]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		t.Fatal(err)
+	}
+	msg := make([]byte, 1024+7)
+	if _, err := rand.Read(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, impl := range impls {
+		t.Logf("Testing implementation: %v\n", impl.name)
+
+		blk := impl.ctor(key[:])
+		ref := newPMAC(serialBlock{blk})
+		p := newPMAC(blk)
+		for sz := 0; sz <= len(msg); sz += 13 {
+			ref.Reset()
+			ref.Write(msg[:sz])
+			expected := ref.Sum(nil)
+
+			for _, chunk := range []int{1, 15, 16, 17, 64, 100} {
+				p.Reset()
+				for off := 0; off < sz; off += chunk {
+					end := off + chunk
+					if end > sz {
+						end = sz
+					}
+					p.Write(msg[off:end])
+				}
+				assertEqual(t, sz, expected, p.Sum(nil))
+			}
+		}
+	}
+
+	ok, err := VerifyPMAC(key[:], msg, newPMAC(newBlock(key[:])).Sum(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatalf("VerifyPMAC succeeded with the tag of a different message")
+	}
+
+	p, _ := NewPMAC(key[:])
+	p.Write(msg)
+	tag := p.Sum(nil)
+	if ok, _ = VerifyPMAC(key[:], msg, tag); !ok {
+		t.Fatalf("VerifyPMAC failed")
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2017 Yawning Angel <yawning at schwanenlied dot me>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS
+// BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN
+// ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package sm4
+
+import "github.com/mad-day/Yawning-crypto/bsaes/internal/sbox"
+
+// sm4Sbox is the SM4 S-box, expressed in terms of the AES S-box.
+var sm4Sbox = sbox.Sbox{In: sboxIn, Out: sboxOut}
+
+// sboxes32 applies the SM4 S-box to each of the 32 bytes of b.
+func sboxes32(b []byte) {
+	sm4Sbox.Apply32(b)
+}
+
+// sboxes64 applies the SM4 S-box to each of the 64 bytes of b.
+func sboxes64(b []byte) {
+	sm4Sbox.Apply64(b)
+}
+
+// sboxIn maps the SM4 S-box input to the AES S-box input.
+func sboxIn(q [8]uint64) [8]uint64 {
+	q0, q1, q2, q3, q4, q5, q6, q7 := q[0], q[1], q[2], q[3], q[4], q[5], q[6], q[7]
+
+	q[0] = ^(q1 ^ q2)
+	q[1] = ^(q0 ^ q1 ^ q2 ^ q4)
+	q[2] = q1 ^ q3
+	q[3] = q0 ^ q2 ^ q4 ^ q5
+	q[4] = q1 ^ q3 ^ q4 ^ q5
+	q[5] = ^(q1 ^ q4 ^ q5 ^ q6)
+	q[6] = q0 ^ q1 ^ q3 ^ q4 ^ q7
+	q[7] = q0 ^ q2 ^ q3
+
+	return q
+}
+
+// sboxOut maps the AES S-box output to the SM4 S-box output.
+func sboxOut(q [8]uint64) [8]uint64 {
+	q0, q1, q2, q3, q4, q5, q6, q7 := q[0], q[1], q[2], q[3], q[4], q[5], q[6], q[7]
+
+	q[0] = ^(q2 ^ q3 ^ q4 ^ q7)
+	q[1] = ^(q1 ^ q3 ^ q4 ^ q5)
+	q[2] = q2 ^ q3 ^ q7
+	q[3] = ^(q2 ^ q6 ^ q7)
+	q[4] = ^(q2 ^ q4 ^ q5 ^ q6)
+	q[5] = ^(q0 ^ q1 ^ q6 ^ q7)
+	q[6] = q0 ^ q5 ^ q6
+	q[7] = q3 ^ q5 ^ q7
+
+	return q
+}
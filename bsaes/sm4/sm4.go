@@ -0,0 +1,239 @@
+// Copyright (c) 2017 Yawning Angel <yawning at schwanenlied dot me>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS
+// BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN
+// ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package sm4 is a constant time SM4 (GB/T 32907-2016) implementation.
+//
+// The SM4 S-box is affine equivalent to the AES S-box, so it is evaluated
+// with the bitsliced AES S-box circuit from the parent package wrapped in the
+// appropriate affine transforms, instead of with table lookups.  The 32 bit
+// or 64 bit circuit is selected at runtime, and processes 8 or 16 blocks at a
+// time via the bulk interface, which allows `crypto/cipher`'s CTR, CBC, and
+// GCM to use the less-slow implementations from the parent package.
+package sm4
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"math"
+	"runtime"
+	"strconv"
+
+	"github.com/mad-day/Yawning-crypto/bsaes/internal/modes"
+)
+
+const (
+	// BlockSize is the SM4 block size in bytes.
+	BlockSize = 16
+
+	// KeySize is the SM4 key size in bytes.
+	KeySize = 16
+
+	numRounds = 32
+	maxStride = 16
+)
+
+var fk = [4]uint32{0xa3b1bac6, 0x56aa3350, 0x677d9197, 0xb27022dc}
+
+var ck = [numRounds]uint32{
+	0x00070e15, 0x1c232a31, 0x383f464d, 0x545b6269,
+	0x70777e85, 0x8c939aa1, 0xa8afb6bd, 0xc4cbd2d9,
+	0xe0e7eef5, 0xfc030a11, 0x181f262d, 0x343b4249,
+	0x50575e65, 0x6c737a81, 0x888f969d, 0xa4abb2b9,
+	0xc0c7ced5, 0xdce3eaf1, 0xf8ff060d, 0x141b2229,
+	0x30373e45, 0x4c535a61, 0x686f767d, 0x848b9299,
+	0xa0a7aeb5, 0xbcc3cad1, 0xd8dfe6ed, 0xf4fb0209,
+	0x10171e25, 0x2c333a41, 0x484f565d, 0x646b7279,
+}
+
+var (
+	sboxes    = sboxes64
+	sboxBytes = 64
+)
+
+// KeySizeError is the error returned when the key is not KeySize bytes.
+type KeySizeError int
+
+func (k KeySizeError) Error() string {
+	return "bsaes/sm4: invalid key size " + strconv.Itoa(int(k))
+}
+
+type block struct {
+	modes.BlockModesImpl
+
+	rk       [numRounds]uint32
+	wasReset bool
+}
+
+func (b *block) BlockSize() int {
+	return BlockSize
+}
+
+// Stride returns the number of blocks that should be passed to BulkEncrypt
+// and BulkDecrypt, which is the number of S-box invocations per round that
+// fill the bitsliced circuit.
+func (b *block) Stride() int {
+	return sboxBytes / 4
+}
+
+func (b *block) Encrypt(dst, src []byte) {
+	if b.wasReset {
+		panic("bsaes/sm4: Encrypt() called after Reset()")
+	}
+	b.crypt(dst, src, 1, false)
+}
+
+func (b *block) Decrypt(dst, src []byte) {
+	if b.wasReset {
+		panic("bsaes/sm4: Decrypt() called after Reset()")
+	}
+	b.crypt(dst, src, 1, true)
+}
+
+func (b *block) BulkEncrypt(dst, src []byte) {
+	if b.wasReset {
+		panic("bsaes/sm4: BulkEncrypt() called after Reset()")
+	}
+	b.crypt(dst, src, b.Stride(), false)
+}
+
+func (b *block) BulkDecrypt(dst, src []byte) {
+	if b.wasReset {
+		panic("bsaes/sm4: BulkDecrypt() called after Reset()")
+	}
+	b.crypt(dst, src, b.Stride(), true)
+}
+
+func (b *block) Reset() {
+	if !b.wasReset {
+		b.wasReset = true
+		for i := range b.rk {
+			b.rk[i] = 0
+		}
+	}
+}
+
+// crypt encrypts or decrypts n blocks, evaluating the round function of
+// every block with a single pass through the bitsliced S-box each round.
+func (b *block) crypt(dst, src []byte, n int, decrypt bool) {
+	var x [maxStride][4]uint32
+	var t [4 * maxStride]byte
+
+	for i := 0; i < n; i++ {
+		s := src[i*BlockSize:]
+		x[i][0] = binary.BigEndian.Uint32(s[0:])
+		x[i][1] = binary.BigEndian.Uint32(s[4:])
+		x[i][2] = binary.BigEndian.Uint32(s[8:])
+		x[i][3] = binary.BigEndian.Uint32(s[12:])
+	}
+
+	for r := 0; r < numRounds; r++ {
+		rk := b.rk[r]
+		if decrypt {
+			rk = b.rk[numRounds-1-r]
+		}
+		for i := 0; i < n; i++ {
+			binary.BigEndian.PutUint32(t[i*4:], x[i][1]^x[i][2]^x[i][3]^rk)
+		}
+		sboxes(t[:sboxBytes])
+		for i := 0; i < n; i++ {
+			v := l(binary.BigEndian.Uint32(t[i*4:]))
+			x[i][0], x[i][1], x[i][2], x[i][3] = x[i][1], x[i][2], x[i][3], x[i][0]^v
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		d := dst[i*BlockSize:]
+		binary.BigEndian.PutUint32(d[0:], x[i][3])
+		binary.BigEndian.PutUint32(d[4:], x[i][2])
+		binary.BigEndian.PutUint32(d[8:], x[i][1])
+		binary.BigEndian.PutUint32(d[12:], x[i][0])
+	}
+
+	for i := range x {
+		x[i] = [4]uint32{}
+	}
+	for i := range t {
+		t[i] = 0
+	}
+}
+
+func rotl(x uint32, n uint) uint32 {
+	return (x << n) | (x >> (32 - n))
+}
+
+// l is the linear transform L of the round function.
+func l(b uint32) uint32 {
+	return b ^ rotl(b, 2) ^ rotl(b, 10) ^ rotl(b, 18) ^ rotl(b, 24)
+}
+
+// lPrime is the linear transform L' of the key schedule.
+func lPrime(b uint32) uint32 {
+	return b ^ rotl(b, 13) ^ rotl(b, 23)
+}
+
+func (b *block) keysched(key []byte) {
+	var k [4]uint32
+	var t [4 * maxStride]byte
+
+	for i := range k {
+		k[i] = binary.BigEndian.Uint32(key[i*4:]) ^ fk[i]
+	}
+	for r := 0; r < numRounds; r++ {
+		binary.BigEndian.PutUint32(t[:], k[1]^k[2]^k[3]^ck[r])
+		sboxes(t[:sboxBytes])
+		rk := k[0] ^ lPrime(binary.BigEndian.Uint32(t[:]))
+		k[0], k[1], k[2], k[3] = k[1], k[2], k[3], rk
+		b.rk[r] = rk
+	}
+
+	k = [4]uint32{}
+	for i := range t {
+		t[i] = 0
+	}
+}
+
+// NewCipher creates and returns a new cipher.Block.  The key argument should
+// be the 16 byte SM4 key.
+func NewCipher(key []byte) (cipher.Block, error) {
+	if len(key) != KeySize {
+		return nil, KeySizeError(len(key))
+	}
+
+	b := new(block)
+	b.keysched(key)
+	b.BlockModesImpl.Init(b)
+	runtime.SetFinalizer(b, (*block).Reset)
+
+	return b, nil
+}
+
+func init() {
+	maxUintptr := uint64(^uintptr(0))
+	switch maxUintptr {
+	case math.MaxUint32:
+		sboxes, sboxBytes = sboxes32, 32
+	case math.MaxUint64:
+		sboxes, sboxBytes = sboxes64, 64
+	default:
+		panic("bsaes/sm4: unsupported architecture")
+	}
+}
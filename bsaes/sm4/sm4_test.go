@@ -0,0 +1,242 @@
+// sm4_test.go - SM4 tests.
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to sm4_test.go, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package sm4
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+var sboxTable = [256]byte{
+	0xd6, 0x90, 0xe9, 0xfe, 0xcc, 0xe1, 0x3d, 0xb7, 0x16, 0xb6, 0x14, 0xc2, 0x28, 0xfb, 0x2c, 0x05,
+	0x2b, 0x67, 0x9a, 0x76, 0x2a, 0xbe, 0x04, 0xc3, 0xaa, 0x44, 0x13, 0x26, 0x49, 0x86, 0x06, 0x99,
+	0x9c, 0x42, 0x50, 0xf4, 0x91, 0xef, 0x98, 0x7a, 0x33, 0x54, 0x0b, 0x43, 0xed, 0xcf, 0xac, 0x62,
+	0xe4, 0xb3, 0x1c, 0xa9, 0xc9, 0x08, 0xe8, 0x95, 0x80, 0xdf, 0x94, 0xfa, 0x75, 0x8f, 0x3f, 0xa6,
+	0x47, 0x07, 0xa7, 0xfc, 0xf3, 0x73, 0x17, 0xba, 0x83, 0x59, 0x3c, 0x19, 0xe6, 0x85, 0x4f, 0xa8,
+	0x68, 0x6b, 0x81, 0xb2, 0x71, 0x64, 0xda, 0x8b, 0xf8, 0xeb, 0x0f, 0x4b, 0x70, 0x56, 0x9d, 0x35,
+	0x1e, 0x24, 0x0e, 0x5e, 0x63, 0x58, 0xd1, 0xa2, 0x25, 0x22, 0x7c, 0x3b, 0x01, 0x21, 0x78, 0x87,
+	0xd4, 0x00, 0x46, 0x57, 0x9f, 0xd3, 0x27, 0x52, 0x4c, 0x36, 0x02, 0xe7, 0xa0, 0xc4, 0xc8, 0x9e,
+	0xea, 0xbf, 0x8a, 0xd2, 0x40, 0xc7, 0x38, 0xb5, 0xa3, 0xf7, 0xf2, 0xce, 0xf9, 0x61, 0x15, 0xa1,
+	0xe0, 0xae, 0x5d, 0xa4, 0x9b, 0x34, 0x1a, 0x55, 0xad, 0x93, 0x32, 0x30, 0xf5, 0x8c, 0xb1, 0xe3,
+	0x1d, 0xf6, 0xe2, 0x2e, 0x82, 0x66, 0xca, 0x60, 0xc0, 0x29, 0x23, 0xab, 0x0d, 0x53, 0x4e, 0x6f,
+	0xd5, 0xdb, 0x37, 0x45, 0xde, 0xfd, 0x8e, 0x2f, 0x03, 0xff, 0x6a, 0x72, 0x6d, 0x6c, 0x5b, 0x51,
+	0x8d, 0x1b, 0xaf, 0x92, 0xbb, 0xdd, 0xbc, 0x7f, 0x11, 0xd9, 0x5c, 0x41, 0x1f, 0x10, 0x5a, 0xd8,
+	0x0a, 0xc1, 0x31, 0x88, 0xa5, 0xcd, 0x7b, 0xbd, 0x2d, 0x74, 0xd0, 0x12, 0xb8, 0xe5, 0xb4, 0xb0,
+	0x89, 0x69, 0x97, 0x4a, 0x0c, 0x96, 0x77, 0x7e, 0x65, 0xb9, 0xf1, 0x09, 0xc5, 0x6e, 0xc6, 0x84,
+	0x18, 0xf0, 0x7d, 0xec, 0x3a, 0xdc, 0x4d, 0x20, 0x79, 0xee, 0x5f, 0x3e, 0xd7, 0xcb, 0x39, 0x48,
+}
+
+// The first vector is from GB/T 32907-2016 Appendix A, the rest were
+// generated with OpenSSL.
+var ecbVectors = []struct {
+	key        string
+	plaintext  string
+	ciphertext string
+}{
+	{
+		"0123456789abcdeffedcba9876543210",
+		"0123456789abcdeffedcba9876543210",
+		"681edf34d206965e86b3e94f536e4246",
+	},
+	{
+		"83f77108d06c605453912b974abb4441",
+		"83d1e6daf6b7872261e5955c92592f1c63039e0fb3dc62be613482d4eea731c1",
+		"b81e3e951cecba4534debc57efff9c78f3d960a32dba44bc2b404f0f16920c5c",
+	},
+	{
+		"aa024265215cf92a4ab5609cd0dbdf44",
+		"512f2cb5f92da0043914cce1b7f7b73a8c76cb5b0099c5cb0ba5da075efe52c0",
+		"d762212b031746640b367cd6833dcd25a4c7a231064a55a3258881aa8671b357",
+	},
+	{
+		"8793901fc9c112f931575d366e005cef",
+		"a47e713552603deaab2ecfc457a12f5e796eed7bd619c4c989dc39f7596e6a69",
+		"3f7686fad17e7fa4ea005b8c62f97efe2ba9e624f2aede142d30b3e0b8cea929",
+	},
+	{
+		"4cf7c780f93cdf1e3aa011c5c493891b",
+		"efd91c27004ca3470aaa42463d184eede1ac123bf060fa83a7686062b8bea096",
+		"f03ae435754fd973f9422eafcec50b47996eabd0c9eb06ec9f8040415697d3e0",
+	},
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic("mustDecodeHex: " + err.Error())
+	}
+	return b
+}
+
+func assertEqual(t *testing.T, idx int, expected, actual []byte) {
+	if !bytes.Equal(expected, actual) {
+		t.Fatalf("[%d]: expected %x, actual %x", idx, expected, actual)
+	}
+}
+
+func TestSbox(t *testing.T) {
+	for _, v := range []struct {
+		name string
+		fn   func([]byte)
+		n    int
+	}{
+		{"ct32", sboxes32, 32},
+		{"ct64", sboxes64, 64},
+	} {
+		for base := 0; base < 256; base += v.n {
+			b := make([]byte, v.n)
+			for i := range b {
+				b[i] = byte(base + i)
+			}
+			v.fn(b)
+			for i, s := range b {
+				if s != sboxTable[base+i] {
+					t.Fatalf("%s: S(%02x) = %02x, expected %02x", v.name, base+i, s, sboxTable[base+i])
+				}
+			}
+		}
+	}
+}
+
+// forEachSboxImpl runs fn with each of the S-box implementations installed,
+// restoring the runtime selected one afterwards.
+func forEachSboxImpl(t *testing.T, fn func(*testing.T)) {
+	oldSboxes, oldSboxBytes := sboxes, sboxBytes
+	defer func() { sboxes, sboxBytes = oldSboxes, oldSboxBytes }()
+
+	sboxes, sboxBytes = sboxes32, 32
+	t.Run("ct32", fn)
+	sboxes, sboxBytes = sboxes64, 64
+	t.Run("ct64", fn)
+}
+
+func TestECB(t *testing.T) {
+	forEachSboxImpl(t, doTestECB)
+}
+
+func doTestECB(t *testing.T) {
+	for i, vec := range ecbVectors {
+		key := mustDecodeHex(vec.key)
+		pt := mustDecodeHex(vec.plaintext)
+		ct := mustDecodeHex(vec.ciphertext)
+
+		b, err := NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dst := make([]byte, len(pt))
+		for off := 0; off < len(pt); off += BlockSize {
+			b.Encrypt(dst[off:], pt[off:])
+		}
+		assertEqual(t, i, ct, dst)
+		for off := 0; off < len(ct); off += BlockSize {
+			b.Decrypt(dst[off:], dst[off:])
+		}
+		assertEqual(t, i, pt, dst)
+	}
+}
+
+func TestECB_1000000(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	// GB/T 32907-2016 Appendix A, example 2.
+	key := mustDecodeHex("0123456789abcdeffedcba9876543210")
+	expected := mustDecodeHex("595298c7c6fd271f0402f804c33d3f66")
+
+	b, _ := NewCipher(key)
+	x := append([]byte{}, key...)
+	for i := 0; i < 1000000; i++ {
+		b.Encrypt(x, x)
+	}
+	assertEqual(t, 0, expected, x)
+}
+
+func TestBulk(t *testing.T) {
+	forEachSboxImpl(t, doTestBulk)
+}
+
+func doTestBulk(t *testing.T) {
+	var key [KeySize]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewCipher(key[:])
+	blk := b.(*block)
+
+	src := make([]byte, blk.Stride()*BlockSize)
+	if _, err := rand.Read(src); err != nil {
+		t.Fatal(err)
+	}
+	dst := make([]byte, len(src))
+	check := make([]byte, len(src))
+
+	for off := 0; off < len(src); off += BlockSize {
+		b.Encrypt(check[off:], src[off:])
+	}
+	blk.BulkEncrypt(dst, src)
+	assertEqual(t, 0, check, dst)
+	blk.BulkDecrypt(dst, dst)
+	assertEqual(t, 0, src, dst)
+}
+
+func TestModes(t *testing.T) {
+	// CTR vector generated with OpenSSL.
+	key := mustDecodeHex("0123456789abcdeffedcba9876543210")
+	iv := mustDecodeHex("000102030405060708090a0b0c0d0e0f")
+	pt := mustDecodeHex("4951054935198bd7bd41949737e4f02c604716c1ffdb3d650b859c0e093ae0d3d4357bab1dc4ef8d")
+	ct := mustDecodeHex("4fc9992808bfe37a97cc6315d64c09460f401b8abf78c164d1148feb883a4dc9c8ef294bd7eb18e3")
+
+	b, _ := NewCipher(key)
+	dst := make([]byte, len(pt))
+	cipher.NewCTR(b, iv).XORKeyStream(dst, pt)
+	assertEqual(t, 0, ct, dst)
+
+	// GCM and CBC must also work, via the bulk interface.
+	g, err := cipher.NewGCM(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, g.NonceSize())
+	sealed := g.Seal(nil, nonce, pt, nil)
+	opened, err := g.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, 0, pt, opened)
+
+	ptCBC := pt[:32]
+	ctCBC := make([]byte, len(ptCBC))
+	cipher.NewCBCEncrypter(b, iv).CryptBlocks(ctCBC, ptCBC)
+	cipher.NewCBCDecrypter(b, iv).CryptBlocks(dst[:32], ctCBC)
+	assertEqual(t, 0, ptCBC, dst[:32])
+
+	if _, err := NewCipher(key[:15]); err == nil {
+		t.Fatalf("NewCipher accepted an invalid key")
+	}
+}
+
+var benchOutput []byte
+
+func BenchmarkCTR(b *testing.B) {
+	var key, iv [16]byte
+	blk, _ := NewCipher(key[:])
+	buf := make([]byte, 16384)
+
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cipher.NewCTR(blk, iv[:]).XORKeyStream(buf, buf)
+	}
+	benchOutput = buf
+}
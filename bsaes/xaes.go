@@ -0,0 +1,150 @@
+// Copyright (c) 2017 Yawning Angel <yawning at schwanenlied dot me>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS
+// BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN
+// ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bsaes
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"runtime"
+)
+
+const (
+	// XAES256GCMKeySize is the XAES-256-GCM key size in bytes.
+	XAES256GCMKeySize = 32
+
+	// XAES256GCMNonceSize is the XAES-256-GCM nonce size in bytes.
+	XAES256GCMNonceSize = 24
+
+	xaesLabel = 0x58 // 'X'
+)
+
+type xaes256GCM struct {
+	b    cipher.Block
+	k1   [BlockSize]byte
+	ctor func([]byte) cipher.Block
+}
+
+func (x *xaes256GCM) Reset() {
+	memwipe(x.k1[:])
+	resetIfAble(x.b)
+}
+
+func (x *xaes256GCM) NonceSize() int {
+	return XAES256GCMNonceSize
+}
+
+func (x *xaes256GCM) Overhead() int {
+	return 16
+}
+
+// deriveKey derives the per-nonce AES-256 key via the NIST SP 800-108r1
+// counter mode KDF, instantiated with CMAC-AES-256.  Since both KDF inputs
+// are exactly one block long, CMAC collapses to E(K, M ^ K1).
+func (x *xaes256GCM) deriveKey(key *[XAES256GCMKeySize]byte, nonce []byte) {
+	var m [BlockSize]byte
+
+	m[2] = xaesLabel
+	copy(m[4:], nonce[:12])
+	for i, v := range x.k1 {
+		m[i] ^= v
+	}
+
+	m[1] ^= 0x01
+	x.b.Encrypt(key[:BlockSize], m[:])
+	m[1] ^= 0x01 ^ 0x02
+	x.b.Encrypt(key[BlockSize:], m[:])
+
+	memwipe(m[:])
+}
+
+func (x *xaes256GCM) newGCM(nonce []byte) (cipher.Block, cipher.AEAD) {
+	var key [XAES256GCMKeySize]byte
+	defer memwipe(key[:])
+
+	x.deriveKey(&key, nonce)
+	blk := x.ctor(key[:])
+	aead, err := cipher.NewGCM(blk)
+	if err != nil {
+		panic("bsaes/xaes256GCM: failed to initialize GCM: " + err.Error())
+	}
+
+	return blk, aead
+}
+
+func (x *xaes256GCM) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != XAES256GCMNonceSize {
+		panic("bsaes/xaes256GCM.Seal: nonce with invalid size provided")
+	}
+
+	blk, aead := x.newGCM(nonce)
+	defer resetIfAble(blk)
+
+	return aead.Seal(dst, nonce[12:], plaintext, additionalData)
+}
+
+func (x *xaes256GCM) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != XAES256GCMNonceSize {
+		panic("bsaes/xaes256GCM.Open: nonce with invalid size provided")
+	}
+
+	blk, aead := x.newGCM(nonce)
+	defer resetIfAble(blk)
+
+	return aead.Open(dst, nonce[12:], ciphertext, additionalData)
+}
+
+// NewXAES256GCM creates and returns a new XAES-256-GCM cipher.AEAD, as
+// specified in https://c2sp.org/XAES-256-GCM.  The key argument must be 32
+// bytes long.
+//
+// XAES-256-GCM takes a 24 byte nonce, which is large enough to be generated
+// at random for an effectively unlimited number of messages under one key.
+func NewXAES256GCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != XAES256GCMKeySize {
+		return nil, aes.KeySizeError(len(key))
+	}
+
+	return newXAES256GCM(key, newBlock), nil
+}
+
+func newXAES256GCM(key []byte, ctor func([]byte) cipher.Block) cipher.AEAD {
+	x := new(xaes256GCM)
+	x.ctor = ctor
+	x.b = ctor(key)
+	x.b.Encrypt(x.k1[:], x.k1[:])
+	doubleBlock(&x.k1)
+
+	runtime.SetFinalizer(x, (*xaes256GCM).Reset)
+
+	return x
+}
+
+// doubleBlock multiplies p by x in GF(2^128), using the big endian
+// convention shared by CMAC, EAX and PMAC.
+func doubleBlock(p *[BlockSize]byte) {
+	msb := p[0] >> 7
+	for i := 0; i < BlockSize-1; i++ {
+		p[i] = (p[i] << 1) | (p[i+1] >> 7)
+	}
+	p[BlockSize-1] = (p[BlockSize-1] << 1) ^ (0x87 & -msb)
+}
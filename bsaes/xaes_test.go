@@ -0,0 +1,130 @@
+// xaes_test.go - XAES-256-GCM tests.
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to xaes_test.go, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package bsaes
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// The test vectors are from the C2SP specification.
+//
+// https://c2sp.org/XAES-256-GCM
+
+var xaesVectors = []struct {
+	key        byte
+	nonce      string
+	plaintext  string
+	ad         string
+	ciphertext string
+}{
+	{
+		0x01,
+		"ABCDEFGHIJKLMNOPQRSTUVWX",
+		"XAES-256-GCM",
+		"",
+		"ce546ef63c9cc60765923609b33a9a1974e96e52daf2fcf7075e2271",
+	},
+	{
+		0x03,
+		"ABCDEFGHIJKLMNOPQRSTUVWX",
+		"XAES-256-GCM",
+		"c2sp.org/XAES-256-GCM",
+		"986ec1832593df5443a179437fd083bf3fdb41abd740a21f71eb769d",
+	},
+}
+
+func TestXAES256GCM(t *testing.T) {
+	for _, impl := range impls {
+		t.Logf("Testing implementation: %v\n", impl.name)
+		for i, vec := range xaesVectors {
+			ct, err := hex.DecodeString(vec.ciphertext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			key := bytes.Repeat([]byte{vec.key}, XAES256GCMKeySize)
+			nonce, pt, ad := []byte(vec.nonce), []byte(vec.plaintext), []byte(vec.ad)
+
+			x := newXAES256GCM(key, impl.ctor)
+			dst := x.Seal(nil, nonce, pt, ad)
+			assertEqual(t, i, ct, dst)
+
+			dst, err = x.Open(nil, nonce, ct, ad)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertEqual(t, i, pt, dst)
+
+			ct[0] ^= 0x80
+			if _, err = x.Open(nil, nonce, ct, ad); err == nil {
+				t.Fatalf("[%d] Open succeeded with a corrupted ciphertext", i)
+			}
+		}
+	}
+
+	if _, err := NewXAES256GCM(make([]byte, 16)); err == nil {
+		t.Fatalf("NewXAES256GCM accepted a 128 bit key")
+	}
+}
+
+func TestXAES256GCM_Accumulated(t *testing.T) {
+	const (
+		iterations = 10000
+		expected   = "e6b9edf2df6cec60c8cbd864e2211b597fb69a529160cd040d56c0c210081939"
+	)
+
+	for _, impl := range impls {
+		if testing.Short() && !implIsNative(impl) {
+			continue
+		}
+		if implIsMasked(impl) {
+			// Far too slow, and covered by the known answer tests.
+			continue
+		}
+		t.Logf("Testing implementation: %v\n", impl.name)
+
+		s, d := sha3.NewShake128(), sha3.NewShake128()
+		for i := 0; i < iterations; i++ {
+			var l [1]byte
+
+			key := make([]byte, XAES256GCMKeySize)
+			s.Read(key)
+			nonce := make([]byte, XAES256GCMNonceSize)
+			s.Read(nonce)
+			s.Read(l[:])
+			pt := make([]byte, int(l[0]))
+			s.Read(pt)
+			s.Read(l[:])
+			ad := make([]byte, int(l[0]))
+			s.Read(ad)
+
+			x := newXAES256GCM(key, impl.ctor)
+			ct := x.Seal(nil, nonce, pt, ad)
+			dst, err := x.Open(nil, nonce, ct, ad)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertEqual(t, i, pt, dst)
+
+			d.Write(ct)
+		}
+
+		assertEqual(t, 0, mustDecodeHex(expected), d.Sum(nil))
+	}
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic("mustDecodeHex: " + err.Error())
+	}
+	return b
+}
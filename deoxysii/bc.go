@@ -0,0 +1,134 @@
+// bc.go - Deoxys-BC-384 tweakable block cipher
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to the software, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package deoxysii
+
+import "github.com/mad-day/Yawning-crypto/bsaes/ct64"
+
+const (
+	bcRounds = 16
+	stkCount = bcRounds + 1
+	stkSize  = 16
+
+	// bcStride is the number of blocks that are processed in parallel,
+	// each with their own tweak.
+	bcStride = 4
+)
+
+var (
+	rcons = [stkCount]byte{
+		0x2f, 0x5e, 0xbc, 0x63, 0xc6, 0x97, 0x35, 0x6a,
+		0xd4, 0xb3, 0x7d, 0xfa, 0xef, 0xc5, 0x91, 0x39,
+		0x72,
+	}
+
+	// The tweakey schedule's byte permutation.
+	hPerm = [stkSize]byte{1, 6, 11, 12, 5, 10, 15, 0, 9, 14, 3, 4, 13, 2, 7, 8}
+)
+
+// bcKey is the key dependent half of the Deoxys-BC-384 tweakey schedule,
+// TK2 ^ TK3 ^ RC for each round.  The tweak (TK1) is mixed in on the fly.
+type bcKey [stkCount][stkSize]byte
+
+func (k *bcKey) init(key []byte) {
+	var tk2, tk3 [stkSize]byte
+
+	copy(tk2[:], key[16:32])
+	copy(tk3[:], key[0:16])
+
+	for i := range k {
+		for j := range k[i] {
+			k[i][j] = tk2[j] ^ tk3[j]
+		}
+		k[i][0] ^= 1
+		k[i][1] ^= 2
+		k[i][2] ^= 4
+		k[i][3] ^= 8
+		k[i][4] ^= rcons[i]
+		k[i][5] ^= rcons[i]
+		k[i][6] ^= rcons[i]
+		k[i][7] ^= rcons[i]
+
+		h(&tk2)
+		h(&tk3)
+		for j := range tk2 {
+			tk2[j] = lfsr2(tk2[j])
+			tk3[j] = lfsr3(tk3[j])
+		}
+	}
+
+	burnBytes(tk2[:])
+	burnBytes(tk3[:])
+}
+
+func (k *bcKey) reset() {
+	for i := range k {
+		burnBytes(k[i][:])
+	}
+}
+
+// encrypt4 encrypts the bcStride blocks in src with the corresponding
+// tweaks, and writes the ciphertexts to dst.
+func (k *bcKey) encrypt4(dst, src, tweaks *[bcStride][stkSize]byte) {
+	var tk1, stk [bcStride][stkSize]byte
+	var q, skey [8]uint64
+
+	tk1 = *tweaks
+	ct64.Load16xU32(&q, src[0][:], src[1][:], src[2][:], src[3][:])
+	for i := 0; i < stkCount; i++ {
+		for j := range stk {
+			for l := range stk[j] {
+				stk[j][l] = k[i][l] ^ tk1[j][l]
+			}
+			h(&tk1[j])
+		}
+		ct64.Load16xU32(&skey, stk[0][:], stk[1][:], stk[2][:], stk[3][:])
+
+		if i > 0 {
+			ct64.Sbox(&q)
+			ct64.ShiftRows(&q)
+			ct64.MixColumns(&q)
+		}
+		ct64.AddRoundKey(&q, skey[:])
+	}
+	ct64.Store16xU32(dst[0][:], dst[1][:], dst[2][:], dst[3][:], &q)
+
+	for j := range tk1 {
+		burnBytes(tk1[j][:])
+		burnBytes(stk[j][:])
+	}
+	burnUint64s(q[:])
+	burnUint64s(skey[:])
+}
+
+// h applies the tweakey schedule's byte permutation to tk.
+func h(tk *[stkSize]byte) {
+	t := *tk
+	for i, v := range hPerm {
+		tk[i] = t[v]
+	}
+}
+
+func lfsr2(x byte) byte {
+	return x<<1 | (x>>7^x>>5)&1
+}
+
+func lfsr3(x byte) byte {
+	return x>>1 | (x<<7^x<<1)&0x80
+}
+
+func burnBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+func burnUint64s(b []uint64) {
+	for i := range b {
+		b[i] = 0
+	}
+}
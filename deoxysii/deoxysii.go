@@ -0,0 +1,260 @@
+// deoxysii.go - Deoxys-II-256-128
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to the software, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+// Package deoxysii implements the Deoxys-II-256-128 nonce-misuse resistant
+// Authenticated Cipher.
+//
+// This implementation is derived from the Deoxys v1.41 specification by
+// Jérémy Jean, Ivica Nikolić, Thomas Peyrin, and Yannick Seurin.  The
+// underlying Deoxys-BC-384 tweakable block cipher is built on the
+// constant time bitsliced AES round function from the bsaes package.
+package deoxysii
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// KeySize is the size of a key in bytes.
+	KeySize = 32
+
+	// NonceSize is the size of a nonce in bytes.
+	NonceSize = 15
+
+	// TagSize is the size of an authentication tag in bytes.
+	TagSize = 16
+
+	// Version is the version of the Deoxys specification implemented.
+	Version = "1.41"
+
+	blockSize = 16
+
+	prefixADBlock  = 0x2
+	prefixADFinal  = 0x6
+	prefixMsgBlock = 0x0
+	prefixMsgFinal = 0x4
+	prefixTag      = 0x1
+)
+
+var (
+	// ErrInvalidKeySize is the error thrown via a panic when a key is an
+	// invalid size.
+	ErrInvalidKeySize = errors.New("deoxysii: invalid key size")
+
+	// ErrInvalidNonceSize is the error thrown via a panic when a nonce is
+	// an invalid size.
+	ErrInvalidNonceSize = errors.New("deoxysii: invalid nonce size")
+
+	// ErrOpen is the error returned when the message authentication fails
+	// during an Open call.
+	ErrOpen = errors.New("deoxysii: message authentication failed")
+)
+
+// AEAD is a Deoxys-II-256-128 instance, implementing crypto/cipher.AEAD.
+type AEAD struct {
+	key bcKey
+}
+
+// NonceSize returns the size of the nonce that must be passed to Seal and
+// Open.
+func (ae *AEAD) NonceSize() int {
+	return NonceSize
+}
+
+// Overhead returns the maximum difference between the lengths of a plaintext
+// and its ciphertext.
+func (ae *AEAD) Overhead() int {
+	return TagSize
+}
+
+// Seal encrypts and authenticates plaintext, authenticates the
+// additional data and appends the result to dst, returning the updated
+// slice. The nonce must be NonceSize() bytes long and should be unique
+// for all time, for a given key, however Deoxys-II only loses privacy
+// for repeated messages when a nonce is reused.
+//
+// The plaintext and dst must overlap exactly or not at all. To reuse
+// plaintext's storage for the encrypted output, use plaintext[:0] as dst.
+func (ae *AEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	var tag [TagSize]byte
+
+	if len(nonce) != NonceSize {
+		panic(ErrInvalidNonceSize)
+	}
+
+	ret, out := sliceForAppend(dst, len(plaintext)+TagSize)
+	ae.authenticate(&tag, nonce, plaintext, additionalData)
+	ae.xorKeyStream(out, plaintext, &tag, nonce)
+	copy(out[len(plaintext):], tag[:])
+
+	return ret
+}
+
+// Open decrypts and authenticates ciphertext, authenticates the
+// additional data and, if successful, appends the resulting plaintext
+// to dst, returning the updated slice. The nonce must be NonceSize()
+// bytes long and both it and the additional data must match the
+// value passed to Seal.
+//
+// The ciphertext and dst must overlap exactly or not at all. To reuse
+// ciphertext's storage for the decrypted output, use ciphertext[:0] as dst.
+//
+// Even if the function fails, the contents of dst, up to its capacity,
+// may be overwritten.
+func (ae *AEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	var tag, expectedTag [TagSize]byte
+
+	if len(nonce) != NonceSize {
+		panic(ErrInvalidNonceSize)
+	}
+	if len(ciphertext) < TagSize {
+		return nil, ErrOpen
+	}
+
+	ctLen := len(ciphertext) - TagSize
+	copy(tag[:], ciphertext[ctLen:])
+	ret, out := sliceForAppend(dst, ctLen)
+	ae.xorKeyStream(out, ciphertext[:ctLen], &tag, nonce)
+	ae.authenticate(&expectedTag, nonce, out, additionalData)
+
+	if subtle.ConstantTimeCompare(tag[:], expectedTag[:]) != 1 {
+		burnBytes(out)
+		return nil, ErrOpen
+	}
+
+	return ret, nil
+}
+
+// Reset securely purges stored sensitive data from the AEAD instance.
+func (ae *AEAD) Reset() {
+	ae.key.reset()
+}
+
+// authenticate computes the tag over the additional data and message.
+func (ae *AEAD) authenticate(tag *[TagSize]byte, nonce, msg, ad []byte) {
+	var auth [blockSize]byte
+	var tweaks, blocks [bcStride][stkSize]byte
+
+	ae.absorb(&auth, prefixADBlock, prefixADFinal, ad)
+	ae.absorb(&auth, prefixMsgBlock, prefixMsgFinal, msg)
+
+	tweaks[0][0] = prefixTag << 4
+	copy(tweaks[0][1:], nonce)
+	blocks[0] = auth
+	ae.key.encrypt4(&blocks, &blocks, &tweaks)
+	copy(tag[:], blocks[0][:])
+
+	burnBytes(auth[:])
+	burnBytes(blocks[0][:])
+}
+
+// absorb xors the encryption of each block of src into auth, tweaked with
+// the block index and prefixBlock, or prefixFinal for the padded final
+// partial block.
+func (ae *AEAD) absorb(auth *[blockSize]byte, prefixBlock, prefixFinal byte, src []byte) {
+	var tweaks, blocks [bcStride][stkSize]byte
+
+	var i uint64
+	for len(src) > 0 {
+		n := 0
+		for ; n < bcStride && len(src) > 0; n++ {
+			prefix := prefixBlock
+			if len(src) < blockSize {
+				prefix = prefixFinal
+				blocks[n] = [stkSize]byte{}
+				copy(blocks[n][:], src)
+				blocks[n][len(src)] = 0x80
+				src = src[len(src):]
+			} else {
+				copy(blocks[n][:], src[:blockSize])
+				src = src[blockSize:]
+			}
+			encodeTweak(&tweaks[n], prefix, i)
+			i++
+		}
+		ae.key.encrypt4(&blocks, &blocks, &tweaks)
+		for j := 0; j < n; j++ {
+			xorBytes(auth[:], auth[:], blocks[j][:])
+		}
+	}
+
+	for j := range blocks {
+		burnBytes(blocks[j][:])
+	}
+}
+
+// xorKeyStream xors src with the keystream derived from tag and nonce, and
+// writes the result to dst.
+func (ae *AEAD) xorKeyStream(dst, src []byte, tag *[TagSize]byte, nonce []byte) {
+	var tweaks, blocks [bcStride][stkSize]byte
+	var nonceBlock [stkSize]byte
+
+	copy(nonceBlock[1:], nonce)
+
+	var i uint64
+	for len(src) > 0 {
+		for j := range tweaks {
+			tweaks[j] = *tag
+			tweaks[j][0] |= 0x80
+			ctr := binary.BigEndian.Uint64(tweaks[j][8:]) ^ (i + uint64(j))
+			binary.BigEndian.PutUint64(tweaks[j][8:], ctr)
+			blocks[j] = nonceBlock
+		}
+		ae.key.encrypt4(&blocks, &blocks, &tweaks)
+		for j := 0; j < bcStride && len(src) > 0; j++ {
+			n := xorBytes(dst, src, blocks[j][:])
+			dst, src = dst[n:], src[n:]
+		}
+		i += bcStride
+	}
+
+	for j := range blocks {
+		burnBytes(blocks[j][:])
+	}
+}
+
+func encodeTweak(tweak *[stkSize]byte, prefix byte, i uint64) {
+	*tweak = [stkSize]byte{}
+	tweak[0] = prefix << 4
+	binary.BigEndian.PutUint64(tweak[8:], i)
+}
+
+func xorBytes(dst, a, b []byte) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		dst[i] = a[i] ^ b[i]
+	}
+	return n
+}
+
+// New returns a new keyed Deoxys-II-256-128 instance.
+func New(key []byte) *AEAD {
+	if len(key) != KeySize {
+		panic(ErrInvalidKeySize)
+	}
+
+	ae := new(AEAD)
+	ae.key.init(key)
+	return ae
+}
+
+// Shamelessly stolen from the Go runtime library.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
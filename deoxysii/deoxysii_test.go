@@ -0,0 +1,265 @@
+// deoxysii_test.go - Deoxys-II-256-128 tests
+//
+// To the extent possible under law, Yawning Angel has waived all copyright
+// and related or neighboring rights to the software, using the Creative
+// Commons "CC0" public domain dedication. See LICENSE or
+// <http://creativecommons.org/publicdomain/zero/1.0/> for full details.
+
+package deoxysii
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var _ cipher.AEAD = (*AEAD)(nil)
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic("mustDecodeHex: " + err.Error())
+	}
+	return b
+}
+
+// The designers' test vectors from the reference implementation, covering
+// AD only, message only, and both, with full and partial final blocks.
+type katVector struct {
+	Name           string
+	Key            string
+	Nonce          string
+	AssociatedData string
+	Message        string
+	Sealed         string
+}
+
+func TestKAT(t *testing.T) {
+	var vectors []katVector
+
+	f, err := os.Open(filepath.Join("testdata", "Deoxys-II-256-128-official-20190608.json"))
+	if err != nil {
+		t.Fatalf("Failed to open test vectors: %v", err)
+	}
+	defer f.Close()
+	if err = json.NewDecoder(f).Decode(&vectors); err != nil {
+		t.Fatalf("Failed to parse test vectors: %v", err)
+	}
+	if len(vectors) == 0 {
+		t.Fatalf("No test vectors")
+	}
+
+	for _, v := range vectors {
+		key := mustDecodeHex(v.Key)
+		nonce := mustDecodeHex(v.Nonce)
+		ad := mustDecodeHex(v.AssociatedData)
+		msg := mustDecodeHex(v.Message)
+		expected := mustDecodeHex(v.Sealed)
+
+		ae := New(key)
+		sealed := ae.Seal(nil, nonce, msg, ad)
+		if !bytes.Equal(expected, sealed) {
+			t.Fatalf("%s: Seal: expected %x, actual %x", v.Name, expected, sealed)
+		}
+		opened, err := ae.Open(nil, nonce, sealed, ad)
+		if err != nil {
+			t.Fatalf("%s: Open: %v", v.Name, err)
+		}
+		if !bytes.Equal(msg, opened) {
+			t.Fatalf("%s: Open: expected %x, actual %x", v.Name, msg, opened)
+		}
+
+		sealed[len(sealed)-1] ^= 0x01
+		if _, err = ae.Open(nil, nonce, sealed, ad); err == nil {
+			t.Fatalf("%s: Open: accepted a corrupted tag", v.Name)
+		}
+	}
+}
+
+// refSbox is the AES S-box, computed the long way.
+func refSbox(x byte) byte {
+	inv := byte(0)
+	for y := 1; y < 256 && x != 0; y++ {
+		if gfMul(x, byte(y)) == 1 {
+			inv = byte(y)
+			break
+		}
+	}
+	s := inv
+	for i := uint(1); i < 5; i++ {
+		s ^= inv<<i | inv>>(8-i)
+	}
+	return s ^ 0x63
+}
+
+func gfMul(a, b byte) byte {
+	var p byte
+	for b != 0 {
+		if b&1 != 0 {
+			p ^= a
+		}
+		hi := a & 0x80
+		a <<= 1
+		if hi != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+	return p
+}
+
+// refEncrypt is a byte oriented Deoxys-BC-384 implementation, used to check
+// the bitsliced one.
+func refEncrypt(key []byte, tweak, src *[stkSize]byte) [stkSize]byte {
+	var tk1, tk2, tk3, s [stkSize]byte
+	copy(tk2[:], key[16:32])
+	copy(tk3[:], key[0:16])
+	tk1, s = *tweak, *src
+
+	for i := 0; i < stkCount; i++ {
+		if i > 0 {
+			var t [stkSize]byte
+			for j := range s {
+				t[j] = refSbox(s[j])
+			}
+			for c := 0; c < 4; c++ { // ShiftRows
+				for r := 0; r < 4; r++ {
+					s[c*4+r] = t[((c+r)%4)*4+r]
+				}
+			}
+			for c := 0; c < 4; c++ { // MixColumns
+				a0, a1, a2, a3 := s[c*4], s[c*4+1], s[c*4+2], s[c*4+3]
+				s[c*4+0] = gfMul(a0, 2) ^ gfMul(a1, 3) ^ a2 ^ a3
+				s[c*4+1] = a0 ^ gfMul(a1, 2) ^ gfMul(a2, 3) ^ a3
+				s[c*4+2] = a0 ^ a1 ^ gfMul(a2, 2) ^ gfMul(a3, 3)
+				s[c*4+3] = gfMul(a0, 3) ^ a1 ^ a2 ^ gfMul(a3, 2)
+			}
+		}
+		rc := [stkSize]byte{1, 2, 4, 8, rcons[i], rcons[i], rcons[i], rcons[i]}
+		for j := range s {
+			s[j] ^= tk1[j] ^ tk2[j] ^ tk3[j] ^ rc[j]
+		}
+		h(&tk1)
+		h(&tk2)
+		h(&tk3)
+		for j := range tk2 {
+			tk2[j] = lfsr2(tk2[j])
+			tk3[j] = lfsr3(tk3[j])
+		}
+	}
+	return s
+}
+
+func TestBC(t *testing.T) {
+	var key [KeySize]byte
+	var tweaks, src, dst [bcStride][stkSize]byte
+
+	for iter := 0; iter < 16; iter++ {
+		rand.Read(key[:])
+		for i := range src {
+			rand.Read(tweaks[i][:])
+			rand.Read(src[i][:])
+		}
+
+		var k bcKey
+		k.init(key[:])
+		k.encrypt4(&dst, &src, &tweaks)
+		for i := range src {
+			expected := refEncrypt(key[:], &tweaks[i], &src[i])
+			if expected != dst[i] {
+				t.Fatalf("[%d][%d]: expected %x, actual %x", iter, i, expected, dst[i])
+			}
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var key [KeySize]byte
+	var nonce [NonceSize]byte
+	rand.Read(key[:])
+	rand.Read(nonce[:])
+	ae := New(key[:])
+
+	buf := make([]byte, 256)
+	rand.Read(buf)
+	for _, adLen := range []int{0, 1, 15, 16, 17, 64, 65, 97} {
+		ad := buf[:adLen]
+		for msgLen := 0; msgLen <= len(buf)/2; msgLen++ {
+			msg := buf[128 : 128+msgLen]
+
+			sealed := ae.Seal(nil, nonce[:], msg, ad)
+			if len(sealed) != msgLen+TagSize {
+				t.Fatalf("[%d, %d]: unexpected length %d", adLen, msgLen, len(sealed))
+			}
+			opened, err := ae.Open(nil, nonce[:], sealed, ad)
+			if err != nil {
+				t.Fatalf("[%d, %d]: Open: %v", adLen, msgLen, err)
+			}
+			if !bytes.Equal(msg, opened) {
+				t.Fatalf("[%d, %d]: expected %x, actual %x", adLen, msgLen, msg, opened)
+			}
+
+			// In-place.
+			inPlace := append([]byte{}, msg...)
+			inPlace = ae.Seal(inPlace[:0], nonce[:], inPlace, ad)
+			if !bytes.Equal(sealed, inPlace) {
+				t.Fatalf("[%d, %d]: in-place Seal mismatch", adLen, msgLen)
+			}
+			if _, err = ae.Open(inPlace[:0], nonce[:], inPlace, ad); err != nil {
+				t.Fatalf("[%d, %d]: in-place Open: %v", adLen, msgLen, err)
+			}
+
+			// Tampering.
+			sealed[msgLen/2] ^= 0x20
+			if _, err = ae.Open(nil, nonce[:], sealed, ad); err != ErrOpen {
+				t.Fatalf("[%d, %d]: Open accepted tampered ciphertext", adLen, msgLen)
+			}
+			sealed[msgLen/2] ^= 0x20
+			if adLen > 0 {
+				ad[0] ^= 1
+				_, err = ae.Open(nil, nonce[:], sealed, ad)
+				ad[0] ^= 1
+				if err != ErrOpen {
+					t.Fatalf("[%d, %d]: Open accepted tampered AD", adLen, msgLen)
+				}
+			}
+		}
+	}
+}
+
+func TestPadding(t *testing.T) {
+	// The padded final partial block uses a different tweak than a full
+	// block with the same contents.
+	var key [KeySize]byte
+	var nonce [NonceSize]byte
+	ae := New(key[:])
+
+	short := []byte("0123456789abcde")
+	full := append(append([]byte{}, short...), 0x80)
+	if bytes.Equal(ae.Seal(nil, nonce[:], nil, short), ae.Seal(nil, nonce[:], nil, full)) {
+		t.Fatalf("AD padding is ambiguous")
+	}
+	a, b := ae.Seal(nil, nonce[:], short, nil), ae.Seal(nil, nonce[:], full, nil)
+	if bytes.Equal(a[len(a)-TagSize:], b[len(b)-TagSize:]) {
+		t.Fatalf("message padding is ambiguous")
+	}
+}
+
+func BenchmarkSeal(b *testing.B) {
+	var key [KeySize]byte
+	var nonce [NonceSize]byte
+	ae := New(key[:])
+	buf := make([]byte, 16384)
+	out := make([]byte, 0, len(buf)+TagSize)
+
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ae.Seal(out[:0], nonce[:], buf, nil)
+	}
+}